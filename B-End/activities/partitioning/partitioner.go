@@ -0,0 +1,408 @@
+package partitioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"hash/fnv"
+	"log"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/utils"
+	"strings"
+)
+
+// rowIdentifierSeparator keeps ["ab","c"] and ["a","bc"] from hashing to the same key
+const rowIdentifierSeparator = "\x1f"
+
+// PartitionFileID is the ID (and stream topic) of one partition of a file.
+func PartitionFileID(fileID string, partitionNumber int) string {
+	return fmt.Sprintf("%v-Partition-%v", fileID, partitionNumber)
+}
+
+// SetupFilePartitions creates a topic for each partition of the file
+// and returns a FileToBeRead describing each partition.
+// The returned files can be handed to the reconciliation engine
+// exactly like the original file.
+func SetupFilePartitions(ctx context.Context, file models.FileToBeRead, partitionCount int) ([]models.FileToBeRead, error) {
+	if partitionCount <= 0 {
+		return nil, errors.New("partition count must be greater than zero")
+	}
+
+	streamName, err := sectionsStreamName(file.FilePurpose)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]models.FileToBeRead, 0, partitionCount)
+	for partitionNumber := 1; partitionNumber <= partitionCount; partitionNumber++ {
+		partition := file
+		partition.ID = PartitionFileID(file.ID, partitionNumber)
+
+		err = file.ReadFileResultsStream.SetupStream(ctx, streamName, partition.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error on setting up partition topic: [%v], Error: %v", partition.ID, err)
+		}
+
+		partitions = append(partitions, partition)
+	}
+
+	return partitions, nil
+}
+
+// DeleteFileTopics deletes the topic of a file together with the topics of its partitions.
+func DeleteFileTopics(ctx context.Context, file models.FileToBeRead, partitionCount int) error {
+	streamName, err := sectionsStreamName(file.FilePurpose)
+	if err != nil {
+		return err
+	}
+
+	topicNames := []string{file.ID}
+	for partitionNumber := 1; partitionNumber <= partitionCount; partitionNumber++ {
+		topicNames = append(topicNames, PartitionFileID(file.ID, partitionNumber))
+	}
+
+	for _, topicName := range topicNames {
+		err = file.ReadFileResultsStream.DeleteStreamTopic(ctx, streamName, topicName)
+		if err != nil {
+			return fmt.Errorf("error on deleting topic: [%v], Error: %v", topicName, err)
+		}
+	}
+	return nil
+}
+
+// PartitionFile consumes every section of a file and republishes each row to
+// the partition picked by hashing the row's identifier columns.
+// Rows that share a row identifier always land in the same partition number,
+// so partition K of the primary file only ever needs to be reconciled
+// against partition K of the comparison file.
+func PartitionFile(
+	ctx context.Context,
+	file models.FileToBeRead,
+	partitions []models.FileToBeRead,
+	taskDetails models.ReconTaskDetails,
+	sectionSize int,
+) error {
+	if len(partitions) == 0 || sectionSize <= 0 {
+		return errors.New("invalid partitions or section size")
+	}
+
+	streamName, err := sectionsStreamName(file.FilePurpose)
+	if err != nil {
+		return err
+	}
+
+	identifierColumnIndexes := rowIdentifierColumnIndexes(taskDetails.ComparisonPairs, file.FilePurpose)
+	if len(identifierColumnIndexes) == 0 {
+		return errors.New("partitioning requires at least one row identifier comparison pair")
+	}
+
+	writers := make([]*partitionWriter, len(partitions))
+	isFullyPartitioned := true
+	for i, partition := range partitions {
+		writers[i] = &partitionWriter{
+			partition:             partition,
+			partitionNumber:       i + 1,
+			partitionCount:        len(partitions),
+			taskDetails:           taskDetails,
+			sectionSize:           sectionSize,
+			sectionSequenceNumber: 1,
+		}
+
+		//when resuming after a restart, the sections that were
+		//already published to the partition are still on its topic
+		if taskDetails.Checkpoints != nil {
+			lastSectionRead, isFullyRead := taskDetails.Checkpoints.GetLastSectionRead(ctx, taskDetails.ID, partition.ID)
+			writers[i].lastSectionAlreadyPublished = lastSectionRead
+			isFullyPartitioned = isFullyPartitioned && isFullyRead
+		} else {
+			isFullyPartitioned = false
+		}
+	}
+
+	if isFullyPartitioned {
+		log.Printf("File: [%v] has already been partitioned", file.ID)
+		return nil
+	}
+
+	consumerId := fmt.Sprintf("%v-Partitioner", file.ID)
+
+	//always start from the beginning of the file, partitioning is
+	//deterministic so the same sections are produced again
+	_ = file.ReadFileResultsStream.DeleteStreamConsumer(
+		utils.NewContextWithDefaultTimeout(),
+		streamName,
+		consumerId,
+	)
+
+	sectionsStreamConsumer, err := file.ReadFileResultsStream.CreateStreamConsumer(
+		utils.NewContextWithDefaultTimeout(),
+		streamName,
+		file.ID,
+		consumerId,
+	)
+
+	if err != nil {
+		log.Printf("Error creating partitioner consumer for file: [%v], Error: %v", file.ID, err)
+		return err
+	}
+
+	receivedSections := make(map[int]bool)
+	for {
+		section, err := sectionsStreamConsumer.FetchNextWithContext(ctx)
+
+		if err != nil {
+			log.Printf("Error getting next section to partition for file: [%v], Error: %v", file.ID, err)
+
+			//the task was cancelled
+			if ctx.Err() != nil {
+				return cleanUpPartitioner(ctx, file, streamName, consumerId)
+			}
+
+			//the file may still be being read
+			if errors.Is(err, models.ErrFetchTimeout) {
+				continue
+			}
+			return fmt.Errorf("error on fetching section to partition for file: [%v], Error: %w", file.ID, err)
+		}
+
+		//a section can be published twice when
+		//reading resumes after a restart
+		if receivedSections[section.SectionSequenceNumber] {
+			continue
+		}
+		receivedSections[section.SectionSequenceNumber] = true
+
+		partitionedRows := partitionRows(section.SectionRows, identifierColumnIndexes, len(partitions))
+
+		for i, rows := range partitionedRows {
+			if len(section.ColumnHeaders) > 0 {
+				writers[i].columnHeaders = section.ColumnHeaders
+			}
+			err = writers[i].write(ctx, rows)
+			if err != nil {
+				if ctx.Err() != nil {
+					return cleanUpPartitioner(ctx, file, streamName, consumerId)
+				}
+				return err
+			}
+		}
+
+		if section.IsLastSection {
+			break
+		}
+	}
+
+	// flush what is left and tell every partition
+	// that there is nothing more coming
+	for _, writer := range writers {
+		err = writer.close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Finished partitioning file: [%v] into [%v] partitions", file.ID, len(partitions))
+
+	return deletePartitionedFileStreams(file, streamName, consumerId)
+}
+
+// cleanUpPartitioner deletes what the partitioner of a cancelled task
+// left behind, then reports the cancellation.
+func cleanUpPartitioner(ctx context.Context, file models.FileToBeRead, streamName string, consumerId string) error {
+	log.Printf("Partitioning cancelled for file: [%v]", file.ID)
+
+	//the streams are kept for a retry when other work of the task failed
+	if models.IsStoppedAfterFailure(ctx) {
+		return ctx.Err()
+	}
+
+	err := deletePartitionedFileStreams(file, streamName, consumerId)
+	if err != nil {
+		log.Printf("Error cleaning up cancelled partitioner: [%v], Error: %v", file.ID, err)
+	}
+	return ctx.Err()
+}
+
+// deletePartitionedFileStreams deletes the partitioner consumer
+// and the topic of the file that was partitioned.
+func deletePartitionedFileStreams(file models.FileToBeRead, streamName string, consumerId string) error {
+	err := file.ReadFileResultsStream.DeleteStreamConsumer(
+		utils.NewContextWithDefaultTimeout(),
+		streamName,
+		consumerId,
+	)
+
+	if err != nil {
+		log.Printf("Error deleting partitioner consumer: [%v], Error: %v", consumerId, err)
+		return err
+	}
+
+	// every row now lives in a partition topic
+	err = file.ReadFileResultsStream.DeleteStreamTopic(
+		utils.NewContextWithDefaultTimeout(),
+		streamName,
+		file.ID,
+	)
+
+	if err != nil {
+		log.Printf("Error deleting partitioned file topic: [%v], Error: %v", file.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeterminePartitionNumber returns the 1 based partition a row belongs to.
+func DeterminePartitionNumber(row models.FileSectionRow, identifierColumnIndexes []int, partitionCount int) int {
+	values := make([]string, 0, len(identifierColumnIndexes))
+	for _, columnIndex := range identifierColumnIndexes {
+		if columnIndex < len(row.ParsedColumnsFromRow) {
+			values = append(values, row.ParsedColumnsFromRow[columnIndex])
+		} else {
+			values = append(values, "")
+		}
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strings.Join(values, rowIdentifierSeparator)))
+	return int(hash.Sum32()%uint32(partitionCount)) + 1
+}
+
+func partitionRows(rows []models.FileSectionRow, identifierColumnIndexes []int, partitionCount int) [][]models.FileSectionRow {
+	partitionedRows := make([][]models.FileSectionRow, partitionCount)
+	for _, row := range rows {
+		partitionNumber := DeterminePartitionNumber(row, identifierColumnIndexes, partitionCount)
+		partitionedRows[partitionNumber-1] = append(partitionedRows[partitionNumber-1], row)
+	}
+	return partitionedRows
+}
+
+func rowIdentifierColumnIndexes(comparisonPairs []models.ComparisonPair, filePurpose file_purpose.FilePurposeType) []int {
+	columnIndexes := make([]int, 0)
+	for _, pair := range comparisonPairs {
+		if !pair.IsRowIdentifier {
+			continue
+		}
+		if filePurpose == file_purpose.PrimaryFile {
+			columnIndexes = append(columnIndexes, pair.PrimaryFileColumnIndex)
+		} else {
+			columnIndexes = append(columnIndexes, pair.ComparisonFileColumnIndex)
+		}
+	}
+	return columnIndexes
+}
+
+func sectionsStreamName(filePurpose file_purpose.FilePurposeType) (string, error) {
+	switch filePurpose {
+	case file_purpose.PrimaryFile:
+		return constants.PRIMARY_FILE_SECTIONS_STREAM_NAME, nil
+	case file_purpose.ComparisonFile:
+		return constants.COMPARISON_FILE_SECTIONS_STREAM_NAME, nil
+	default:
+		return "", fmt.Errorf("unknown file purpose: [%v]", filePurpose)
+	}
+}
+
+// partitionWriter batches the rows of one partition into sections
+// and publishes them to the partition's topic.
+type partitionWriter struct {
+	partition             models.FileToBeRead
+	partitionNumber       int
+	partitionCount        int
+	taskDetails           models.ReconTaskDetails
+	sectionSize           int
+	sectionSequenceNumber int
+	columnHeaders         []string
+	pendingRows           []models.FileSectionRow
+
+	lastSectionAlreadyPublished int
+}
+
+func (w *partitionWriter) write(ctx context.Context, rows []models.FileSectionRow) error {
+	for _, row := range rows {
+		w.pendingRows = append(w.pendingRows, row)
+		if len(w.pendingRows) == w.sectionSize {
+			err := w.publish(ctx, w.pendingRows, false)
+			if err != nil {
+				return err
+			}
+			w.pendingRows = nil
+		}
+	}
+	return nil
+}
+
+func (w *partitionWriter) close(ctx context.Context) error {
+	if len(w.pendingRows) > 0 {
+		err := w.publish(ctx, w.pendingRows, false)
+		if err != nil {
+			return err
+		}
+		w.pendingRows = nil
+	}
+
+	//empty last section with IsLastSection=true
+	//to signal the end of the partition
+	return w.publish(ctx, []models.FileSectionRow{}, true)
+}
+
+func (w *partitionWriter) publish(ctx context.Context, rows []models.FileSectionRow, isLastSection bool) error {
+	//only the first section of a partition carries the column headers
+	var columnHeaders []string
+	if w.sectionSequenceNumber == 1 {
+		columnHeaders = w.columnHeaders
+	}
+
+	if w.sectionSequenceNumber <= w.lastSectionAlreadyPublished {
+		w.sectionSequenceNumber++
+		return nil
+	}
+
+	//hold on to the section while the task is paused
+	err := w.taskDetails.Control.WaitWhilePaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	fileSection := models.FileSection{
+		ID:                    uuid.New().String(),
+		TaskID:                w.taskDetails.ID,
+		FileID:                w.partition.ID,
+		SectionSequenceNumber: w.sectionSequenceNumber,
+		OriginalFilePurpose:   w.partition.FilePurpose,
+		SectionRows:           rows,
+		ColumnHeaders:         columnHeaders,
+		IsLastSection:         isLastSection,
+		PartitionNumber:       w.partitionNumber,
+		PartitionCount:        w.partitionCount,
+	}
+
+	err = w.partition.ReadFileResultsStream.PublishToTopic(ctx, w.partition.ID, &fileSection)
+
+	if err != nil {
+		return fmt.Errorf("error on publishing to partition topic: [%v], SectionId: [%v], Error: %v",
+			w.partition.ID,
+			fileSection.SectionSequenceNumber,
+			err,
+		)
+	}
+
+	if w.taskDetails.Checkpoints != nil {
+		err = w.taskDetails.Checkpoints.RecordSectionRead(
+			ctx,
+			w.taskDetails.ID,
+			w.partition.ID,
+			fileSection.SectionSequenceNumber,
+			fileSection.IsLastSection,
+		)
+		if err != nil {
+			log.Printf("Error on checkpointing partition: [%v], SectionId: [%v], Error: %v", w.partition.ID, fileSection.SectionSequenceNumber, err)
+		}
+	}
+
+	w.sectionSequenceNumber++
+	return nil
+}
@@ -0,0 +1,169 @@
+package partitioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"testing"
+)
+
+func TestFilePartitioningActivity(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "File Partitioning Activity Suite")
+}
+
+var _ = Describe("partitionRows", func() {
+	comparisonPairs := []models.ComparisonPair{
+		{
+			PrimaryFileColumnIndex:    0,
+			ComparisonFileColumnIndex: 1,
+			IsRowIdentifier:           true,
+		},
+		{
+			PrimaryFileColumnIndex:    1,
+			ComparisonFileColumnIndex: 0,
+			IsRowIdentifier:           false,
+		},
+	}
+
+	Context("when the same row identifier appears in the primary and comparison files", func() {
+		It("should put both rows in the same partition", func() {
+			primaryRow := models.FileSectionRow{ParsedColumnsFromRow: []string{"TXN-001", "100"}}
+			comparisonRow := models.FileSectionRow{ParsedColumnsFromRow: []string{"250", "TXN-001"}}
+
+			primaryIndexes := rowIdentifierColumnIndexes(comparisonPairs, file_purpose.PrimaryFile)
+			comparisonIndexes := rowIdentifierColumnIndexes(comparisonPairs, file_purpose.ComparisonFile)
+
+			Expect(primaryIndexes).To(Equal([]int{0}))
+			Expect(comparisonIndexes).To(Equal([]int{1}))
+			Expect(DeterminePartitionNumber(primaryRow, primaryIndexes, 8)).To(
+				Equal(DeterminePartitionNumber(comparisonRow, comparisonIndexes, 8)),
+			)
+		})
+	})
+
+	Context("when rows are split across partitions", func() {
+		It("should keep every row, in row order, inside a partition in range", func() {
+			rows := make([]models.FileSectionRow, 0)
+			for i := 0; i < 50; i++ {
+				rows = append(rows, models.FileSectionRow{
+					RowNumber:            uint64(i),
+					ParsedColumnsFromRow: []string{string(rune('A' + i)), "1"},
+				})
+			}
+
+			partitionedRows := partitionRows(rows, []int{0}, 4)
+
+			Expect(partitionedRows).To(HaveLen(4))
+			totalRows := 0
+			for _, partition := range partitionedRows {
+				for i := 1; i < len(partition); i++ {
+					Expect(partition[i].RowNumber).To(BeNumerically(">", partition[i-1].RowNumber))
+				}
+				totalRows += len(partition)
+			}
+			Expect(totalRows).To(Equal(len(rows)))
+		})
+	})
+})
+
+// fileSections hands out its sections after fetchTimeouts fetches have run out of time,
+// once they run out every fetch fails with fetchErr
+type fileSections struct {
+	sections      []models.FileSection
+	fetchTimeouts int
+	fetchErr      error
+	fetches       int
+	published     []models.FileSection
+}
+
+func (f *fileSections) SetupStream(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (f *fileSections) DeleteStreamTopic(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (f *fileSections) PublishToTopic(ctx context.Context, topicName string, data interface{}) error {
+	f.published = append(f.published, *data.(*models.FileSection))
+	return nil
+}
+
+func (f *fileSections) CreateStreamConsumer(ctx context.Context, streamName, topicName, consumerName string) (models.StreamConsumer, error) {
+	return f, nil
+}
+
+func (f *fileSections) DeleteStreamConsumer(ctx context.Context, streamName string, consumerName string) error {
+	return nil
+}
+
+func (f *fileSections) Close(ctx context.Context) error {
+	return nil
+}
+
+func (f *fileSections) FetchNext() (*models.FileSection, error) {
+	return f.FetchNextWithContext(context.Background())
+}
+
+func (f *fileSections) FetchNextWithContext(ctx context.Context) (*models.FileSection, error) {
+	f.fetches++
+	if f.fetchTimeouts > 0 {
+		f.fetchTimeouts--
+		return nil, fmt.Errorf("error getting Next FileSection: %w", models.ErrFetchTimeout)
+	}
+	if len(f.sections) == 0 {
+		return nil, f.fetchErr
+	}
+	section := f.sections[0]
+	f.sections = f.sections[1:]
+	return &section, nil
+}
+
+var _ = Describe("PartitionFile", func() {
+	taskDetails := models.ReconTaskDetails{
+		ID:              "task_1",
+		ComparisonPairs: []models.ComparisonPair{{PrimaryFileColumnIndex: 0, IsRowIdentifier: true}},
+	}
+
+	Context("when a section takes longer than the fetch timeout to arrive", func() {
+		It("should keep waiting for it", func() {
+			stream := &fileSections{
+				fetchTimeouts: 2,
+				sections: []models.FileSection{{
+					SectionSequenceNumber: 1,
+					IsLastSection:         true,
+					SectionRows:           []models.FileSectionRow{{RowNumber: 1, ParsedColumnsFromRow: []string{"TXN-001"}}},
+				}},
+			}
+			file := models.FileToBeRead{ID: "PrimaryFile-1", FilePurpose: file_purpose.PrimaryFile, ReadFileResultsStream: stream}
+			partitions, err := SetupFilePartitions(context.Background(), file, 2)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(PartitionFile(context.Background(), file, partitions, taskDetails, 10)).To(Succeed())
+			Expect(stream.fetchTimeouts).To(Equal(0))
+			// the row in its partition and the last section of each partition
+			Expect(stream.published).To(HaveLen(3))
+		})
+	})
+
+	Context("when a section can't be fetched", func() {
+		It("should return the error instead of fetching it again", func() {
+			decodeErr := errors.New("error on decoding FileSection")
+			stream := &fileSections{fetchErr: decodeErr}
+			file := models.FileToBeRead{ID: "PrimaryFile-1", FilePurpose: file_purpose.PrimaryFile, ReadFileResultsStream: stream}
+			partitions, err := SetupFilePartitions(context.Background(), file, 2)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = PartitionFile(context.Background(), file, partitions, taskDetails, 10)
+
+			Expect(err).To(MatchError(decodeErr))
+			Expect(stream.fetches).To(Equal(1))
+			Expect(stream.published).To(BeEmpty())
+		})
+	})
+})
@@ -8,12 +8,10 @@ import (
 	"github.com/google/uuid"
 	"io"
 	"log"
-	"os"
 	"reconciler.io/models"
 	"reconciler.io/models/enums/recon_status"
 	"reconciler.io/models/enums/supported_file_extensions"
-	"strconv"
-	"strings"
+	"reconciler.io/models/enums/task_event_type"
 )
 
 // ReadFileIntoChannel reads a file and converts it into a stream of FileSections.
@@ -34,16 +32,50 @@ func ReadFileIntoChannel(ctx context.Context, fileToBeRead models.FileToBeRead,
 
 // readCSVFile reads and parses a CSV file.
 func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDetails models.ReconTaskDetails, sectionSize int) error {
+	if taskDetails.Files == nil {
+		return errors.New("no file store to read the file from")
+	}
+
 	// Open the CSV file
-	file, err := os.Open(fileToBeRead.FilePath)
+	file, err := taskDetails.Files.Get(ctx, fileToBeRead.FilePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
+	// the size of the file lets the task estimate how much is left to read
+	totalBytes := file.Size
+
+	// when resuming after a restart, the sections that were
+	// already published are still on the stream
+	lastSectionAlreadyRead := 0
+	if taskDetails.Checkpoints != nil {
+		lastSectionAlreadyRead, _ = taskDetails.Checkpoints.GetLastSectionRead(ctx, taskDetails.ID, fileToBeRead.ID)
+	}
+
 	// Read the CSV file using a CSV reader
 	reader := csv.NewReader(file)
 
+	// publish the section and record how far into the file the reader has got
+	publishSection := func(fileSection models.FileSection) error {
+		err := publishSectionToStream(ctx, fileToBeRead, taskDetails, fileSection, lastSectionAlreadyRead)
+		if err != nil {
+			return err
+		}
+		taskDetails.ProgressTracker.RecordSectionRead(
+			fileToBeRead.ID,
+			fileToBeRead.FilePurpose,
+			len(fileSection.SectionRows),
+			reader.InputOffset(),
+			totalBytes,
+			fileSection.IsLastSection,
+		)
+		if fileSection.IsLastSection {
+			taskDetails.PublishEvent(task_event_type.FileReadCompleted, fileToBeRead.ID, fileSection.SectionSequenceNumber)
+		}
+		return nil
+	}
+
 	var sectionRows []models.FileSectionRow
 	rowNumber := uint64(0)
 	sectionSequenceNumber := 1
@@ -67,10 +99,9 @@ func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDeta
 
 		row := models.FileSectionRow{
 			RowNumber:            rowNumber,
-			RawData:              strconv.Quote(strings.Join(record, ",")),
 			ParsedColumnsFromRow: record,
 			ReconResult:          recon_status.Pending,
-			ReconResultReasons:   []string{},
+			ReconResultReasons:   []models.ReconReason{},
 		}
 		sectionRows = append(sectionRows, row)
 
@@ -84,12 +115,12 @@ func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDeta
 				OriginalFilePurpose:   fileToBeRead.FilePurpose,
 				SectionRows:           sectionRows,
 				ComparisonPairs:       taskDetails.ComparisonPairs,
-				ColumnHeaders:         columnHeaders,
+				ColumnHeaders:         sectionColumnHeaders(sectionSequenceNumber, columnHeaders),
 				ReconConfig:           taskDetails.ReconConfig,
 				IsLastSection:         false,
 			}
 
-			err = publishSectionToStream(ctx, fileToBeRead, fileSection)
+			err = publishSection(fileSection)
 			if err != nil {
 				return err
 			}
@@ -112,13 +143,13 @@ func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDeta
 			OriginalFilePurpose:   fileToBeRead.FilePurpose,
 			SectionRows:           sectionRows,
 			ComparisonPairs:       taskDetails.ComparisonPairs,
-			ColumnHeaders:         columnHeaders,
+			ColumnHeaders:         sectionColumnHeaders(sectionSequenceNumber, columnHeaders),
 			ReconConfig:           taskDetails.ReconConfig,
 			IsLastSection:         false,
 		}
 		//increment the section sequence number
 		sectionSequenceNumber++
-		err = publishSectionToStream(ctx, fileToBeRead, fileSection)
+		err = publishSection(fileSection)
 		if err != nil {
 			return err
 		}
@@ -133,11 +164,11 @@ func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDeta
 			OriginalFilePurpose:   fileToBeRead.FilePurpose,
 			SectionRows:           []models.FileSectionRow{},
 			ComparisonPairs:       taskDetails.ComparisonPairs,
-			ColumnHeaders:         columnHeaders,
+			ColumnHeaders:         sectionColumnHeaders(sectionSequenceNumber, columnHeaders),
 			ReconConfig:           taskDetails.ReconConfig,
 			IsLastSection:         true,
 		}
-		err = publishSectionToStream(ctx, fileToBeRead, lastFileSection)
+		err = publishSection(lastFileSection)
 		if err != nil {
 			return err
 		}
@@ -153,11 +184,11 @@ func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDeta
 			OriginalFilePurpose:   fileToBeRead.FilePurpose,
 			SectionRows:           []models.FileSectionRow{},
 			ComparisonPairs:       taskDetails.ComparisonPairs,
-			ColumnHeaders:         columnHeaders,
+			ColumnHeaders:         sectionColumnHeaders(sectionSequenceNumber, columnHeaders),
 			ReconConfig:           taskDetails.ReconConfig,
 			IsLastSection:         true,
 		}
-		err = publishSectionToStream(ctx, fileToBeRead, lastFileSection)
+		err = publishSection(lastFileSection)
 		if err != nil {
 			return err
 		}
@@ -166,9 +197,26 @@ func readCSVFile(ctx context.Context, fileToBeRead models.FileToBeRead, taskDeta
 	return nil
 }
 
-func publishSectionToStream(ctx context.Context, fileToBeRead models.FileToBeRead, fileSection models.FileSection) error {
+func publishSectionToStream(
+	ctx context.Context,
+	fileToBeRead models.FileToBeRead,
+	taskDetails models.ReconTaskDetails,
+	fileSection models.FileSection,
+	lastSectionAlreadyRead int,
+) error {
+	if fileSection.SectionSequenceNumber <= lastSectionAlreadyRead {
+		log.Printf("Skipping already published SeqNum: [%v] File: [%v]", fileSection.SectionSequenceNumber, fileSection.FileID)
+		return nil
+	}
+
+	//hold on to the section while the task is paused
+	err := taskDetails.Control.WaitWhilePaused(ctx)
+	if err != nil {
+		return fmt.Errorf("stopped reading file: [%v], Error: %v", fileSection.FileID, err)
+	}
+
 	log.Printf("Publishing SeqNum: [%v] File: [%v]", fileSection.SectionSequenceNumber, fileSection.FileID)
-	err := fileToBeRead.ReadFileResultsStream.PublishToTopic(
+	err = fileToBeRead.ReadFileResultsStream.PublishToTopic(
 		ctx,
 		fileToBeRead.ID,
 		&fileSection,
@@ -182,6 +230,28 @@ func publishSectionToStream(ctx context.Context, fileToBeRead models.FileToBeRea
 		)
 		return err
 	}
+
+	if taskDetails.Checkpoints != nil {
+		err = taskDetails.Checkpoints.RecordSectionRead(
+			ctx,
+			taskDetails.ID,
+			fileToBeRead.ID,
+			fileSection.SectionSequenceNumber,
+			fileSection.IsLastSection,
+		)
+		if err != nil {
+			log.Printf("Error on checkpointing SeqNum: [%v] File: [%v], Error: %v", fileSection.SectionSequenceNumber, fileSection.FileID, err)
+		}
+	}
+	return nil
+}
+
+// sectionColumnHeaders only sends the column headers with the first section,
+// consumers keep them for the rest of the file
+func sectionColumnHeaders(sectionSequenceNumber int, columnHeaders []string) []string {
+	if sectionSequenceNumber == 1 {
+		return columnHeaders
+	}
 	return nil
 }
 
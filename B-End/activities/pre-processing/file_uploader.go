@@ -6,13 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"reconciler.io/constants"
 	"reconciler.io/models"
 	"reconciler.io/models/enums/file_purpose"
-	"reconciler.io/models/enums/file_storage_locations"
 	"reconciler.io/models/enums/supported_file_extensions"
 	"reconciler.io/utils"
 )
@@ -28,8 +27,12 @@ func UploadFile(ctx context.Context, taskDetail models.ReconTaskDetails, filePur
 
 	fileID = fmt.Sprintf("%v-%v", string(filePurpose), fileID)
 
+	if taskDetail.Files == nil {
+		return nil, errors.New("no file store to upload the file to")
+	}
+
 	// Determine the pre-processing storage location.
-	storageLocation := file_storage_locations.LocalFileSystem // or S3FileSystem based on your setup.
+	storageLocation := taskDetail.Files.Location()
 
 	// Determine for file extension
 	fileExtension, err := determineFileExtension(fileDetails)
@@ -39,7 +42,7 @@ func UploadFile(ctx context.Context, taskDetail models.ReconTaskDetails, filePur
 	}
 
 	// Determine the pre-processing path.
-	filePath := utils.GenerateFilePath(fileID, storageLocation, taskDetail.UserID, fileExtension)
+	filePath := utils.GenerateFilePath(fileID, taskDetail.UserID, fileExtension)
 
 	// Check for file metadata
 	if err != nil {
@@ -47,7 +50,7 @@ func UploadFile(ctx context.Context, taskDetail models.ReconTaskDetails, filePur
 	}
 
 	// Save the pre-processing to the storage location.
-	err = saveFile(fileDetails, filePath)
+	err = saveFile(ctx, taskDetail.Files, fileDetails, filePath)
 
 	if err != nil {
 		return nil, err
@@ -56,6 +59,11 @@ func UploadFile(ctx context.Context, taskDetail models.ReconTaskDetails, filePur
 	readResultsStream, err := createStream(ctx, fileID, filePurpose)
 
 	if err != nil {
+		// the file can't be read without its stream
+		deleteErr := taskDetail.Files.Delete(ctx, filePath)
+		if deleteErr != nil {
+			log.Printf("error on deleting uploaded file: [%v], Error: %v", filePath, deleteErr)
+		}
 		return nil, err
 	}
 	// Create the FileToBeRead object.
@@ -76,8 +84,26 @@ func UploadFile(ctx context.Context, taskDetail models.ReconTaskDetails, filePur
 	return &fileToBeRead, nil
 }
 
+// ReconnectFileStream sets up the sections stream of a file
+// that was uploaded before the service restarted.
+func ReconnectFileStream(ctx context.Context, fileToBeRead models.FileToBeRead) (models.FileToBeRead, error) {
+	readResultsStream, err := createStream(ctx, fileToBeRead.ID, fileToBeRead.FilePurpose)
+
+	if err != nil {
+		return models.FileToBeRead{}, err
+	}
+
+	fileToBeRead.ReadFileResultsStream = readResultsStream
+	return fileToBeRead, nil
+}
+
 func createStream(ctx context.Context, fileId string, filePurpose file_purpose.FilePurposeType) (models.StreamProvider, error) {
 	streamProvider, err := models.NewStreamProvider(constants.NATS_URL)
+	if err != nil {
+		err = fmt.Errorf("error on connecting to FileSectionsStream: [%v]", err)
+		return nil, err
+	}
+
 	topicName := fileId
 	switch filePurpose {
 	case file_purpose.PrimaryFile:
@@ -132,19 +158,12 @@ func generateFileID(file *multipart.FileHeader) (string, error) {
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
-func saveFile(file *multipart.FileHeader, filePath string) error {
+func saveFile(ctx context.Context, files models.FileStore, file *multipart.FileHeader, filePath string) error {
 	src, err := file.Open()
 	if err != nil {
 		return err
 	}
 	defer src.Close()
 
-	out, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, src)
-	return err
+	return files.Put(ctx, filePath, src)
 }
@@ -0,0 +1,106 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reconciler.io/activities/partitioning"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"sync"
+)
+
+// ReconcileFiles reconciles the primary file of a task against its comparison file
+// until it is done or the context is.
+// Large files are split into partitions that are each reconciled independently
+// when the task asks for more than one partition.
+func ReconcileFiles(
+	ctx context.Context,
+	primaryFile models.FileToBeRead,
+	comparisonFile models.FileToBeRead,
+	reconTaskDetails models.ReconTaskDetails,
+) error {
+	if reconTaskDetails.PartitionCount > 1 {
+		return BeginPartitionedFileReconciliation(ctx, primaryFile, comparisonFile, reconTaskDetails)
+	}
+	return BeginFileReconciliation(ctx, primaryFile, comparisonFile, reconTaskDetails)
+}
+
+// BeginPartitionedFileReconciliation re-partitions both files by the hash of their
+// row identifiers and reconciles every primary partition against the matching comparison partition.
+func BeginPartitionedFileReconciliation(
+	ctx context.Context,
+	primaryFile models.FileToBeRead,
+	comparisonFile models.FileToBeRead,
+	taskInfo models.ReconTaskDetails,
+) error {
+	primaryFilePartitions, err := partitioning.SetupFilePartitions(context.Background(), primaryFile, taskInfo.PartitionCount)
+
+	//error on setting up partitions
+	if err != nil {
+		return fmt.Errorf("error on setting up PrimaryFile partitions: %v", err)
+	}
+
+	comparisonFilePartitions, err := partitioning.SetupFilePartitions(context.Background(), comparisonFile, taskInfo.PartitionCount)
+
+	//error on setting up partitions
+	if err != nil {
+		return fmt.Errorf("error on setting up ComparisonFile partitions: %v", err)
+	}
+
+	sectionSize := constants.FILE_SECTION_BATCH_SIZE
+	errs := make(chan error, 2+taskInfo.PartitionCount)
+	var wg sync.WaitGroup
+
+	//the partition reconcilers would wait forever for the sections of a partitioner that failed,
+	//so the first error stops everything else that is partitioning or reconciling the files
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	reportError := func(err error) {
+		if errors.Is(err, context.Canceled) && models.IsStoppedAfterFailure(ctx) {
+			return
+		}
+		errs <- err
+		stop(models.ErrStoppedAfterFailure)
+	}
+
+	//re-partition both files by the hash of their row identifiers
+	for _, file := range []struct {
+		file       models.FileToBeRead
+		partitions []models.FileToBeRead
+	}{
+		{primaryFile, primaryFilePartitions},
+		{comparisonFile, comparisonFilePartitions},
+	} {
+		wg.Add(1)
+		go func(file models.FileToBeRead, partitions []models.FileToBeRead) {
+			defer wg.Done()
+			err := partitioning.PartitionFile(ctx, file, partitions, taskInfo, sectionSize)
+			if err != nil {
+				reportError(fmt.Errorf("error on partitioning file: [%v], Error: %w", file.ID, err))
+			}
+		}(file.file, file.partitions)
+	}
+
+	//each primary partition only needs to be
+	//reconciled against the matching comparison partition
+	for i := range primaryFilePartitions {
+		wg.Add(1)
+		go func(primaryPartition models.FileToBeRead, comparisonPartition models.FileToBeRead) {
+			defer wg.Done()
+			err := BeginFileReconciliation(ctx, primaryPartition, comparisonPartition, taskInfo)
+			if err != nil {
+				reportError(fmt.Errorf("error on reconciling partition: [%v], Error: %w", primaryPartition.ID, err))
+			}
+		}(primaryFilePartitions[i], comparisonFilePartitions[i])
+	}
+
+	wg.Wait()
+	close(errs)
+
+	partitionErrors := make([]error, 0)
+	for err := range errs {
+		partitionErrors = append(partitionErrors, err)
+	}
+	return errors.Join(partitionErrors...)
+}
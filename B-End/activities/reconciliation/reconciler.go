@@ -2,25 +2,45 @@ package reconciliation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reconciler.io/constants"
 	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
 	"reconciler.io/models/enums/recon_status"
+	"reconciler.io/models/enums/task_event_type"
 	"reconciler.io/utils"
 	"sync"
 )
 
+// BeginFileReconciliation reconciles every section of the primary file against the comparison file.
+// It stops and cleans up the streams of the files as soon as the context is done.
 func BeginFileReconciliation(
+	ctx context.Context,
 	primaryFile models.FileToBeRead,
 	comparisonFile models.FileToBeRead,
 	reconTaskDetails models.ReconTaskDetails,
 ) error {
 
+	checkpoints := reconTaskDetails.Checkpoints
+
+	//a task resumed after a restart may have
+	//already reconciled every section of this file
+	if checkpoints != nil && checkpoints.HaveAllSectionsBeenReconciled(context.Background(), reconTaskDetails.ID, primaryFile.ID) {
+		log.Printf("all sections already reconciled for file: [%v]", primaryFile.ID)
+		return nil
+	}
+
 	//create a consumer on the primary file sections stream
 	log.Printf("creating primaryFileSectionsStreamConsumer for file: [%v]", primaryFile.ID)
 	consumerId := primaryFile.ID
 	topicName := primaryFile.ID
+
+	//always start from the beginning of the file.
+	//sections that were already reconciled before a restart are skipped below
+	deleteStaleStreamConsumer(primaryFile.ReadFileResultsStream, constants.PRIMARY_FILE_SECTIONS_STREAM_NAME, consumerId)
+
 	primaryFileSectionsStreamConsumer, err := primaryFile.ReadFileResultsStream.CreateStreamConsumer(
 		utils.NewContextWithDefaultTimeout(),
 		constants.PRIMARY_FILE_SECTIONS_STREAM_NAME,
@@ -36,21 +56,99 @@ func BeginFileReconciliation(
 
 	log.Printf("successfully created primaryFileSectionsStreamConsumer for file: [%v]", primaryFile.ID)
 
+	//only a bounded number of sections of this task
+	//are reconciled at the same time
+	workerPool := DefaultSectionWorkerPool
+	workerPool.RegisterTask(reconTaskDetails.ID, reconTaskDetails.MaxConcurrentSections)
+	defer workerPool.UnregisterTask(reconTaskDetails.ID)
+
 	var wg sync.WaitGroup
+	var columnHeaders []string
+	failedSections := &sectionErrors{}
+	receivedSections := make(map[int]bool)
 	for {
-		//for each primary file section
-		//we will spin up a separate consumer
-		//on the comparison file stream
-		wg.Add(1)
+		//no new sections are taken on once one has failed,
+		//the task fails with the errors of the failed sections
+		if failedSections.any() {
+			wg.Wait()
+			return failedSections.join()
+		}
 
 		log.Printf("Waiting new primary fileSection. fileID: [%v]", primaryFile.ID)
-		primaryFileSection, err := primaryFileSectionsStreamConsumer.FetchNext()
+		primaryFileSection, err := primaryFileSectionsStreamConsumer.FetchNextWithContext(ctx)
 
 		if err != nil {
 			log.Printf("Error getting next PrimarySection: %v", err)
+
+			//the task was cancelled, clean up
+			//everything that was created for it
+			if ctx.Err() != nil {
+				wg.Wait()
+				return cleanUpCancelledFileReconciliation(ctx, primaryFile, comparisonFile)
+			}
+
+			//the service is shutting down, the remaining
+			//sections are picked up again after a restart
+			if workerPool.IsDraining() {
+				wg.Wait()
+				return ErrSectionWorkerPoolDraining
+			}
+			continue
+		}
+
+		//a section can be published twice when
+		//reading resumes after a restart
+		if receivedSections[primaryFileSection.SectionSequenceNumber] {
+			log.Printf("Skipping duplicate PrimaryFileSection:[%v]", primaryFileSection.SectionSequenceNumber)
+			continue
+		}
+		receivedSections[primaryFileSection.SectionSequenceNumber] = true
+
+		//task level details and the column headers
+		//are not repeated in every section on the stream
+		primaryFileSection.AttachTaskMetadata(reconTaskDetails)
+		if len(primaryFileSection.ColumnHeaders) > 0 {
+			columnHeaders = primaryFileSection.ColumnHeaders
+		} else {
+			primaryFileSection.ColumnHeaders = columnHeaders
+		}
+
+		if checkpoints != nil && checkpoints.HasSectionBeenReconciled(
+			context.Background(),
+			reconTaskDetails.ID,
+			primaryFileSection.FileID,
+			primaryFileSection.SectionSequenceNumber,
+		) {
+			log.Printf("Skipping already reconciled PrimaryFileSection:[%v]", primaryFileSection.SectionSequenceNumber)
+			if primaryFileSection.IsLastSection {
+				break
+			}
 			continue
 		}
 
+		//no new sections are taken on while the task is paused
+		err = reconTaskDetails.Control.WaitWhilePaused(ctx)
+
+		//wait for a free worker before taking on this section.
+		//we don't fetch any more primary sections until one is free
+		if err == nil {
+			err = workerPool.AcquireWorker(ctx, reconTaskDetails.ID)
+		}
+
+		if err != nil {
+			log.Printf("Error acquiring worker for PrimarySection: [%v], Error: %v", primaryFileSection.SectionSequenceNumber, err)
+			wg.Wait()
+			if ctx.Err() != nil {
+				return cleanUpCancelledFileReconciliation(ctx, primaryFile, comparisonFile)
+			}
+			return err
+		}
+
+		//for each primary file section
+		//we will spin up a separate consumer
+		//on the comparison file stream
+		wg.Add(1)
+
 		log.Printf("Begining reconciliation for PrimaryFileSection:[%v]", primaryFileSection.SectionSequenceNumber)
 
 		go func(
@@ -61,36 +159,47 @@ func BeginFileReconciliation(
 			comparisonFileID string,
 			wg *sync.WaitGroup,
 		) {
+			//registered first so that it runs last, once a panic has been recorded
+			defer wg.Done()
+			defer workerPool.ReleaseWorker(reconTaskDetails.ID)
+
 			// Recovery mechanism
 			defer func() {
 				if r := recover(); r != nil {
 					log.Printf("Reconciliation goroutine panicked with error: %v", r)
+					failedSections.add(fmt.Errorf("reconciliation of PrimaryFileSection: [%v] panicked: %v", primaryFileSection.SectionSequenceNumber, r))
 				}
 			}()
 
-			defer wg.Done()
-
 			log.Printf("Reconciling Primary FileSectionID: [%v], FileID: [%v]",
 				primaryFileSection.SectionSequenceNumber,
 				primaryFileSection.FileID,
 			)
 
 			//reconcile the section from the primary file
-			reconciledFileSection, err := ReconcileFileSection(
+			reconciledFileSection, err := ReconcileFileSectionWithContext(
+				ctx,
 				primaryFileSection,
 				comparisonFileSectionsStream,
 				reconciliationConfigs,
 				comparisonFileID,
+				reconTaskDetails,
 			)
 
 			//error on reconciliation
 			if err != nil {
 				log.Printf(
 					"Error on file section reconciliation. "+
-						"Seq Number: [{%d}], FileID: [{%s}]",
+						"Seq Number: [{%d}], FileID: [{%s}], Error: %v",
 					primaryFileSection.SectionSequenceNumber,
 					primaryFileSection.FileID,
+					err,
 				)
+
+				//a cancelled task is cleaned up once every section has stopped
+				if ctx.Err() == nil {
+					failedSections.add(fmt.Errorf("error on reconciling PrimaryFileSection: [%v], Error: %w", primaryFileSection.SectionSequenceNumber, err))
+				}
 				return
 			}
 
@@ -106,11 +215,17 @@ func BeginFileReconciliation(
 			//we mark them as failed with the reason that no matching row found
 			reconciledFileSection = giveEachRowAFinalReconStatus(reconciledFileSection)
 
+			//only the first section carries the column headers
+			if reconciledFileSection.SectionSequenceNumber != 1 {
+				reconciledFileSection.ColumnHeaders = nil
+				reconciledFileSection.ComparisonColumnHeaders = nil
+			}
+
 			//publish the reconciled file section
 			//to the reconstruction channel
 			toBeReconstructedStreamTopicName := fmt.Sprintf("Reconstruct-%v", reconciledFileSection.TaskID)
 			err = fileReconstructionChannel.PublishToTopic(
-				context.Background(),
+				ctx,
 				toBeReconstructedStreamTopicName,
 				reconciledFileSection,
 			)
@@ -119,13 +234,36 @@ func BeginFileReconciliation(
 			if err != nil {
 				log.Printf(
 					"Failed to publish to reconstruction channel"+
-						"TaskID:[%v] ,Seq Number: [{%v}], FileID: [{%v}]",
+						"TaskID:[%v] ,Seq Number: [{%v}], FileID: [{%v}], Error: %v",
 					primaryFileSection.TaskID,
 					primaryFileSection.SectionSequenceNumber,
 					primaryFileSection.FileID,
+					err,
 				)
+				if ctx.Err() == nil {
+					failedSections.add(fmt.Errorf("error on publishing reconciled PrimaryFileSection: [%v], Error: %v", primaryFileSection.SectionSequenceNumber, err))
+				}
 				return
 			}
+
+			reconTaskDetails.ProgressTracker.RecordSectionReconciled(reconciledFileSection)
+			reconTaskDetails.PublishEvent(
+				task_event_type.SectionReconciled,
+				primaryFileSection.FileID,
+				primaryFileSection.SectionSequenceNumber,
+			)
+
+			if checkpoints != nil {
+				err = checkpoints.RecordSectionReconciled(
+					context.Background(),
+					reconTaskDetails.ID,
+					primaryFileSection.FileID,
+					primaryFileSection.SectionSequenceNumber,
+				)
+				if err != nil {
+					log.Printf("Failed to checkpoint reconciled section. Seq Number: [{%v}], Error: %v", primaryFileSection.SectionSequenceNumber, err)
+				}
+			}
 		}(
 			*primaryFileSection,
 			comparisonFile.ReadFileResultsStream,
@@ -144,6 +282,16 @@ func BeginFileReconciliation(
 	// to finish
 	wg.Wait()
 
+	if ctx.Err() != nil {
+		return cleanUpCancelledFileReconciliation(ctx, primaryFile, comparisonFile)
+	}
+
+	//the streams are kept so that the failed
+	//sections can be reconciled again on a retry
+	if failedSections.any() {
+		return failedSections.join()
+	}
+
 	// clean up the consumers that were created
 	err = primaryFile.ReadFileResultsStream.DeleteStreamConsumer(
 		utils.NewContextWithDefaultTimeout(),
@@ -172,13 +320,83 @@ func BeginFileReconciliation(
 	return nil
 }
 
+// sectionErrors collects the errors of the sections that failed to be reconciled
+type sectionErrors struct {
+	errs []error
+	mu   sync.Mutex
+}
+
+func (e *sectionErrors) add(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errs = append(e.errs, err)
+}
+
+func (e *sectionErrors) any() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.errs) > 0
+}
+
+func (e *sectionErrors) join() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return errors.Join(e.errs...)
+}
+
+func deleteStaleStreamConsumer(stream models.StreamProvider, streamName string, consumerId string) {
+	err := stream.DeleteStreamConsumer(
+		utils.NewContextWithDefaultTimeout(),
+		streamName,
+		consumerId,
+	)
+
+	//most of the time there is no stale consumer to delete
+	if err == nil {
+		log.Printf("Deleted stale stream consumer: [%v]", consumerId)
+	}
+}
+
+// cleanUpCancelledFileReconciliation deletes the consumer and the topics
+// of a file whose task was cancelled, then reports the cancellation.
+func cleanUpCancelledFileReconciliation(
+	ctx context.Context,
+	primaryFile models.FileToBeRead,
+	comparisonFile models.FileToBeRead,
+) error {
+	log.Printf("Reconciliation cancelled for file: [%v]", primaryFile.ID)
+
+	//the streams are kept for a retry when other work of the task failed
+	if models.IsStoppedAfterFailure(ctx) {
+		return ctx.Err()
+	}
+
+	deleteStaleStreamConsumer(primaryFile.ReadFileResultsStream, constants.PRIMARY_FILE_SECTIONS_STREAM_NAME, primaryFile.ID)
+
+	for _, topic := range []struct {
+		stream     models.StreamProvider
+		streamName string
+		topicName  string
+	}{
+		{primaryFile.ReadFileResultsStream, constants.PRIMARY_FILE_SECTIONS_STREAM_NAME, primaryFile.ID},
+		{comparisonFile.ReadFileResultsStream, constants.COMPARISON_FILE_SECTIONS_STREAM_NAME, comparisonFile.ID},
+	} {
+		err := topic.stream.DeleteStreamTopic(utils.NewContextWithDefaultTimeout(), topic.streamName, topic.topicName)
+		if err != nil {
+			log.Printf("Error deleting topic of cancelled task: [%v], Error: %v", topic.topicName, err)
+		}
+	}
+
+	return ctx.Err()
+}
+
 func giveEachRowAFinalReconStatus(reconciledFileSection models.FileSection) models.FileSection {
 	finalReconciledSectionRows := make([]models.FileSectionRow, 0)
 	for _, fileSectionRow := range reconciledFileSection.SectionRows {
 		if fileSectionRow.ReconResult == recon_status.Pending {
 			fileSectionRow.ReconResult = recon_status.Failed
-			fileSectionRow.ReconResultReasons = []string{
-				"no matching record found in the entire comparison file",
+			fileSectionRow.ReconResultReasons = []models.ReconReason{
+				{Code: recon_reason_code.NoMatchFound},
 			}
 			finalReconciledSectionRows = append(finalReconciledSectionRows, fileSectionRow)
 		} else {
@@ -196,9 +414,35 @@ func ReconcileFileSection(
 	reconConfig models.ReconciliationConfigs,
 	comparisonFileID string,
 ) (models.FileSection, error) {
+	return ReconcileFileSectionWithContext(
+		context.Background(),
+		primarySection,
+		comparisonSectionsStream,
+		reconConfig,
+		comparisonFileID,
+		models.ReconTaskDetails{},
+	)
+}
+
+// ReconcileFileSectionWithContext is ReconcileFileSection that gives up
+// as soon as the context is done. It keeps waiting for comparison sections
+// while the task is paused or its comparison file is still being read,
+// otherwise running out of time waiting for one is an error.
+func ReconcileFileSectionWithContext(
+	ctx context.Context,
+	primarySection models.FileSection,
+	comparisonSectionsStream models.StreamProvider,
+	reconConfig models.ReconciliationConfigs,
+	comparisonFileID string,
+	reconTaskDetails models.ReconTaskDetails,
+) (models.FileSection, error) {
 
 	consumerId := fmt.Sprintf("%v-%v", primarySection.SectionSequenceNumber, primarySection.FileID)
 
+	//a consumer left behind by an interrupted run would
+	//carry on from the middle of the comparison file
+	deleteStaleStreamConsumer(comparisonSectionsStream, constants.COMPARISON_FILE_SECTIONS_STREAM_NAME, consumerId)
+
 	comparisonSectionsStreamConsumer, err := comparisonSectionsStream.CreateStreamConsumer(
 		utils.NewContextWithDefaultTimeout(),
 		constants.COMPARISON_FILE_SECTIONS_STREAM_NAME,
@@ -210,20 +454,51 @@ func ReconcileFileSection(
 		return primarySection, err
 	}
 
-	for {
+	var fetchErr error
+	receivedComparisonSections := make(map[int]bool)
+	for ctx.Err() == nil {
 		log.Printf("Waiting for ComparisonFileSection. "+
 			"PrimaryFileSectionID: [%v], FileID: [%v]",
 			primarySection.SectionSequenceNumber,
 			primarySection.FileID,
 		)
 
-		comparisonSection, err := comparisonSectionsStreamConsumer.FetchNext()
+		comparisonSection, err := comparisonSectionsStreamConsumer.FetchNextWithContext(ctx)
 
 		if err != nil {
 			log.Printf("Error getting FetchNext ComparsionSection: %v", err)
+			if ctx.Err() != nil {
+				break
+			}
+
+			//the comparison file is not fully published yet,
+			//its remaining sections are still to come
+			if errors.Is(err, models.ErrFetchTimeout) && isComparisonFileStillBeingRead(reconTaskDetails, comparisonFileID) {
+				continue
+			}
+
+			//the section can't be finalized without
+			//having seen the whole comparison file
+			fetchErr = fmt.Errorf("error on fetching ComparisonFileSection for PrimaryFileSection: [%v], Error: %w", primarySection.SectionSequenceNumber, err)
 			break
 		}
 
+		//a section can be published twice when
+		//reading resumes after a restart
+		if receivedComparisonSections[comparisonSection.SectionSequenceNumber] {
+			if comparisonSection.IsLastSection {
+				break
+			}
+			continue
+		}
+		receivedComparisonSections[comparisonSection.SectionSequenceNumber] = true
+
+		//keep the headers of the comparison file so the
+		//columns of the matched rows can be named on output
+		if len(comparisonSection.ColumnHeaders) > 0 {
+			primarySection.ComparisonColumnHeaders = comparisonSection.ColumnHeaders
+		}
+
 		log.Printf(
 			"Recieved ComparisonFileSection [%v] "+
 				"for PrimaryFileSection [%v], FileID: [%v]",
@@ -275,12 +550,38 @@ func ReconcileFileSection(
 
 	if err != nil {
 		log.Printf("Error deleting ComparsionFileSectionConsumer: [%v], Error: %v", consumerId, err)
-		return primarySection, nil
+	} else {
+		log.Printf("Successfully deleted ComparsionFileSectionConsumer: [%v]", consumerId)
+	}
+
+	if fetchErr != nil {
+		return primarySection, fetchErr
+	}
+	return primarySection, ctx.Err()
+}
+
+// isComparisonFileStillBeingRead is true while the task is paused or more sections
+// of the comparison file are still to be published. Without a progress tracker or
+// checkpoints to go by the file is taken to be fully read.
+func isComparisonFileStillBeingRead(reconTaskDetails models.ReconTaskDetails, comparisonFileID string) bool {
+	if reconTaskDetails.Control.IsPaused() {
+		return true
 	}
 
-	log.Printf("Successfully deleted ComparsionFileSectionConsumer: [%v]", consumerId)
+	if reconTaskDetails.ProgressTracker == nil && reconTaskDetails.Checkpoints == nil {
+		return false
+	}
+
+	if reconTaskDetails.ProgressTracker.IsFileFullyRead(comparisonFileID) {
+		return false
+	}
 
-	return primarySection, nil
+	//the file may have been read before a restart
+	if reconTaskDetails.Checkpoints != nil {
+		_, isFullyRead := reconTaskDetails.Checkpoints.GetLastSectionRead(context.Background(), reconTaskDetails.ID, comparisonFileID)
+		return !isFullyRead
+	}
+	return true
 }
 
 func reconcileWithComparisonSection(primarySection models.FileSection, comparisonSection models.FileSection, reconConfig models.ReconciliationConfigs) models.FileSection {
@@ -292,6 +593,7 @@ func reconcileWithComparisonSection(primarySection models.FileSection, compariso
 				primarySection.ComparisonPairs,
 				reconConfig,
 				primarySection.ColumnHeaders,
+				primarySection.ComparisonColumnHeaders,
 			)
 			if found {
 				log.Printf(
@@ -300,11 +602,14 @@ func reconcileWithComparisonSection(primarySection models.FileSection, compariso
 					primaryRow.RowNumber,
 					comparisonRow.RowNumber,
 				)
+				matchedRowNumber := comparisonRow.RowNumber
 				primarySection.SectionRows[i].ReconResult = rowReconStatus
 				primarySection.SectionRows[i].ReconResultReasons = append(
 					primarySection.SectionRows[i].ReconResultReasons,
 					reasons...,
 				)
+				primarySection.SectionRows[i].MatchedRowNumber = &matchedRowNumber
+				primarySection.SectionRows[i].MatchedColumnsFromRow = comparisonRow.ParsedColumnsFromRow
 				break
 			} else {
 				log.Printf(
@@ -327,7 +632,8 @@ func isRowMatch(
 	comparisonPairs []models.ComparisonPair,
 	reconConfig models.ReconciliationConfigs,
 	columnHeaders []string,
-) (bool, recon_status.ReconciliationStatus, []string) {
+	comparisonColumnHeaders []string,
+) (bool, recon_status.ReconciliationStatus, []models.ReconReason) {
 	//check if this is supposed to be the same row in both files
 	//by using the comparison pair isRowIdentifier flag
 	//a row identifier can be made up of 1 or more comparison pairs
@@ -337,8 +643,13 @@ func isRowMatch(
 	//are the same. if they are the same then we can know
 	//that the rest of the other values in this row must match
 	for _, pair := range rowIdentifierComparisonPairs {
-		primaryValue := primaryRow.ParsedColumnsFromRow[pair.PrimaryFileColumnIndex]
-		comparisonValue := comparisonRow.ParsedColumnsFromRow[pair.ComparisonFileColumnIndex]
+		primaryValue, hasPrimaryValue := columnValue(primaryRow, pair.PrimaryFileColumnIndex)
+		comparisonValue, hasComparisonValue := columnValue(comparisonRow, pair.ComparisonFileColumnIndex)
+
+		//a row that is too short to have the identifier can't be told to be the same row
+		if !hasPrimaryValue || !hasComparisonValue {
+			return false, recon_status.Pending, nil
+		}
 
 		if reconConfig.ShouldReconciliationBeCaseSensitive {
 			if primaryValue != comparisonValue {
@@ -351,58 +662,79 @@ func isRowMatch(
 		}
 	}
 
-	//now we can match the other values in the comparison pair columns
+	//now we can match the other values in the comparison pair columns,
+	//every column that differs is reported so they can all be fixed at once
+	mismatches := make([]models.ReconReason, 0)
 	for _, pair := range nonRowIdComparisonPairs {
-		primaryValue := primaryRow.ParsedColumnsFromRow[pair.PrimaryFileColumnIndex]
-		comparisonValue := comparisonRow.ParsedColumnsFromRow[pair.ComparisonFileColumnIndex]
+		primaryValue, hasPrimaryValue := columnValue(primaryRow, pair.PrimaryFileColumnIndex)
+		comparisonValue, hasComparisonValue := columnValue(comparisonRow, pair.ComparisonFileColumnIndex)
+
+		//a column missing from a short row differs from whatever the other row has
+		if !hasPrimaryValue || !hasComparisonValue {
+			mismatches = append(mismatches, columnMismatchReason(comparisonRow, pair, columnHeaders, comparisonColumnHeaders, primaryValue, comparisonValue))
+			continue
+		}
 
 		if reconConfig.ShouldReconciliationBeCaseSensitive {
 			if primaryValue != comparisonValue {
-				reason := fmt.Sprintf(
-					"RowMismatchFound. \n"+
-						"PrimaryFileRow: [%v] PrimaryFileColumn: [%v] \n"+
-						"ComparisonFileRow: [%v] ComparisonFileColumn: [%v] \n"+
-						"PrimaryFile value: [%v] \n"+
-						"ComparisonFile value: [%v]\n",
-					primaryRow.RowNumber,
-					columnHeaders[pair.PrimaryFileColumnIndex],
-					comparisonRow.RowNumber,
-					columnHeaders[pair.ComparisonFileColumnIndex],
-					primaryValue,
-					comparisonValue,
-				)
-				return true, recon_status.Failed, []string{reason}
+				mismatches = append(mismatches, columnMismatchReason(comparisonRow, pair, columnHeaders, comparisonColumnHeaders, primaryValue, comparisonValue))
 			}
 		} else {
 			if primaryValue != comparisonValue {
-				reason := fmt.Sprintf(
-					"RowMismatchFound. \n"+
-						"PrimaryFileRow: [%v] PrimaryFileColumn: [%v] \n"+
-						"ComparisonFileRow: [%v] ComparisonFileColumn: [%v] \n"+
-						"PrimaryFile value: [%v] \n"+
-						"ComparisonFile value: [%v]\n",
-					primaryRow.RowNumber,
-					columnHeaders[pair.PrimaryFileColumnIndex],
-					comparisonRow.RowNumber,
-					columnHeaders[pair.ComparisonFileColumnIndex],
-					primaryValue,
-					comparisonValue,
-				)
-				return true, recon_status.Failed, []string{reason}
+				mismatches = append(mismatches, columnMismatchReason(comparisonRow, pair, columnHeaders, comparisonColumnHeaders, primaryValue, comparisonValue))
 			}
 		}
 	}
 
+	if len(mismatches) > 0 {
+		return true, recon_status.Failed, mismatches
+	}
+
 	// by this time, we know that all the values in the row
 	// are the exact same. We can mark the row as reconciled
-	reason := fmt.Sprintf(
-		"RowMatchFound. \n"+
-			"PrimaryFile Row: [%v] \n"+
-			"ComparisonFile Row: [%v] \n",
-		primaryRow.RowNumber,
-		comparisonRow.RowNumber,
-	)
-	return true, recon_status.Successfull, []string{reason}
+	counterpartRowNumber := comparisonRow.RowNumber
+	reason := models.ReconReason{
+		Code:                 recon_reason_code.RowMatchFound,
+		CounterpartRowNumber: &counterpartRowNumber,
+	}
+	return true, recon_status.Successfull, []models.ReconReason{reason}
+}
+
+// columnMismatchReason is the reason a row failed because a compared column differs from the matched row,
+// the columns are named by the headers of their own files
+func columnMismatchReason(
+	comparisonRow models.FileSectionRow,
+	pair models.ComparisonPair,
+	columnHeaders []string,
+	comparisonColumnHeaders []string,
+	primaryValue string,
+	comparisonValue string,
+) models.ReconReason {
+	counterpartRowNumber := comparisonRow.RowNumber
+	return models.ReconReason{
+		Code:                 recon_reason_code.RowMismatchFound,
+		PrimaryColumn:        columnHeader(columnHeaders, pair.PrimaryFileColumnIndex),
+		ComparisonColumn:     columnHeader(comparisonColumnHeaders, pair.ComparisonFileColumnIndex),
+		PrimaryValue:         primaryValue,
+		ComparisonValue:      comparisonValue,
+		CounterpartRowNumber: &counterpartRowNumber,
+	}
+}
+
+// columnValue is the value of the row in the column at the index, false when the row is too short to have it
+func columnValue(row models.FileSectionRow, index int) (string, bool) {
+	if index < 0 || index >= len(row.ParsedColumnsFromRow) {
+		return "", false
+	}
+	return row.ParsedColumnsFromRow[index], true
+}
+
+// columnHeader is the header of the column at the index, or empty when the file has no such header
+func columnHeader(columnHeaders []string, index int) string {
+	if index < 0 || index >= len(columnHeaders) {
+		return ""
+	}
+	return columnHeaders[index]
 }
 
 func getRowIdentifierComparisonPairs(
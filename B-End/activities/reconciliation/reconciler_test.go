@@ -1,11 +1,18 @@
 package reconciliation
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_reason_code"
 	"reconciler.io/models/enums/recon_status"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestBeginFileReconciliationActivity(t *testing.T) {
@@ -38,8 +45,8 @@ var _ = Describe("reconcileWithComparisonSection", func() {
 							"column_1", "column_2",
 						},
 						ReconResult: recon_status.Successfull,
-						ReconResultReasons: []string{
-							"RowMatchFound. \nPrimaryFile Row: [1] \nComparisonFile Row: [2] \n",
+						ReconResultReasons: []models.ReconReason{
+							{Code: recon_reason_code.RowMatchFound},
 						},
 					},
 				},
@@ -107,8 +114,8 @@ var _ = Describe("reconcileWithComparisonSection", func() {
 					"column_1", "column_2",
 				},
 				ReconResult: recon_status.Successfull,
-				ReconResultReasons: []string{
-					"RowMatchFound. \nPrimaryFile Row: [1] \nComparisonFile Row: [2] \n",
+				ReconResultReasons: []models.ReconReason{
+					{Code: recon_reason_code.RowMatchFound},
 				},
 			},
 		},
@@ -143,3 +150,473 @@ var _ = Describe("reconcileWithComparisonSection", func() {
 		Expect(actualReconResults).To(Equal(expectedReconResults))
 	})
 })
+
+var _ = Describe("isRowMatch", func() {
+	comparisonPairs := []models.ComparisonPair{
+		{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 0, IsRowIdentifier: true},
+		{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 1, IsRowIdentifier: false},
+		{PrimaryFileColumnIndex: 2, ComparisonFileColumnIndex: 2, IsRowIdentifier: false},
+	}
+	columnHeaders := []string{"Id", "Amount", "Date"}
+	comparisonColumnHeaders := []string{"Reference", "Value", "Posted"}
+	comparisonRowNumber := uint64(4)
+
+	Context("when the rows have the same identifiers but a column differs", func() {
+		It("should fail the row with the column that differs as its reason", func() {
+			found, status, reasons := isRowMatch(
+				models.FileSectionRow{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				models.FileSectionRow{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "150", "2024-01-01"}},
+				comparisonPairs,
+				models.ReconciliationConfigs{},
+				columnHeaders,
+				comparisonColumnHeaders,
+			)
+			Expect(found).To(BeTrue())
+			Expect(status).To(Equal(recon_status.Failed))
+			Expect(reasons).To(Equal([]models.ReconReason{{
+				Code:                 recon_reason_code.RowMismatchFound,
+				PrimaryColumn:        "Amount",
+				ComparisonColumn:     "Value",
+				PrimaryValue:         "100",
+				ComparisonValue:      "150",
+				CounterpartRowNumber: &comparisonRowNumber,
+			}}))
+		})
+	})
+
+	Context("when the rows have the same identifiers but several columns differ", func() {
+		It("should give every column that differs as a reason", func() {
+			found, status, reasons := isRowMatch(
+				models.FileSectionRow{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				models.FileSectionRow{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "150", "2024-01-02"}},
+				comparisonPairs,
+				models.ReconciliationConfigs{ShouldReconciliationBeCaseSensitive: true},
+				columnHeaders,
+				comparisonColumnHeaders,
+			)
+			Expect(found).To(BeTrue())
+			Expect(status).To(Equal(recon_status.Failed))
+			Expect(reasons).To(HaveLen(2))
+			Expect(reasons[0].PrimaryColumn).To(Equal("Amount"))
+			Expect(reasons[1].PrimaryColumn).To(Equal("Date"))
+			Expect(reasons[1].ComparisonColumn).To(Equal("Posted"))
+			Expect(reasons[1].PrimaryValue).To(Equal("2024-01-01"))
+			Expect(reasons[1].ComparisonValue).To(Equal("2024-01-02"))
+			Expect(*reasons[1].CounterpartRowNumber).To(Equal(comparisonRowNumber))
+		})
+	})
+
+	Context("when a column that differs has no header", func() {
+		It("should leave the column unnamed", func() {
+			found, status, reasons := isRowMatch(
+				models.FileSectionRow{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				models.FileSectionRow{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "100", "2024-01-02"}},
+				comparisonPairs,
+				models.ReconciliationConfigs{},
+				columnHeaders,
+				comparisonColumnHeaders[:2],
+			)
+			Expect(found).To(BeTrue())
+			Expect(status).To(Equal(recon_status.Failed))
+			Expect(reasons[0].PrimaryColumn).To(Equal("Date"))
+			Expect(reasons[0].ComparisonColumn).To(BeEmpty())
+		})
+	})
+
+	Context("when a row is too short to have a compared column", func() {
+		It("should fail the row with the missing column as its reason", func() {
+			found, status, reasons := isRowMatch(
+				models.FileSectionRow{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				models.FileSectionRow{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "100"}},
+				comparisonPairs,
+				models.ReconciliationConfigs{},
+				columnHeaders,
+				comparisonColumnHeaders,
+			)
+			Expect(found).To(BeTrue())
+			Expect(status).To(Equal(recon_status.Failed))
+			Expect(reasons).To(HaveLen(1))
+			Expect(reasons[0].PrimaryColumn).To(Equal("Date"))
+			Expect(reasons[0].ComparisonColumn).To(Equal("Posted"))
+			Expect(reasons[0].PrimaryValue).To(Equal("2024-01-01"))
+			Expect(reasons[0].ComparisonValue).To(BeEmpty())
+		})
+	})
+
+	Context("when a row is too short to have its identifier", func() {
+		It("should not take it for the same row", func() {
+			found, status, reasons := isRowMatch(
+				models.FileSectionRow{RowNumber: 1, ParsedColumnsFromRow: []string{}},
+				models.FileSectionRow{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				comparisonPairs,
+				models.ReconciliationConfigs{},
+				columnHeaders,
+				comparisonColumnHeaders,
+			)
+			Expect(found).To(BeFalse())
+			Expect(status).To(Equal(recon_status.Pending))
+			Expect(reasons).To(BeEmpty())
+		})
+	})
+
+	Context("when the rows match", func() {
+		It("should give the matched row as its reason", func() {
+			found, status, reasons := isRowMatch(
+				models.FileSectionRow{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				models.FileSectionRow{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "100", "2024-01-01"}},
+				comparisonPairs,
+				models.ReconciliationConfigs{},
+				columnHeaders,
+				comparisonColumnHeaders,
+			)
+			Expect(found).To(BeTrue())
+			Expect(status).To(Equal(recon_status.Successfull))
+			Expect(reasons).To(Equal([]models.ReconReason{{
+				Code:                 recon_reason_code.RowMatchFound,
+				CounterpartRowNumber: &comparisonRowNumber,
+			}}))
+		})
+	})
+})
+
+var _ = Describe("reconcileWithComparisonSection matched rows", func() {
+	It("should carry the columns of the comparison row each row was matched to", func() {
+		primarySection := models.FileSection{
+			ColumnHeaders: []string{"Id", "Amount"},
+			ComparisonPairs: []models.ComparisonPair{
+				{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 0, IsRowIdentifier: true},
+				{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 1},
+			},
+			SectionRows: []models.FileSectionRow{
+				{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100"}, ReconResult: recon_status.Pending},
+				{RowNumber: 2, ParsedColumnsFromRow: []string{"8", "200"}, ReconResult: recon_status.Pending},
+			},
+		}
+		comparisonSection := models.FileSection{
+			SectionRows: []models.FileSectionRow{
+				{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "150"}},
+			},
+		}
+
+		reconciledSection := reconcileWithComparisonSection(primarySection, comparisonSection, models.ReconciliationConfigs{})
+
+		Expect(reconciledSection.SectionRows[0].MatchedColumnsFromRow).To(Equal([]string{"7", "150"}))
+		Expect(reconciledSection.SectionRows[1].MatchedColumnsFromRow).To(BeNil())
+	})
+})
+
+// comparisonSections hands out its sections to every consumer created on it,
+// the first fetchTimeouts fetches run out of time and beforeSections is called before the first section.
+// Fetching panics with panicWith when it is set
+type comparisonSections struct {
+	sections       []models.FileSection
+	fetchTimeouts  int
+	beforeSections func()
+	panicWith      error
+}
+
+// slowPanic takes a while to describe, as the recovery of a panicked section does before recording it
+type slowPanic struct{}
+
+func (p slowPanic) Error() string {
+	time.Sleep(100 * time.Millisecond)
+	return "comparison stream broke"
+}
+
+func (c *comparisonSections) SetupStream(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (c *comparisonSections) DeleteStreamTopic(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (c *comparisonSections) PublishToTopic(ctx context.Context, topicName string, data interface{}) error {
+	return nil
+}
+
+func (c *comparisonSections) CreateStreamConsumer(ctx context.Context, streamName, topicName, consumerName string) (models.StreamConsumer, error) {
+	return &comparisonSectionsConsumer{stream: c}, nil
+}
+
+func (c *comparisonSections) DeleteStreamConsumer(ctx context.Context, streamName string, consumerName string) error {
+	return nil
+}
+
+func (c *comparisonSections) Close(ctx context.Context) error {
+	return nil
+}
+
+type comparisonSectionsConsumer struct {
+	stream  *comparisonSections
+	fetched int
+}
+
+func (c *comparisonSectionsConsumer) FetchNext() (*models.FileSection, error) {
+	return c.FetchNextWithContext(context.Background())
+}
+
+func (c *comparisonSectionsConsumer) FetchNextWithContext(ctx context.Context) (*models.FileSection, error) {
+	if c.stream.panicWith != nil {
+		panic(c.stream.panicWith)
+	}
+	if c.stream.fetchTimeouts > 0 {
+		c.stream.fetchTimeouts--
+		return nil, fmt.Errorf("error getting Next FileSection: %w", models.ErrFetchTimeout)
+	}
+	if c.fetched == 0 && c.stream.beforeSections != nil {
+		c.stream.beforeSections()
+	}
+	section := c.stream.sections[c.fetched]
+	c.fetched++
+	return &section, nil
+}
+
+var _ = Describe("ReconcileFileSectionWithContext", func() {
+	var (
+		primarySection models.FileSection
+		stream         *comparisonSections
+	)
+
+	BeforeEach(func() {
+		primarySection = models.FileSection{
+			SectionSequenceNumber: 1,
+			FileID:                "PrimaryFile-1",
+			ColumnHeaders:         []string{"Id", "Amount"},
+			ComparisonPairs: []models.ComparisonPair{
+				{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 0, IsRowIdentifier: true},
+				{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 1},
+			},
+			SectionRows: []models.FileSectionRow{
+				{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100"}, ReconResult: recon_status.Pending},
+			},
+		}
+		stream = &comparisonSections{
+			sections: []models.FileSection{{
+				SectionSequenceNumber: 1,
+				IsLastSection:         true,
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 4, ParsedColumnsFromRow: []string{"7", "100"}},
+				},
+			}},
+		}
+	})
+
+	Context("when the task is paused for longer than the fetch timeout", func() {
+		It("should keep waiting for the comparison sections until the task is resumed", func() {
+			control := models.NewTaskControl()
+			control.Pause()
+			stream.fetchTimeouts = 3
+			stream.beforeSections = control.Resume
+
+			reconciledSection, err := ReconcileFileSectionWithContext(
+				context.Background(),
+				primarySection,
+				stream,
+				models.ReconciliationConfigs{},
+				"ComparisonFile-1",
+				models.ReconTaskDetails{ID: "task_1", Control: control},
+			)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stream.fetchTimeouts).To(Equal(0))
+			Expect(reconciledSection.SectionRows[0].ReconResult).To(Equal(recon_status.Successfull))
+		})
+	})
+
+	Context("when the comparison file is still being read", func() {
+		It("should keep waiting for its remaining sections", func() {
+			progressTracker := models.NewTaskProgressTracker()
+			stream.fetchTimeouts = 2
+			stream.beforeSections = func() {
+				progressTracker.RecordSectionRead("ComparisonFile-1", file_purpose.ComparisonFile, 1, 10, 10, true)
+			}
+
+			reconciledSection, err := ReconcileFileSectionWithContext(
+				context.Background(),
+				primarySection,
+				stream,
+				models.ReconciliationConfigs{},
+				"ComparisonFile-1",
+				models.ReconTaskDetails{ID: "task_1", ProgressTracker: progressTracker},
+			)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reconciledSection.SectionRows[0].ReconResult).To(Equal(recon_status.Successfull))
+		})
+	})
+
+	Context("when no comparison section arrives after the comparison file was fully read", func() {
+		It("should return the timeout without finalizing the section", func() {
+			progressTracker := models.NewTaskProgressTracker()
+			progressTracker.RecordSectionRead("ComparisonFile-1", file_purpose.ComparisonFile, 1, 10, 10, true)
+			stream.fetchTimeouts = 1
+
+			reconciledSection, err := ReconcileFileSectionWithContext(
+				context.Background(),
+				primarySection,
+				stream,
+				models.ReconciliationConfigs{},
+				"ComparisonFile-1",
+				models.ReconTaskDetails{ID: "task_1", ProgressTracker: progressTracker},
+			)
+
+			Expect(err).To(MatchError(models.ErrFetchTimeout))
+			Expect(reconciledSection.SectionRows[0].ReconResult).To(Equal(recon_status.Pending))
+		})
+	})
+})
+
+var _ = Describe("BeginFileReconciliation", func() {
+	Context("when a section fails to be reconciled", func() {
+		It("should return the error of the section instead of finishing", func() {
+			primarySections := &comparisonSections{
+				sections: []models.FileSection{{
+					SectionSequenceNumber: 1,
+					FileID:                "PrimaryFile-1",
+					IsLastSection:         true,
+					SectionRows: []models.FileSectionRow{
+						{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100"}, ReconResult: recon_status.Pending},
+					},
+				}},
+			}
+
+			// the comparison file never gets to the section
+			comparisonFileSections := &comparisonSections{fetchTimeouts: 1}
+
+			err := BeginFileReconciliation(
+				context.Background(),
+				models.FileToBeRead{ID: "PrimaryFile-1", ReadFileResultsStream: primarySections},
+				models.FileToBeRead{ID: "ComparisonFile-1", ReadFileResultsStream: comparisonFileSections},
+				models.ReconTaskDetails{
+					ID:                           "task_1",
+					ComparisonPairs:              []models.ComparisonPair{{IsRowIdentifier: true}},
+					FileToBeReconstructedChannel: &comparisonSections{},
+				},
+			)
+
+			Expect(err).To(MatchError(models.ErrFetchTimeout))
+		})
+	})
+
+	Context("when the reconciliation of a section panics", func() {
+		It("should return the panic instead of finishing", func() {
+			primarySections := &comparisonSections{
+				sections: []models.FileSection{{
+					SectionSequenceNumber: 1,
+					FileID:                "PrimaryFile-1",
+					IsLastSection:         true,
+					SectionRows: []models.FileSectionRow{
+						{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100"}, ReconResult: recon_status.Pending},
+					},
+				}},
+			}
+
+			comparisonFileSections := &comparisonSections{panicWith: slowPanic{}}
+
+			err := BeginFileReconciliation(
+				context.Background(),
+				models.FileToBeRead{ID: "PrimaryFile-1", ReadFileResultsStream: primarySections},
+				models.FileToBeRead{ID: "ComparisonFile-1", ReadFileResultsStream: comparisonFileSections},
+				models.ReconTaskDetails{
+					ID:                           "task_1",
+					ComparisonPairs:              []models.ComparisonPair{{IsRowIdentifier: true}},
+					FileToBeReconstructedChannel: &comparisonSections{},
+				},
+			)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("panicked: comparison stream broke"))
+		})
+	})
+})
+
+// fileStreams fails every fetch from failingTopic with fetchErr,
+// fetches from its other topics wait until the context is done
+type fileStreams struct {
+	failingTopic  string
+	fetchErr      error
+	deletedTopics []string
+	mu            sync.Mutex
+}
+
+func (f *fileStreams) SetupStream(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (f *fileStreams) DeleteStreamTopic(ctx context.Context, streamName string, topicName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deletedTopics = append(f.deletedTopics, topicName)
+	return nil
+}
+
+func (f *fileStreams) PublishToTopic(ctx context.Context, topicName string, data interface{}) error {
+	return nil
+}
+
+func (f *fileStreams) CreateStreamConsumer(ctx context.Context, streamName, topicName, consumerName string) (models.StreamConsumer, error) {
+	return &fileStreamsConsumer{streams: f, topicName: topicName}, nil
+}
+
+func (f *fileStreams) DeleteStreamConsumer(ctx context.Context, streamName string, consumerName string) error {
+	return nil
+}
+
+func (f *fileStreams) Close(ctx context.Context) error {
+	return nil
+}
+
+func (f *fileStreams) topicsDeleted() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.deletedTopics...)
+}
+
+type fileStreamsConsumer struct {
+	streams   *fileStreams
+	topicName string
+}
+
+func (c *fileStreamsConsumer) FetchNext() (*models.FileSection, error) {
+	return c.FetchNextWithContext(context.Background())
+}
+
+func (c *fileStreamsConsumer) FetchNextWithContext(ctx context.Context) (*models.FileSection, error) {
+	if c.topicName == c.streams.failingTopic {
+		return nil, c.streams.fetchErr
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var _ = Describe("BeginPartitionedFileReconciliation", func() {
+	Context("when a file fails to be partitioned", func() {
+		It("should stop reconciling the partitions and return the error", func() {
+			decodeErr := errors.New("error on decoding FileSection")
+			streams := &fileStreams{failingTopic: "PrimaryFile-1", fetchErr: decodeErr}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- BeginPartitionedFileReconciliation(
+					context.Background(),
+					models.FileToBeRead{ID: "PrimaryFile-1", FilePurpose: file_purpose.PrimaryFile, ReadFileResultsStream: streams},
+					models.FileToBeRead{ID: "ComparisonFile-1", FilePurpose: file_purpose.ComparisonFile, ReadFileResultsStream: streams},
+					models.ReconTaskDetails{
+						ID:                           "task_1",
+						PartitionCount:               2,
+						ComparisonPairs:              []models.ComparisonPair{{IsRowIdentifier: true}},
+						FileToBeReconstructedChannel: &comparisonSections{},
+					},
+				)
+			}()
+
+			var err error
+			Eventually(done, 5*time.Second).Should(Receive(&err))
+			Expect(err).To(MatchError(decodeErr))
+
+			// the streams are kept so that the files can be partitioned again on a retry
+			Expect(streams.topicsDeleted()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,223 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"reconciler.io/constants"
+	"sync"
+	"time"
+)
+
+// ErrSectionWorkerPoolDraining is returned to callers that want a worker
+// after the pool has started draining for shutdown.
+var ErrSectionWorkerPoolDraining = errors.New("section worker pool is draining")
+
+// drainPollInterval is how often Drain checks whether the active workers have finished
+var drainPollInterval = 50 * time.Millisecond
+
+// DefaultSectionWorkerPool is the pool shared by every task reconciled by this process.
+var DefaultSectionWorkerPool = NewSectionWorkerPool(
+	constants.MAX_CONCURRENT_SECTION_RECONCILIATIONS,
+	constants.MAX_CONCURRENT_SECTION_RECONCILIATIONS_PER_TASK,
+)
+
+// SectionWorkerPool limits how many primary file sections are reconciled at the same time,
+// both across the whole process and for each individual task.
+// Callers that cannot get a slot block in AcquireWorker, which gives the
+// primary file sections stream consumer backpressure.
+type SectionWorkerPool struct {
+	globalWorkerSlots   chan struct{}
+	defaultTaskLimit    int
+	taskWorkerSlots     map[string]chan struct{}
+	registrationsByTask map[string]int
+	activeWorkersByTask map[string]int
+	queuedByTask        map[string]int
+	draining            chan struct{}
+	isDraining          bool
+	mu                  sync.Mutex
+}
+
+// SectionWorkerPoolStats is a point in time snapshot of a SectionWorkerPool.
+type SectionWorkerPoolStats struct {
+	MaxWorkers          int
+	ActiveWorkers       int
+	QueueDepth          int
+	ActiveWorkersByTask map[string]int
+	QueueDepthByTask    map[string]int
+}
+
+func NewSectionWorkerPool(maxWorkers int, maxWorkersPerTask int) *SectionWorkerPool {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	if maxWorkersPerTask <= 0 || maxWorkersPerTask > maxWorkers {
+		maxWorkersPerTask = maxWorkers
+	}
+	return &SectionWorkerPool{
+		globalWorkerSlots:   make(chan struct{}, maxWorkers),
+		defaultTaskLimit:    maxWorkersPerTask,
+		taskWorkerSlots:     make(map[string]chan struct{}),
+		registrationsByTask: make(map[string]int),
+		activeWorkersByTask: make(map[string]int),
+		queuedByTask:        make(map[string]int),
+		draining:            make(chan struct{}),
+	}
+}
+
+// RegisterTask sets the number of sections of a task that may be reconciled concurrently.
+// A limit of zero or less falls back to the pool's per task default,
+// a limit above the capacity of the pool is cut down to the capacity.
+// Every partition of a task registers the task, the first registration sets the limit
+// and the limit is shared by all of them.
+func (p *SectionWorkerPool) RegisterTask(taskID string, maxWorkers int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.registrationsByTask[taskID]++
+	if _, exists := p.taskWorkerSlots[taskID]; exists {
+		return
+	}
+
+	if maxWorkers <= 0 {
+		maxWorkers = p.defaultTaskLimit
+	}
+	if maxWorkers > cap(p.globalWorkerSlots) {
+		maxWorkers = cap(p.globalWorkerSlots)
+	}
+	p.taskWorkerSlots[taskID] = make(chan struct{}, maxWorkers)
+}
+
+// AcquireWorker blocks until both a task slot and a global slot are free,
+// the context is done or the pool starts draining.
+func (p *SectionWorkerPool) AcquireWorker(ctx context.Context, taskID string) error {
+	p.mu.Lock()
+	if p.isDraining {
+		p.mu.Unlock()
+		return ErrSectionWorkerPoolDraining
+	}
+	taskSlots, exists := p.taskWorkerSlots[taskID]
+	if !exists {
+		taskSlots = make(chan struct{}, p.defaultTaskLimit)
+		p.taskWorkerSlots[taskID] = taskSlots
+	}
+	p.queuedByTask[taskID]++
+	p.mu.Unlock()
+
+	// always take the task slot first so that a task which is
+	// already at its limit does not hold on to global slots
+	select {
+	case taskSlots <- struct{}{}:
+	case <-ctx.Done():
+		p.leaveQueue(taskID)
+		return ctx.Err()
+	case <-p.draining:
+		p.leaveQueue(taskID)
+		return ErrSectionWorkerPoolDraining
+	}
+
+	select {
+	case p.globalWorkerSlots <- struct{}{}:
+	case <-ctx.Done():
+		<-taskSlots
+		p.leaveQueue(taskID)
+		return ctx.Err()
+	case <-p.draining:
+		<-taskSlots
+		p.leaveQueue(taskID)
+		return ErrSectionWorkerPoolDraining
+	}
+
+	p.mu.Lock()
+	p.queuedByTask[taskID]--
+	p.activeWorkersByTask[taskID]++
+	p.mu.Unlock()
+	return nil
+}
+
+// ReleaseWorker frees the slots taken by a successful AcquireWorker.
+func (p *SectionWorkerPool) ReleaseWorker(taskID string) {
+	p.mu.Lock()
+	taskSlots := p.taskWorkerSlots[taskID]
+	p.activeWorkersByTask[taskID]--
+	p.mu.Unlock()
+
+	<-p.globalWorkerSlots
+	<-taskSlots
+}
+
+// UnregisterTask drops the bookkeeping for a task once its last registration
+// is gone and it has no active or queued workers.
+func (p *SectionWorkerPool) UnregisterTask(taskID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.registrationsByTask[taskID] > 0 {
+		p.registrationsByTask[taskID]--
+	}
+	if p.registrationsByTask[taskID] > 0 || p.activeWorkersByTask[taskID] > 0 || p.queuedByTask[taskID] > 0 {
+		return
+	}
+	delete(p.registrationsByTask, taskID)
+	delete(p.taskWorkerSlots, taskID)
+	delete(p.activeWorkersByTask, taskID)
+	delete(p.queuedByTask, taskID)
+}
+
+// Drain stops the pool from handing out any more workers, including to callers
+// already queued in AcquireWorker, and waits until every active worker has been
+// released or the context is done.
+func (p *SectionWorkerPool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.isDraining {
+		p.isDraining = true
+		close(p.draining)
+	}
+	p.mu.Unlock()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if p.Stats().ActiveWorkers == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// IsDraining reports whether Drain has been called on the pool.
+func (p *SectionWorkerPool) IsDraining() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.isDraining
+}
+
+func (p *SectionWorkerPool) Stats() SectionWorkerPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := SectionWorkerPoolStats{
+		MaxWorkers:          cap(p.globalWorkerSlots),
+		ActiveWorkersByTask: make(map[string]int),
+		QueueDepthByTask:    make(map[string]int),
+	}
+	for taskID, activeWorkers := range p.activeWorkersByTask {
+		stats.ActiveWorkers += activeWorkers
+		stats.ActiveWorkersByTask[taskID] = activeWorkers
+	}
+	for taskID, queued := range p.queuedByTask {
+		stats.QueueDepth += queued
+		stats.QueueDepthByTask[taskID] = queued
+	}
+	return stats
+}
+
+func (p *SectionWorkerPool) leaveQueue(taskID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queuedByTask[taskID]--
+}
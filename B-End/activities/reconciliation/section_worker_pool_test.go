@@ -0,0 +1,116 @@
+package reconciliation
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("SectionWorkerPool", func() {
+	var pool *SectionWorkerPool
+
+	BeforeEach(func() {
+		pool = NewSectionWorkerPool(3, 2)
+	})
+
+	Context("when a task reaches its own limit", func() {
+		It("should queue further sections of that task until a worker is released", func() {
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+
+			acquired := make(chan error)
+			go func() {
+				acquired <- pool.AcquireWorker(context.Background(), "task_1")
+			}()
+
+			Eventually(func() int { return pool.Stats().QueueDepthByTask["task_1"] }).Should(Equal(1))
+			Consistently(acquired, 50*time.Millisecond).ShouldNot(Receive())
+
+			pool.ReleaseWorker("task_1")
+			Eventually(acquired).Should(Receive(BeNil()))
+
+			stats := pool.Stats()
+			Expect(stats.ActiveWorkers).To(Equal(2))
+			Expect(stats.QueueDepth).To(Equal(0))
+		})
+	})
+
+	Context("when the global limit is reached", func() {
+		It("should hold back sections of other tasks", func() {
+			pool.RegisterTask("task_1", 3)
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			Expect(pool.AcquireWorker(ctx, "task_2")).To(MatchError(context.DeadlineExceeded))
+
+			stats := pool.Stats()
+			Expect(stats.ActiveWorkers).To(Equal(3))
+			Expect(stats.ActiveWorkersByTask["task_1"]).To(Equal(3))
+			Expect(stats.QueueDepthByTask["task_2"]).To(Equal(0))
+		})
+	})
+
+	Context("when a task asks for more workers than the pool has", func() {
+		It("should get every worker of the pool rather than the per task default", func() {
+			pool.RegisterTask("task_1", 10)
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			Expect(pool.AcquireWorker(ctx, "task_1")).To(MatchError(context.DeadlineExceeded))
+			Expect(pool.Stats().ActiveWorkersByTask["task_1"]).To(Equal(3))
+		})
+	})
+
+	Context("when a task is unregistered after finishing", func() {
+		It("should drop its bookkeeping", func() {
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			pool.ReleaseWorker("task_1")
+			pool.UnregisterTask("task_1")
+
+			stats := pool.Stats()
+			Expect(stats.ActiveWorkersByTask).NotTo(HaveKey("task_1"))
+			Expect(stats.QueueDepthByTask).NotTo(HaveKey("task_1"))
+		})
+	})
+
+	Context("when the pool is drained", func() {
+		It("should turn away queued and new sections and wait for the active ones", func() {
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+
+			queued := make(chan error)
+			go func() {
+				queued <- pool.AcquireWorker(context.Background(), "task_1")
+			}()
+			Eventually(func() int { return pool.Stats().QueueDepthByTask["task_1"] }).Should(Equal(1))
+
+			drained := make(chan error)
+			go func() {
+				drained <- pool.Drain(context.Background())
+			}()
+
+			Eventually(queued).Should(Receive(MatchError(ErrSectionWorkerPoolDraining)))
+			Expect(pool.AcquireWorker(context.Background(), "task_2")).To(MatchError(ErrSectionWorkerPoolDraining))
+			Consistently(drained, 100*time.Millisecond).ShouldNot(Receive())
+
+			pool.ReleaseWorker("task_1")
+			pool.ReleaseWorker("task_1")
+			Eventually(drained).Should(Receive(BeNil()))
+		})
+
+		It("should give up waiting when the deadline passes", func() {
+			Expect(pool.AcquireWorker(context.Background(), "task_1")).To(Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			Expect(pool.Drain(ctx)).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+})
@@ -1,24 +1,75 @@
 package reconstruction
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"reconciler.io/constants"
 	"reconciler.io/models"
+	"reconciler.io/models/enums/results_format"
+	"reconciler.io/models/enums/supported_file_extensions"
 	"reconciler.io/utils"
-	"sort"
 	"strings"
 )
 
-func ReconstructFile(taskId string, reconstructFileSectionsStream models.StreamProvider, outputPath string) error {
-	var fileSections []models.FileSection
+// resultsFileExtensions are the extensions of the results file in each format
+var resultsFileExtensions = map[results_format.ResultsFormat]string{
+	results_format.Csv:        string(supported_file_extensions.Csv),
+	results_format.Xlsx:       "xlsx",
+	results_format.Html:       "html",
+	results_format.Pdf:        "pdf",
+	results_format.SideBySide: "side-by-side." + string(supported_file_extensions.Csv),
+	results_format.JsonLines:  "jsonl",
+}
+
+// ResultsFilePath is the key in the file store the reconciliation results of a task are kept under,
+// in the main results format of the task.
+func ResultsFilePath(taskDetails models.ReconTaskDetails) string {
+	resultsFilePath := utils.GenerateFilePath(resultsFilePrefix(taskDetails), taskDetails.UserID, supported_file_extensions.Csv)
+	return ResultsFilePathIn(resultsFilePath, taskDetails.MainResultsFormat())
+}
+
+// resultsFilePrefix is what the name of every results file of the task starts with
+func resultsFilePrefix(taskDetails models.ReconTaskDetails) string {
+	return fmt.Sprintf("ReconResults-%v", taskDetails.ID)
+}
+
+// ResultsFilePathIn is where the results written next to the results file are, in the given format.
+func ResultsFilePathIn(resultsFilePath string, format results_format.ResultsFormat) string {
+	return strings.TrimSuffix(resultsFilePath, filepath.Ext(resultsFilePath)) + "." + resultsFileExtensions[format]
+}
+
+// ReconstructFile writes the results of the task as its sections are reconciled
+// and keeps them in the file store of the task under the results file key,
+// along with a file next to it for every other format the task writes its results in.
+// It returns the summary of the results once every section has been reconciled.
+// Sections are written in row order as soon as the ones before them have arrived,
+// only the sections that arrive before their turn are held until it comes.
+// It stops as soon as the context is done.
+func ReconstructFile(ctx context.Context, taskDetails models.ReconTaskDetails, resultsFileKey string) (models.ReconSummary, error) {
+	taskId := taskDetails.ID
+	reconstructFileSectionsStream := taskDetails.FileToBeReconstructedChannel
+
+	if taskDetails.Files == nil {
+		return models.ReconSummary{}, errors.New("no file store to keep the results files in")
+	}
 
 	// Read all pre-processing sections from the stream
 	toBeReconstructedStreamTopicName := fmt.Sprintf("Reconstruct-%v", taskId)
 	consumerId := fmt.Sprintf("Reconstruct-%v-Consumer", taskId)
 
+	// always start from the first reconciled section, sections received
+	// before a restart were only ever written to files that were never finished
+	_ = reconstructFileSectionsStream.DeleteStreamConsumer(
+		utils.NewContextWithDefaultTimeout(),
+		constants.FILE_RECONSTRUCTION_STREAM_NAME,
+		consumerId,
+	)
+
 	reconstructFileSectionsStreamConsumer, err := reconstructFileSectionsStream.CreateStreamConsumer(
 		utils.NewContextWithDefaultTimeout(),
 		constants.FILE_RECONSTRUCTION_STREAM_NAME,
@@ -28,34 +79,106 @@ func ReconstructFile(taskId string, reconstructFileSectionsStream models.StreamP
 
 	if err != nil {
 		log.Printf("error on creating fileReconstructionStreamConsumer: %v", err)
-		return err
+		return models.ReconSummary{}, err
+	}
+
+	reorderBuffer := newSectionReorderBuffer(constants.RECONSTRUCTION_SPILL_DIRECTORY, constants.RECONSTRUCTION_REORDER_BUFFER_SECTIONS)
+	defer reorderBuffer.close()
+
+	// the results files are written on the local disk first,
+	// they are only put in the file store once they are complete
+	stagingDirectory, err := os.MkdirTemp(constants.RECONSTRUCTION_SPILL_DIRECTORY, "results-*")
+	if err != nil {
+		return models.ReconSummary{}, fmt.Errorf("error on creating results staging directory: [%v]", err)
 	}
+	defer os.RemoveAll(stagingDirectory)
+	outputPath := filepath.Join(stagingDirectory, path.Base(resultsFileKey))
 
-	//receive all file sections and make sure the file has been reconstructed
-	for {
-		section, err := reconstructFileSectionsStreamConsumer.FetchNext()
+	output := newReconResultsOutput(ctx, taskDetails, outputPath)
+	defer output.abort()
+
+	// the results files are started with the first row,
+	// by then the sections carrying the column headers have arrived
+	writeRow := func(row models.FileSectionRow) error {
+		if !output.isOpen {
+			err := output.open(reorderBuffer.columnHeaders, reorderBuffer.comparisonColumnHeaders)
+			if err != nil {
+				return err
+			}
+		}
+		return output.writeRow(row)
+	}
+
+	//receive all file sections and write them out as soon as it is their turn
+	receivedSections := make(map[string]bool)
+	rowsReconstructed := int64(0)
+	for !reorderBuffer.isComplete() {
+		section, err := reconstructFileSectionsStreamConsumer.FetchNextWithContext(ctx)
 
 		if err != nil {
 			log.Printf("error on getting next reconstruct fileSection: %v", err)
+
+			//the task was cancelled or failed, nothing is
+			//going to be published to the topic anymore
+			if ctx.Err() != nil {
+				return models.ReconSummary{}, cleanUpCancelledReconstruction(ctx, reconstructFileSectionsStream, toBeReconstructedStreamTopicName, consumerId)
+			}
 			continue
 		}
 
+		// a section reconciled again after a restart
+		// is published to the stream a second time
+		sectionKey := fmt.Sprintf("%v-%v", section.PartitionNumber, section.SectionSequenceNumber)
+		if receivedSections[sectionKey] {
+			log.Printf("skipping duplicate reconstruct fileSection:[%v]", sectionKey)
+			continue
+		}
+		receivedSections[sectionKey] = true
+
 		log.Printf("received reconstruct fileSection:[%v]", section.SectionSequenceNumber)
-		fileSections = append(fileSections, *section)
+		err = reorderBuffer.add(*section)
+		if err == nil {
+			err = reorderBuffer.release(writeRow)
+		}
 
-		hasBeenReconstructed := checkIfFullFileHasBeenReconstructed(fileSections)
+		// failed to write results
+		if err != nil {
+			return models.ReconSummary{}, fmt.Errorf("failed to reconstruct section [%v]: %v", sectionKey, err)
+		}
 
-		if hasBeenReconstructed {
-			break
+		rowsReconstructed += int64(len(section.SectionRows))
+		taskDetails.ProgressTracker.RecordRowsReconstructed(rowsReconstructed)
+
+		if taskDetails.Checkpoints != nil {
+			err = taskDetails.Checkpoints.RecordSectionReconstructed(
+				context.Background(),
+				taskId,
+				section.FileID,
+				section.SectionSequenceNumber,
+			)
+			if err != nil {
+				log.Printf("error on checkpointing reconstruct fileSection:[%v], Error: %v", section.SectionSequenceNumber, err)
+			}
 		}
 	}
 
-	// since we have a full file, we can write out the results
-	err = writeReconResultsOutToFile(outputPath, fileSections)
+	// a file without any rows still gets its results files
+	if !output.isOpen {
+		err = output.open(reorderBuffer.columnHeaders, reorderBuffer.comparisonColumnHeaders)
+		if err != nil {
+			return models.ReconSummary{}, err
+		}
+	}
+
+	// since we have a full file, we can finish the results
+	summary, err := output.finish()
+	if err != nil {
+		return models.ReconSummary{}, err
+	}
 
-	// failed to write results
+	err = storeResultsFiles(ctx, taskDetails, outputPath, resultsFileKey)
 	if err != nil {
-		return fmt.Errorf("failed to write results to file: %v", err)
+		return models.ReconSummary{}, err
 	}
 
 	//delete the consumer
@@ -68,82 +191,105 @@ func ReconstructFile(taskId string, reconstructFileSectionsStream models.StreamP
 	// failed to clean up the consumer
 	if err != nil {
 		err = fmt.Errorf("failed to delete reconstruct stream consumer: %v", err)
-		log.Printf(err.Error())
-		return err
+		log.Printf("%v", err)
+		return models.ReconSummary{}, err
 	}
 
 	log.Printf("Successfully deleted reconstruct stream consumer: [%v]", consumerId)
 
-	return nil
+	return summary, nil
 }
 
-func writeReconResultsOutToFile(outputPath string, fileSections []models.FileSection) error {
-	// Create and open the output CSV pre-processing
-	file, err := os.Create(outputPath)
+// cleanUpCancelledReconstruction deletes the consumer and the topic
+// of a task that was cancelled, then reports the cancellation.
+func cleanUpCancelledReconstruction(
+	ctx context.Context,
+	reconstructFileSectionsStream models.StreamProvider,
+	topicName string,
+	consumerId string,
+) error {
+	log.Printf("reconstruction cancelled for topic: [%v]", topicName)
+
+	err := reconstructFileSectionsStream.DeleteStreamConsumer(
+		utils.NewContextWithDefaultTimeout(),
+		constants.FILE_RECONSTRUCTION_STREAM_NAME,
+		consumerId,
+	)
 	if err != nil {
-		return err
+		log.Printf("failed to delete reconstruct stream consumer: [%v], Error: %v", consumerId, err)
+	}
+
+	err = reconstructFileSectionsStream.DeleteStreamTopic(
+		utils.NewContextWithDefaultTimeout(),
+		constants.FILE_RECONSTRUCTION_STREAM_NAME,
+		topicName,
+	)
+	if err != nil {
+		log.Printf("failed to delete reconstruct stream topic: [%v], Error: %v", topicName, err)
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	return ctx.Err()
+}
 
-	// Write the column headers
-	var columnHeaders = fileSections[0].ColumnHeaders
-	columnHeaders = append(columnHeaders, "ReconResult")
-	columnHeaders = append(columnHeaders, "ReconResultReasons")
-	if len(fileSections) > 0 {
-		err := writer.Write(columnHeaders)
+// storeResultsFiles puts the results file of every format written at the output path in the file store of the task,
+// then deletes the results files an earlier attempt at reconstructing the task left there
+func storeResultsFiles(ctx context.Context, taskDetails models.ReconTaskDetails, outputPath string, resultsFileKey string) error {
+	storedKeys := make(map[string]bool)
+	for _, format := range taskDetails.ResultsFormatsToWrite() {
+		key := ResultsFilePathIn(resultsFileKey, format)
+		err := storeResultsFile(ctx, taskDetails.Files, ResultsFilePathIn(outputPath, format), key)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to store %v results file: %v", format, err)
 		}
+		storedKeys[key] = true
 	}
 
-	// Write the rows from each section
-	for _, section := range fileSections {
-		for _, row := range section.SectionRows {
-			row.ParsedColumnsFromRow = append(row.ParsedColumnsFromRow, string(row.ReconResult))
-			row.ParsedColumnsFromRow = append(row.ParsedColumnsFromRow, strings.Join(row.ReconResultReasons, ","))
-			err := writer.Write(row.ParsedColumnsFromRow)
-			if err != nil {
-				return err
-			}
+	prefix := path.Join(path.Dir(resultsFileKey), resultsFilePrefix(taskDetails)+"_")
+	earlierFiles, err := taskDetails.Files.List(ctx, prefix)
+	if err != nil {
+		log.Printf("error on listing earlier results files: [%v], Error: %v", prefix, err)
+		return nil
+	}
+	for _, earlierFile := range earlierFiles {
+		if storedKeys[earlierFile.Key] {
+			continue
+		}
+		err = taskDetails.Files.Delete(ctx, earlierFile.Key)
+		if err != nil {
+			log.Printf("error on deleting earlier results file: [%v], Error: %v", earlierFile.Key, err)
 		}
 	}
 	return nil
 }
 
-func checkIfFullFileHasBeenReconstructed(fileSections []models.FileSection) bool {
-	//every file must have at least 2 file sections
-	if len(fileSections) <= 1 {
-		return false
+// storeResultsFile puts the file at the path in the store under the key
+func storeResultsFile(ctx context.Context, files models.FileStore, filePath string, key string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	// Sort the currently received file sections by section_sequence_number
-	sort.Slice(fileSections, func(i, j int) bool {
-		return fileSections[i].SectionSequenceNumber < fileSections[j].SectionSequenceNumber
-	})
+	return files.Put(ctx, key, file)
+}
 
-	// Firstly, check if the first file section seq number is one
-	firstSection := fileSections[0]
+// writeOutToFile writes the file next to the output path first
+// and only moves it into place once it is complete,
+// so a process that stops half way never leaves a partial results file behind.
+func writeOutToFile(outputPath string, write func(path string) error) error {
+	temporaryPath := outputPath + ".tmp"
+	err := write(temporaryPath)
 
-	// if it isn't then it means we haven't yet received the
-	// beginning of this file but maybe have received other sections
-	// either way, we know the file has not been reconstructed
-	if firstSection.SectionSequenceNumber != 1 {
-		return false
+	// failed to write, don't leave the partial file behind
+	if err != nil {
+		_ = os.Remove(temporaryPath)
+		return err
 	}
 
-	// Check to see if the current file section's sequence number
-	// is the previous file section's sequence number + 1
-	for i := 0; i < len(fileSections)-1; i++ {
-		if fileSections[i+1].SectionSequenceNumber != fileSections[i].SectionSequenceNumber+1 {
-			return false
-		}
-	}
+	return os.Rename(temporaryPath, outputPath)
+}
 
-	// Lastly, check if the last file section has the IsLastSection flag is
-	//set to true
-	lastSection := fileSections[len(fileSections)-1]
-	return lastSection.IsLastSection
+// reasonsText renders the reasons of the row as text, separated by commas
+func reasonsText(row models.FileSectionRow) string {
+	return strings.Join(models.ReconReasonTexts(row.RowNumber, row.ReconResultReasons), ",")
 }
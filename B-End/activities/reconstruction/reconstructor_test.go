@@ -1,10 +1,19 @@
 package reconstruction
 
 import (
+	"context"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io"
+	"os"
+	"path/filepath"
+	"reconciler.io/constants"
 	"reconciler.io/models"
 	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"reconciler.io/models/enums/results_format"
+	"strings"
 	"testing"
 )
 
@@ -13,137 +22,489 @@ func TestFileReconstructionActivity(t *testing.T) {
 	RunSpecs(t, "File Reconstruction Activity Suite")
 }
 
-var _ = Describe("CheckIfFullFileHasBeenReconstructed", func() {
+// testOutputDir returns a directory of its own for the files a test writes,
+// removed once the suite is done
+func testOutputDir() string {
+	dir, err := os.MkdirTemp(testOutputRoot, "test-")
+	Expect(err).NotTo(HaveOccurred())
+	return dir
+}
+
+var testOutputRoot string
+
+var _ = BeforeSuite(func() {
+	var err error
+	testOutputRoot, err = os.MkdirTemp("", "reconstruction-")
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	Expect(os.RemoveAll(testOutputRoot)).To(Succeed())
+})
+
+// sectionRows goes through the rows of the sections in row order,
+// the way the reconstruction puts them back in order
+func sectionRows(fileSections []models.FileSection) reconResultRows {
+	return func(each func(row models.FileSectionRow) error) error {
+		buffer := newSectionReorderBuffer(testOutputDir(), len(fileSections))
+		defer buffer.close()
+		for _, section := range fileSections {
+			err := buffer.add(section)
+			if err != nil {
+				return err
+			}
+		}
+		return buffer.release(each)
+	}
+}
+
+// reconstructSections writes out the results of the sections the way ReconstructFile does, without the streams
+func reconstructSections(taskDetails models.ReconTaskDetails, outputPath string, fileSections []models.FileSection) (models.ReconSummary, error) {
+	buffer := newSectionReorderBuffer(testOutputDir(), len(fileSections))
+	defer buffer.close()
+	for _, section := range fileSections {
+		err := buffer.add(section)
+		if err != nil {
+			return models.ReconSummary{}, err
+		}
+	}
+
+	output := newReconResultsOutput(context.Background(), taskDetails, outputPath)
+	defer output.abort()
+	err := output.open(buffer.columnHeaders, buffer.comparisonColumnHeaders)
+	if err != nil {
+		return models.ReconSummary{}, err
+	}
+
+	err = buffer.release(output.writeRow)
+	if err != nil {
+		return models.ReconSummary{}, err
+	}
+	return output.finish()
+}
+
+// releaseRows adds the sections to the buffer one at a time
+// and returns the numbers of the rows released after each of them
+func releaseRows(buffer *sectionReorderBuffer, fileSections ...models.FileSection) [][]uint64 {
+	released := make([][]uint64, 0, len(fileSections))
+	for _, section := range fileSections {
+		Expect(buffer.add(section)).To(Succeed())
 
-	Context("when fileSections is empty", func() {
-		It("should return false", func() {
-			Expect(checkIfFullFileHasBeenReconstructed(
-				[]models.FileSection{},
-			)).To(BeFalse())
+		rowNumbers := make([]uint64, 0)
+		Expect(buffer.release(func(row models.FileSectionRow) error {
+			rowNumbers = append(rowNumbers, row.RowNumber)
+			return nil
+		})).To(Succeed())
+		released = append(released, rowNumbers)
+	}
+	return released
+}
+
+func sectionOfRows(sectionSequenceNumber int, isLastSection bool, rowNumbers ...uint64) models.FileSection {
+	section := models.FileSection{
+		SectionSequenceNumber: sectionSequenceNumber,
+		OriginalFilePurpose:   file_purpose.PrimaryFile,
+		IsLastSection:         isLastSection,
+		SectionRows:           []models.FileSectionRow{},
+	}
+	for _, rowNumber := range rowNumbers {
+		section.SectionRows = append(section.SectionRows, models.FileSectionRow{RowNumber: rowNumber})
+	}
+	return section
+}
+
+var _ = Describe("SectionReorderBuffer", func() {
+	var buffer *sectionReorderBuffer
+
+	BeforeEach(func() {
+		buffer = newSectionReorderBuffer(testOutputDir(), constants.RECONSTRUCTION_REORDER_BUFFER_SECTIONS)
+	})
+
+	AfterEach(func() {
+		Expect(buffer.close()).To(Succeed())
+	})
+
+	Context("when no section has arrived", func() {
+		It("should not be complete", func() {
+			Expect(buffer.isComplete()).To(BeFalse())
 		})
 	})
 
-	Context("when fileSections has only one section", func() {
-		It("should return false", func() {
-			sections := []models.FileSection{
-				{
-					ID:                    "1",
-					SectionSequenceNumber: 1,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
-				},
-			}
-			Expect(checkIfFullFileHasBeenReconstructed(sections)).To(BeFalse())
+	Context("when only the first section has arrived", func() {
+		It("should release its rows but not be complete", func() {
+			Expect(releaseRows(buffer, sectionOfRows(1, false, 0, 1))).To(Equal([][]uint64{{0, 1}}))
+			Expect(buffer.isComplete()).To(BeFalse())
 		})
 	})
 
-	Context("when fileSections has all sections", func() {
-		It("should return true", func() {
-			sections := []models.FileSection{
-				{
-					ID:                    "2",
-					SectionSequenceNumber: 2,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         true,
-				},
-				{
-					ID:                    "1",
-					SectionSequenceNumber: 1,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
-				},
-			}
-			Expect(checkIfFullFileHasBeenReconstructed(sections)).To(BeTrue())
+	Context("when a section arrives before the ones ahead of it", func() {
+		It("should hold it until they have arrived and then release them all in order", func() {
+			released := releaseRows(buffer,
+				sectionOfRows(2, true, 2, 3),
+				sectionOfRows(1, false, 0, 1),
+			)
+			Expect(released).To(Equal([][]uint64{{}, {0, 1, 2, 3}}))
+			Expect(buffer.isComplete()).To(BeTrue())
 		})
 	})
 
-	Context("when fileSections has more than one section but some sections are missing", func() {
-		It("should return false", func() {
-			sections := []models.FileSection{
-				{
-					ID:                    "2",
-					SectionSequenceNumber: 2,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
-				},
-				{
-					ID:                    "2",
-					SectionSequenceNumber: 5,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         true,
-				},
-				{
-					ID:                    "1",
-					SectionSequenceNumber: 1,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
-				},
-			}
-			Expect(checkIfFullFileHasBeenReconstructed(sections)).To(BeFalse())
+	Context("when sections in the middle of the file are missing", func() {
+		It("should only release the rows up to the first missing section", func() {
+			released := releaseRows(buffer,
+				sectionOfRows(2, false, 2),
+				sectionOfRows(5, true, 5),
+				sectionOfRows(1, false, 0, 1),
+			)
+			Expect(released).To(Equal([][]uint64{{}, {}, {0, 1, 2}}))
+			Expect(buffer.isComplete()).To(BeFalse())
+		})
+	})
+
+	Context("when the beginning of the file has not arrived", func() {
+		It("should release nothing", func() {
+			released := releaseRows(buffer,
+				sectionOfRows(2, false, 2),
+				sectionOfRows(3, false, 3),
+				sectionOfRows(4, false, 4),
+			)
+			Expect(released).To(Equal([][]uint64{{}, {}, {}}))
+			Expect(buffer.isComplete()).To(BeFalse())
+		})
+	})
+
+	Context("when the last section has not arrived", func() {
+		It("should not be complete", func() {
+			releaseRows(buffer,
+				sectionOfRows(2, false, 2),
+				sectionOfRows(3, false, 3),
+				sectionOfRows(1, false, 0, 1),
+			)
+			Expect(buffer.isComplete()).To(BeFalse())
+		})
+	})
+
+	Context("when a section arrives a second time", func() {
+		It("should only release its rows once", func() {
+			released := releaseRows(buffer,
+				sectionOfRows(1, false, 0),
+				sectionOfRows(1, false, 0),
+				sectionOfRows(2, true, 1),
+			)
+			Expect(released).To(Equal([][]uint64{{0}, {}, {1}}))
 		})
 	})
 
-	Context("when fileSections has no section beginning file", func() {
-		It("should return false", func() {
-			sections := []models.FileSection{
-				{
-					ID:                    "2",
-					SectionSequenceNumber: 2,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
+	Context("when more sections arrive before their turn than are held in memory", func() {
+		It("should spill them to files and release them in order once their turn comes", func() {
+			spillDirectory := testOutputDir()
+			buffer = newSectionReorderBuffer(spillDirectory, 1)
+
+			released := releaseRows(buffer,
+				sectionOfRows(4, true, 6),
+				sectionOfRows(3, false, 4, 5),
+				sectionOfRows(2, false, 2, 3),
+			)
+			Expect(released).To(Equal([][]uint64{{}, {}, {}}))
+			Expect(buffer.spilledSections).To(HaveLen(2))
+
+			spilled, err := os.ReadDir(buffer.spillPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spilled).To(HaveLen(2))
+
+			Expect(releaseRows(buffer, sectionOfRows(1, false, 0, 1))).To(Equal([][]uint64{{0, 1, 2, 3, 4, 5, 6}}))
+			Expect(buffer.isComplete()).To(BeTrue())
+			Expect(buffer.spilledSections).To(BeEmpty())
+
+			Expect(buffer.close()).To(Succeed())
+			Expect(os.ReadDir(spillDirectory)).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("Partitioned file reconstruction", func() {
+	partitionedSections := func() []models.FileSection {
+		return []models.FileSection{
+			{
+				SectionSequenceNumber: 1,
+				PartitionNumber:       2,
+				PartitionCount:        2,
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 1}, {RowNumber: 4},
 				},
-				{
-					ID:                    "3",
-					SectionSequenceNumber: 3,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
+			},
+			{
+				SectionSequenceNumber: 2,
+				PartitionNumber:       2,
+				PartitionCount:        2,
+				IsLastSection:         true,
+			},
+			{
+				SectionSequenceNumber: 1,
+				PartitionNumber:       1,
+				PartitionCount:        2,
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 0}, {RowNumber: 2},
 				},
-				{
-					ID:                    "4",
-					SectionSequenceNumber: 4,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
+			},
+			{
+				SectionSequenceNumber: 2,
+				PartitionNumber:       1,
+				PartitionCount:        2,
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 3},
 				},
+			},
+		}
+	}
+
+	var buffer *sectionReorderBuffer
+
+	BeforeEach(func() {
+		buffer = newSectionReorderBuffer(testOutputDir(), constants.RECONSTRUCTION_REORDER_BUFFER_SECTIONS)
+	})
+
+	AfterEach(func() {
+		Expect(buffer.close()).To(Succeed())
+	})
+
+	Context("when only one partition has started", func() {
+		It("should release nothing, since a row of the other partition may come first", func() {
+			released := releaseRows(buffer, partitionedSections()[:2]...)
+			Expect(released).To(Equal([][]uint64{{}, {}}))
+		})
+	})
+
+	Context("when one partition has not yet received its last section", func() {
+		It("should release the rows it can be sure of and not be complete", func() {
+			released := releaseRows(buffer, partitionedSections()...)
+			Expect(released).To(Equal([][]uint64{{}, {}, {0, 1, 2}, {3}}))
+			Expect(buffer.isComplete()).To(BeFalse())
+		})
+	})
+
+	Context("when every partition has received its last section", func() {
+		It("should be complete and have merged the rows back into row order", func() {
+			sections := append(partitionedSections(), models.FileSection{
+				SectionSequenceNumber: 3,
+				PartitionNumber:       1,
+				PartitionCount:        2,
+				IsLastSection:         true,
+			})
+
+			rowNumbers := make([]uint64, 0)
+			for _, released := range releaseRows(buffer, sections...) {
+				rowNumbers = append(rowNumbers, released...)
 			}
-			Expect(checkIfFullFileHasBeenReconstructed(sections)).To(BeFalse())
+			Expect(rowNumbers).To(Equal([]uint64{0, 1, 2, 3, 4}))
+			Expect(buffer.isComplete()).To(BeTrue())
 		})
 	})
 
-	Context("when fileSections has more than one section but has no last sections", func() {
-		It("should return false", func() {
-			sections := []models.FileSection{
-				{
-					ID:                    "2",
-					SectionSequenceNumber: 2,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
-				},
-				{
-					ID:                    "3",
-					SectionSequenceNumber: 3,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
+	Context("when a partition received no rows at all", func() {
+		It("should treat its single empty last section as complete", func() {
+			releaseRows(buffer,
+				models.FileSection{SectionSequenceNumber: 1, PartitionNumber: 1, PartitionCount: 2, IsLastSection: true},
+				models.FileSection{SectionSequenceNumber: 1, PartitionNumber: 2, PartitionCount: 2, SectionRows: []models.FileSectionRow{{RowNumber: 0}}},
+				models.FileSection{SectionSequenceNumber: 2, PartitionNumber: 2, PartitionCount: 2, IsLastSection: true},
+			)
+			Expect(buffer.isComplete()).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("CsvResultSink", func() {
+
+	Context("when the results are written", func() {
+		It("should only leave the finished results file behind", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.Csv")
+			sections := []models.FileSection{{
+				SectionSequenceNumber: 1,
+				ColumnHeaders:         []string{"Id"},
+				SectionRows:           []models.FileSectionRow{{RowNumber: 1, ParsedColumnsFromRow: []string{"1"}}},
+				IsLastSection:         true,
+			}}
+
+			_, err := reconstructSections(models.ReconTaskDetails{}, outputPath, sections)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("Id,ReconResult,ReconResultReasons\n1,,\n"))
+			Expect(outputPath + ".tmp").NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("when the rows have reasons", func() {
+		It("should write each reason as text, separated by commas", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.Csv")
+			comparisonRowNumber := uint64(5)
+			sections := []models.FileSection{{
+				SectionSequenceNumber: 1,
+				ColumnHeaders:         []string{"Id"},
+				SectionRows: []models.FileSectionRow{
+					{
+						RowNumber:            1,
+						ParsedColumnsFromRow: []string{"1"},
+						ReconResult:          recon_status.Successfull,
+						ReconResultReasons:   []models.ReconReason{{Code: recon_reason_code.RowMatchFound, CounterpartRowNumber: &comparisonRowNumber}},
+					},
+					{
+						RowNumber:            2,
+						ParsedColumnsFromRow: []string{"2"},
+						ReconResult:          recon_status.Failed,
+						ReconResultReasons:   []models.ReconReason{{Code: recon_reason_code.NoMatchFound}, {Code: recon_reason_code.NoMatchFound}},
+					},
 				},
-				{
-					ID:                    "1",
-					SectionSequenceNumber: 1,
-					OriginalFilePurpose:   file_purpose.PrimaryFile,
-					ReconConfig:           models.ReconciliationConfigs{},
-					IsLastSection:         false,
+				IsLastSection: true,
+			}}
+
+			_, err := reconstructSections(models.ReconTaskDetails{}, outputPath, sections)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("Id,ReconResult,ReconResultReasons\n" +
+				"1,Successfull,\"RowMatchFound. \nPrimaryFile Row: [1] \nComparisonFile Row: [5] \n\"\n" +
+				"2,Failed,\"no matching record found in the entire comparison file,no matching record found in the entire comparison file\"\n"))
+		})
+	})
+})
+
+// recordedResults keeps the batches of results saved by the reconstruction
+type recordedResults struct {
+	deletedTaskIDs []string
+	batches        [][]models.ReconResult
+}
+
+func (r *recordedResults) DeleteReconResults(ctx context.Context, taskID string) error {
+	r.deletedTaskIDs = append(r.deletedTaskIDs, taskID)
+	return nil
+}
+
+func (r *recordedResults) SaveReconResults(ctx context.Context, taskID string, results []models.ReconResult) error {
+	r.batches = append(r.batches, append([]models.ReconResult{}, results...))
+	return nil
+}
+
+var _ = Describe("ReconResultsSaver", func() {
+
+	Context("when the task has somewhere to record its results", func() {
+		It("should replace earlier results with the results of every row in batches", func() {
+			batchSize := constants.RECON_RESULTS_SAVE_BATCH_SIZE
+			constants.RECON_RESULTS_SAVE_BATCH_SIZE = 2
+			defer func() { constants.RECON_RESULTS_SAVE_BATCH_SIZE = batchSize }()
+
+			matchedRowNumber := uint64(7)
+			mismatch := models.ReconReason{
+				Code:                 recon_reason_code.RowMismatchFound,
+				PrimaryColumn:        "Amount",
+				ComparisonColumn:     "Value",
+				PrimaryValue:         "20",
+				ComparisonValue:      "25",
+				CounterpartRowNumber: &matchedRowNumber,
+			}
+			sections := []models.FileSection{{
+				SectionSequenceNumber: 1,
+				IsLastSection:         true,
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 1, ParsedColumnsFromRow: []string{"1", "10"}, ReconResult: recon_status.Successfull, MatchedRowNumber: &matchedRowNumber},
+					{
+						RowNumber:            2,
+						ParsedColumnsFromRow: []string{"2", "20"},
+						ReconResult:          recon_status.Failed,
+						ReconResultReasons:   []models.ReconReason{mismatch},
+						MatchedRowNumber:     &matchedRowNumber,
+					},
+					{RowNumber: 3, ParsedColumnsFromRow: []string{"3", "30"}, ReconResult: recon_status.Failed},
 				},
+			}}
+			results := &recordedResults{}
+			task := models.ReconTaskDetails{ID: "task_1", Results: results}
+
+			saver, err := newReconResultsSaver(context.Background(), task)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sectionRows(sections)(saver.addRow)).To(Succeed())
+			Expect(saver.flush()).To(Succeed())
+
+			Expect(results.deletedTaskIDs).To(Equal([]string{"task_1"}))
+			Expect(results.batches).To(HaveLen(2))
+			Expect(results.batches[0]).To(HaveLen(2))
+			Expect(results.batches[1]).To(HaveLen(1))
+
+			failedRow := results.batches[0][1]
+			Expect(failedRow.TaskID).To(Equal("task_1"))
+			Expect(*failedRow.MatchedRowNumber).To(Equal(uint64(7)))
+			Expect(failedRow.Reasons).To(Equal([]models.ReconReason{mismatch}))
+			Expect(results.batches[1][0].MatchedRowNumber).To(BeNil())
+		})
+	})
+
+	Context("when the task has nowhere to record its results", func() {
+		It("should do nothing", func() {
+			saver, err := newReconResultsSaver(context.Background(), models.ReconTaskDetails{ID: "task_1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(saver.addRow(models.FileSectionRow{RowNumber: 1})).To(Succeed())
+			Expect(saver.flush()).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Storing results files", func() {
+	sections := []models.FileSection{{
+		SectionSequenceNumber: 1,
+		ColumnHeaders:         []string{"Id", "Amount"},
+		IsLastSection:         true,
+		SectionRows: []models.FileSectionRow{
+			{RowNumber: 0, ParsedColumnsFromRow: []string{"1", "10"}, ReconResult: recon_status.Successfull},
+		},
+	}}
+
+	Context("when the results files of the task are complete", func() {
+		It("should put every format in the file store and delete what an earlier attempt left there", func() {
+			ctx := context.Background()
+			files := models.NewLocalFileStore(testOutputDir())
+			task := models.ReconTaskDetails{
+				ID:             "task_1",
+				UserID:         "user_1",
+				ResultsFormats: []results_format.ResultsFormat{results_format.Csv, results_format.JsonLines},
+				Files:          files,
 			}
-			Expect(checkIfFullFileHasBeenReconstructed(sections)).To(BeFalse())
+
+			Expect(files.Put(ctx, "users/user_1/ReconResults-task_1_20260101000000.Csv", strings.NewReader("Id\n"))).To(Succeed())
+			Expect(files.Put(ctx, "users/user_1/ReconResults-task_10_20260101000000.Csv", strings.NewReader("Id\n"))).To(Succeed())
+
+			outputPath := filepath.Join(testOutputDir(), "ReconResults-task_1_20260102000000.Csv")
+			_, err := reconstructSections(task, outputPath, sections)
+			Expect(err).NotTo(HaveOccurred())
+
+			resultsFileKey := "users/user_1/ReconResults-task_1_20260102000000.Csv"
+			Expect(storeResultsFiles(ctx, task, outputPath, resultsFileKey)).To(Succeed())
+
+			stored, err := files.List(ctx, "users/user_1/")
+			Expect(err).NotTo(HaveOccurred())
+			keys := make([]string, 0, len(stored))
+			for _, file := range stored {
+				keys = append(keys, file.Key)
+			}
+			Expect(keys).To(Equal([]string{
+				"users/user_1/ReconResults-task_10_20260101000000.Csv",
+				"users/user_1/ReconResults-task_1_20260102000000.Csv",
+				"users/user_1/ReconResults-task_1_20260102000000.jsonl",
+			}))
+
+			resultsFile, err := files.Get(ctx, resultsFileKey)
+			Expect(err).NotTo(HaveOccurred())
+			defer resultsFile.Close()
+			contents, err := io.ReadAll(resultsFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(HavePrefix("Id,Amount,ReconResult,ReconResultReasons\n1,10,Successfull"))
+		})
+	})
+
+	Context("when the task has no file store", func() {
+		It("should fail before reconstructing anything", func() {
+			_, err := ReconstructFile(context.Background(), models.ReconTaskDetails{ID: "task_1"}, "shared/ReconResults-task_1.csv")
+			Expect(err).To(HaveOccurred())
 		})
 	})
 })
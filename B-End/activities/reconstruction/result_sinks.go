@@ -0,0 +1,181 @@
+package reconstruction
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/result_sink_type"
+)
+
+// newConfiguredResultSink starts the result sink the task asked for besides its results files
+func newConfiguredResultSink(
+	ctx context.Context,
+	taskDetails models.ReconTaskDetails,
+	sinkConfig models.ResultSinkConfig,
+	columnHeaders []string,
+) (ResultSink, error) {
+	switch sinkConfig.Type {
+	case result_sink_type.Sql:
+		return newSqlResultSink(ctx, taskDetails, sinkConfig.Table, columnHeaders)
+	case result_sink_type.Stream:
+		return newStreamResultSink(ctx, taskDetails, sinkConfig.Subject)
+	default:
+		return nil, fmt.Errorf("results can not be sent to a [%v] sink", sinkConfig.Type)
+	}
+}
+
+// jsonLinesResultSink writes the result of every row as a json document of its own line,
+// next to its output path until it is complete like the csv
+type jsonLinesResultSink struct {
+	outputPath string
+	taskID     string
+	file       *os.File
+	writer     *bufio.Writer
+	encoder    *json.Encoder
+}
+
+func newJsonLinesResultSink(outputPath string, taskID string) (*jsonLinesResultSink, error) {
+	file, err := os.Create(outputPath + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	return &jsonLinesResultSink{
+		outputPath: outputPath,
+		taskID:     taskID,
+		file:       file,
+		writer:     writer,
+		encoder:    json.NewEncoder(writer),
+	}, nil
+}
+
+func (s *jsonLinesResultSink) WriteRow(row models.FileSectionRow) error {
+	return s.encoder.Encode(models.NewReconResult(s.taskID, row))
+}
+
+func (s *jsonLinesResultSink) Finish(summary models.ReconSummary) error {
+	err := s.writer.Flush()
+	if err == nil {
+		err = s.file.Sync()
+	}
+	closeErr := s.file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	// failed to write, don't leave the partial file behind
+	if err != nil {
+		_ = os.Remove(s.file.Name())
+		return err
+	}
+	return os.Rename(s.file.Name(), s.outputPath)
+}
+
+func (s *jsonLinesResultSink) Abort() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// sqlResultSink inserts the result of every row into a table of the database of the service,
+// constants.RECON_RESULTS_SAVE_BATCH_SIZE at a time. The results of any earlier attempt
+// at the task are removed from the table before the first row is inserted.
+type sqlResultSink struct {
+	ctx           context.Context
+	tables        models.ResultTableWriter
+	table         string
+	taskID        string
+	columnHeaders []string
+	batch         []models.ReconResult
+}
+
+func newSqlResultSink(ctx context.Context, taskDetails models.ReconTaskDetails, table string, columnHeaders []string) (*sqlResultSink, error) {
+	if taskDetails.ResultTables == nil {
+		return nil, fmt.Errorf("there is no database to send results to table: [%v]", table)
+	}
+
+	err := taskDetails.ResultTables.PrepareResultTable(ctx, table, taskDetails.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlResultSink{
+		ctx:           ctx,
+		tables:        taskDetails.ResultTables,
+		table:         table,
+		taskID:        taskDetails.ID,
+		columnHeaders: columnHeaders,
+		batch:         make([]models.ReconResult, 0, constants.RECON_RESULTS_SAVE_BATCH_SIZE),
+	}, nil
+}
+
+func (s *sqlResultSink) WriteRow(row models.FileSectionRow) error {
+	s.batch = append(s.batch, models.NewReconResult(s.taskID, row))
+	if len(s.batch) < constants.RECON_RESULTS_SAVE_BATCH_SIZE {
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *sqlResultSink) Finish(summary models.ReconSummary) error {
+	return s.flush()
+}
+
+// Abort drops the rows waiting for a full batch, the ones already inserted stay until the task is reconstructed again
+func (s *sqlResultSink) Abort() {
+	s.batch = s.batch[:0]
+}
+
+// flush inserts the rows that are waiting for a full batch
+func (s *sqlResultSink) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	err := s.tables.InsertResults(s.ctx, s.table, s.columnHeaders, s.batch)
+	if err != nil {
+		return err
+	}
+	s.batch = s.batch[:0]
+	return nil
+}
+
+// streamResultSink publishes the result of every row on a NATS subject as it is reconstructed,
+// followed by the summary of the results on the summary subject of the subject once every row has been.
+// A task that is reconstructed again publishes its results again, consumers tell them apart by the task and row number.
+type streamResultSink struct {
+	ctx     context.Context
+	streams models.StreamProvider
+	subject string
+	taskID  string
+}
+
+func newStreamResultSink(ctx context.Context, taskDetails models.ReconTaskDetails, subject string) (*streamResultSink, error) {
+	if taskDetails.ResultStreams == nil {
+		return nil, fmt.Errorf("there is no stream to publish results on subject: [%v]", subject)
+	}
+
+	for _, streamSubject := range []string{subject, models.ResultSummarySubject(subject)} {
+		err := taskDetails.ResultStreams.SetupStream(ctx, constants.RESULT_SINK_STREAM_NAME, streamSubject)
+		if err != nil {
+			return nil, fmt.Errorf("error on setting up result subject: [%v], Error: %v", streamSubject, err)
+		}
+	}
+
+	return &streamResultSink{ctx: ctx, streams: taskDetails.ResultStreams, subject: subject, taskID: taskDetails.ID}, nil
+}
+
+func (s *streamResultSink) WriteRow(row models.FileSectionRow) error {
+	return s.streams.PublishToTopic(s.ctx, s.subject, models.NewReconResult(s.taskID, row))
+}
+
+func (s *streamResultSink) Finish(summary models.ReconSummary) error {
+	return s.streams.PublishToTopic(s.ctx, models.ResultSummarySubject(s.subject), summary)
+}
+
+// Abort leaves the results that were already published, there is no taking them back
+func (s *streamResultSink) Abort() {}
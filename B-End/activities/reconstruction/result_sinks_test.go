@@ -0,0 +1,165 @@
+package reconstruction
+
+import (
+	"context"
+	"encoding/json"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_status"
+	"reconciler.io/models/enums/result_sink_type"
+	"reconciler.io/models/enums/results_format"
+	"strings"
+)
+
+// recordedResultTables keeps what the reconstruction sends to result tables
+type recordedResultTables struct {
+	preparedTables []string
+	columnHeaders  []string
+	batches        [][]models.ReconResult
+}
+
+func (r *recordedResultTables) PrepareResultTable(ctx context.Context, table string, taskID string) error {
+	r.preparedTables = append(r.preparedTables, table+"/"+taskID)
+	return nil
+}
+
+func (r *recordedResultTables) InsertResults(ctx context.Context, table string, columnHeaders []string, results []models.ReconResult) error {
+	r.columnHeaders = columnHeaders
+	r.batches = append(r.batches, append([]models.ReconResult{}, results...))
+	return nil
+}
+
+// publishedResults keeps what the reconstruction publishes to result streams
+type publishedResults struct {
+	streamSubjects []string
+	messages       map[string][]interface{}
+}
+
+func (p *publishedResults) SetupStream(ctx context.Context, streamName string, topicName string) error {
+	p.streamSubjects = append(p.streamSubjects, streamName+"/"+topicName)
+	return nil
+}
+
+func (p *publishedResults) DeleteStreamTopic(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (p *publishedResults) PublishToTopic(ctx context.Context, topicName string, data interface{}) error {
+	if p.messages == nil {
+		p.messages = make(map[string][]interface{})
+	}
+	p.messages[topicName] = append(p.messages[topicName], data)
+	return nil
+}
+
+func (p *publishedResults) CreateStreamConsumer(ctx context.Context, streamName, topicName, consumerName string) (models.StreamConsumer, error) {
+	return nil, nil
+}
+
+func (p *publishedResults) DeleteStreamConsumer(ctx context.Context, streamName string, consumerName string) error {
+	return nil
+}
+
+func (p *publishedResults) Close(ctx context.Context) error {
+	return nil
+}
+
+var _ = Describe("ResultSinks", func() {
+	matchedRowNumber := uint64(9)
+	sections := []models.FileSection{{
+		SectionSequenceNumber: 1,
+		ColumnHeaders:         []string{"Id", "Amount"},
+		IsLastSection:         true,
+		SectionRows: []models.FileSectionRow{
+			{RowNumber: 0, ParsedColumnsFromRow: []string{"1", "10"}, ReconResult: recon_status.Successfull, MatchedRowNumber: &matchedRowNumber},
+			{RowNumber: 1, ParsedColumnsFromRow: []string{"2", "20"}, ReconResult: recon_status.Failed},
+			{RowNumber: 2, ParsedColumnsFromRow: []string{"3", "30"}, ReconResult: recon_status.Failed},
+		},
+	}}
+
+	Context("when the task writes its results as json lines", func() {
+		It("should write the result of every row on a line of its own", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.Csv")
+			task := models.ReconTaskDetails{ID: "task_1", ResultsFormats: []results_format.ResultsFormat{results_format.JsonLines}}
+
+			_, err := reconstructSections(task, outputPath, sections)
+			Expect(err).NotTo(HaveOccurred())
+
+			jsonLinesPath := ResultsFilePathIn(outputPath, results_format.JsonLines)
+			contents, err := os.ReadFile(jsonLinesPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jsonLinesPath + ".tmp").NotTo(BeAnExistingFile())
+
+			lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+			Expect(lines).To(HaveLen(3))
+
+			var first models.ReconResult
+			Expect(json.Unmarshal([]byte(lines[0]), &first)).To(Succeed())
+			Expect(first.TaskID).To(Equal("task_1"))
+			Expect(first.Status).To(Equal(recon_status.Successfull))
+			Expect(*first.MatchedRowNumber).To(Equal(uint64(9)))
+			Expect(first.Values).To(Equal([]string{"1", "10"}))
+		})
+	})
+
+	Context("when the task sends its results to a table and a subject", func() {
+		It("should send every row to both alongside the results file", func() {
+			batchSize := constants.RECON_RESULTS_SAVE_BATCH_SIZE
+			constants.RECON_RESULTS_SAVE_BATCH_SIZE = 2
+			defer func() { constants.RECON_RESULTS_SAVE_BATCH_SIZE = batchSize }()
+
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.Csv")
+			tables := &recordedResultTables{}
+			streams := &publishedResults{}
+			task := models.ReconTaskDetails{
+				ID: "task_1",
+				ResultSinks: []models.ResultSinkConfig{
+					{Type: result_sink_type.Sql, Table: "settlement_results"},
+					{Type: result_sink_type.Stream, Subject: "recon.results"},
+				},
+				ResultTables:  tables,
+				ResultStreams: streams,
+			}
+
+			summary, err := reconstructSections(task, outputPath, sections)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputPath).To(BeAnExistingFile())
+
+			Expect(tables.preparedTables).To(Equal([]string{"settlement_results/task_1"}))
+			Expect(tables.columnHeaders).To(Equal([]string{"Id", "Amount"}))
+			Expect(tables.batches).To(HaveLen(2))
+			Expect(tables.batches[0]).To(HaveLen(2))
+			Expect(tables.batches[1][0].RowNumber).To(Equal(uint64(2)))
+
+			Expect(streams.streamSubjects).To(Equal([]string{
+				constants.RESULT_SINK_STREAM_NAME + "/recon.results",
+				constants.RESULT_SINK_STREAM_NAME + "/recon.results.summary",
+			}))
+			Expect(streams.messages["recon.results"]).To(HaveLen(3))
+			Expect(streams.messages["recon.results"][1].(models.ReconResult).RowNumber).To(Equal(uint64(1)))
+			Expect(streams.messages["recon.results.summary"]).To(Equal([]interface{}{summary}))
+		})
+	})
+
+	Context("when the service has no database for a table sink", func() {
+		It("should fail without leaving the results files behind", func() {
+			outputDir := testOutputDir()
+			task := models.ReconTaskDetails{
+				ID:             "task_1",
+				ResultsFormats: []results_format.ResultsFormat{results_format.Csv, results_format.JsonLines},
+				ResultSinks:    []models.ResultSinkConfig{{Type: result_sink_type.Sql, Table: "settlement_results"}},
+			}
+
+			_, err := reconstructSections(task, filepath.Join(outputDir, "ReconResults.Csv"), sections)
+			Expect(err).To(HaveOccurred())
+
+			entries, err := os.ReadDir(outputDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,456 @@
+package reconstruction
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/results_format"
+	"time"
+)
+
+// reconResultRows goes through the rows of the results in row order,
+// stopping at the first error each returns
+type reconResultRows func(each func(row models.FileSectionRow) error) error
+
+// ResultSink is somewhere the results of a task are sent to, a row at a time in row order as they are reconstructed.
+// Every results format of the task is written by a sink of its own,
+// and the result sinks of the task send the results to tables and streams besides.
+type ResultSink interface {
+	WriteRow(row models.FileSectionRow) error
+	// Finish completes the results once every row has been written
+	Finish(summary models.ReconSummary) error
+	// Abort removes whatever was written of results that can't be completed,
+	// results that were already sent elsewhere are replaced by the next attempt at the task
+	Abort()
+}
+
+// reconResultsOutput takes the rows of the results of a task in row order as they are reconstructed
+// and passes them on to its summary, to the results that can be queried and to every result sink of the task.
+type reconResultsOutput struct {
+	ctx         context.Context
+	taskDetails models.ReconTaskDetails
+	outputPath  string
+	summariser  *reconSummariser
+	saver       *reconResultsSaver
+	sinks       []ResultSink
+	isOpen      bool
+	isFinished  bool
+}
+
+func newReconResultsOutput(ctx context.Context, taskDetails models.ReconTaskDetails, outputPath string) *reconResultsOutput {
+	return &reconResultsOutput{ctx: ctx, taskDetails: taskDetails, outputPath: outputPath}
+}
+
+// open starts the result sinks once the column headers are known
+func (o *reconResultsOutput) open(columnHeaders []string, comparisonColumnHeaders []string) error {
+	saver, err := newReconResultsSaver(o.ctx, o.taskDetails)
+	if err != nil {
+		return fmt.Errorf("failed to save results of task: %v", err)
+	}
+
+	sinks, err := newResultSinks(o.ctx, o.taskDetails, o.outputPath, columnHeaders, comparisonColumnHeaders)
+	if err != nil {
+		return err
+	}
+
+	o.summariser = newReconSummariser(o.taskDetails, columnHeaders)
+	o.saver = saver
+	o.sinks = sinks
+	o.isOpen = true
+	return nil
+}
+
+func (o *reconResultsOutput) writeRow(row models.FileSectionRow) error {
+	o.summariser.addRow(row)
+
+	err := o.saver.addRow(row)
+	if err != nil {
+		return fmt.Errorf("failed to save results of task: %v", err)
+	}
+
+	for _, sink := range o.sinks {
+		err = sink.WriteRow(row)
+		if err != nil {
+			return fmt.Errorf("failed to send results to sink: %v", err)
+		}
+	}
+	return nil
+}
+
+// finish saves what is left of the results, completes every result sink and returns the summary of the results
+func (o *reconResultsOutput) finish() (models.ReconSummary, error) {
+	err := o.saver.flush()
+	if err != nil {
+		return models.ReconSummary{}, fmt.Errorf("failed to save results of task: %v", err)
+	}
+
+	now := time.Now()
+	summary := o.summariser.finish(o.taskDetails.ProgressTracker.Snapshot(now), now)
+
+	for _, sink := range o.sinks {
+		err = sink.Finish(summary)
+		if err != nil {
+			return models.ReconSummary{}, fmt.Errorf("failed to send results to sink: %v", err)
+		}
+	}
+
+	o.isFinished = true
+	return summary, nil
+}
+
+// abort leaves no partial results files behind, unless they were all finished
+func (o *reconResultsOutput) abort() {
+	if o.isFinished {
+		return
+	}
+	for _, sink := range o.sinks {
+		sink.Abort()
+	}
+}
+
+// newResultSinks starts a sink for every format the task writes its results in and for every result sink of the task
+func newResultSinks(
+	ctx context.Context,
+	taskDetails models.ReconTaskDetails,
+	outputPath string,
+	columnHeaders []string,
+	comparisonColumnHeaders []string,
+) ([]ResultSink, error) {
+	sinks, err := newResultsFileSinks(taskDetails, outputPath, columnHeaders, comparisonColumnHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sinkConfig := range taskDetails.ResultSinks {
+		sink, err := newConfiguredResultSink(ctx, taskDetails, sinkConfig, columnHeaders)
+
+		// failed to start the sink, don't leave the others behind
+		if err != nil {
+			for _, startedSink := range sinks {
+				startedSink.Abort()
+			}
+			return nil, fmt.Errorf("failed to send results to %v sink: %v", sinkConfig.Type, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// newResultsFileSinks starts a sink for every format the task writes its results in,
+// the formats that need the summary before the rows share a spool of the rows
+func newResultsFileSinks(
+	taskDetails models.ReconTaskDetails,
+	outputPath string,
+	columnHeaders []string,
+	comparisonColumnHeaders []string,
+) ([]ResultSink, error) {
+	sinks := make([]ResultSink, 0)
+	var spooledSink *spooledResultSink
+
+	for _, format := range taskDetails.ResultsFormatsToWrite() {
+		formatOutputPath := ResultsFilePathIn(outputPath, format)
+
+		var err error
+		switch format {
+		case results_format.Csv:
+			var sink ResultSink
+			sink, err = newCsvResultSink(formatOutputPath, columnHeaders)
+			if err == nil {
+				sinks = append(sinks, sink)
+			}
+		case results_format.JsonLines:
+			var sink ResultSink
+			sink, err = newJsonLinesResultSink(formatOutputPath, taskDetails.ID)
+			if err == nil {
+				sinks = append(sinks, sink)
+			}
+		case results_format.SideBySide:
+			var sink ResultSink
+			sink, err = newSideBySideResultSink(formatOutputPath, taskDetails, columnHeaders, comparisonColumnHeaders)
+			if err == nil {
+				sinks = append(sinks, sink)
+			}
+		case results_format.Xlsx, results_format.Html, results_format.Pdf:
+			if spooledSink == nil {
+				spooledSink, err = newSpooledResultSink()
+				if err == nil {
+					sinks = append(sinks, spooledSink)
+				}
+			}
+			if err == nil {
+				spooledSink.addFormat(formatOutputPath, spooledResultsFileWriter(format, taskDetails, columnHeaders))
+			}
+		default:
+			err = fmt.Errorf("results can not be written as [%v]", format)
+		}
+
+		// failed to start the sink, don't leave the others behind
+		if err != nil {
+			for _, sink := range sinks {
+				sink.Abort()
+			}
+			return nil, fmt.Errorf("failed to write %v results to file: %v", format, err)
+		}
+	}
+	return sinks, nil
+}
+
+// spooledResultsFileWriter writes the whole results file of a format that needs the summary before the rows
+func spooledResultsFileWriter(
+	format results_format.ResultsFormat,
+	taskDetails models.ReconTaskDetails,
+	columnHeaders []string,
+) func(path string, rows reconResultRows, summary models.ReconSummary) error {
+	return func(path string, rows reconResultRows, summary models.ReconSummary) error {
+		switch format {
+		case results_format.Xlsx:
+			return writeReconResultsWorkbook(path, columnHeaders, rows, summary)
+		case results_format.Html:
+			return writeReconResultsReport(path, taskDetails, columnHeaders, rows, summary)
+		case results_format.Pdf:
+			return writeReconResultsPdf(path, taskDetails, columnHeaders, rows, summary)
+		default:
+			return fmt.Errorf("results can not be written as [%v]", format)
+		}
+	}
+}
+
+// csvResultsFile is a csv written next to its output path
+// and only moved into place once it is complete,
+// so a process that stops half way never leaves a partial results file behind.
+type csvResultsFile struct {
+	outputPath string
+	file       *os.File
+	writer     *csv.Writer
+}
+
+func createCsvResultsFile(outputPath string, headers []string) (*csvResultsFile, error) {
+	file, err := os.Create(outputPath + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	resultsFile := &csvResultsFile{outputPath: outputPath, file: file, writer: csv.NewWriter(file)}
+	err = resultsFile.write(headers)
+	if err != nil {
+		resultsFile.abort()
+		return nil, err
+	}
+	return resultsFile, nil
+}
+
+func (f *csvResultsFile) write(record []string) error {
+	return f.writer.Write(record)
+}
+
+func (f *csvResultsFile) finish() error {
+	f.writer.Flush()
+	err := f.writer.Error()
+	if err == nil {
+		err = f.file.Sync()
+	}
+	closeErr := f.file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	// failed to write, don't leave the partial file behind
+	if err != nil {
+		_ = os.Remove(f.file.Name())
+		return err
+	}
+	return os.Rename(f.file.Name(), f.outputPath)
+}
+
+func (f *csvResultsFile) abort() {
+	_ = f.file.Close()
+	_ = os.Remove(f.file.Name())
+}
+
+// csvResultSink writes the columns of every row followed by its status and the reasons for it
+type csvResultSink struct {
+	file *csvResultsFile
+}
+
+func newCsvResultSink(outputPath string, columnHeaders []string) (*csvResultSink, error) {
+	headers := append(append([]string{}, columnHeaders...), "ReconResult", "ReconResultReasons")
+	file, err := createCsvResultsFile(outputPath, headers)
+	if err != nil {
+		return nil, err
+	}
+	return &csvResultSink{file: file}, nil
+}
+
+func (w *csvResultSink) WriteRow(row models.FileSectionRow) error {
+	record := make([]string, 0, len(row.ParsedColumnsFromRow)+2)
+	record = append(record, row.ParsedColumnsFromRow...)
+	record = append(record, string(row.ReconResult), reasonsText(row))
+	return w.file.write(record)
+}
+
+func (w *csvResultSink) Finish(summary models.ReconSummary) error {
+	return w.file.finish()
+}
+
+func (w *csvResultSink) Abort() {
+	w.file.abort()
+}
+
+// spooledResultSink keeps the rows in a spool as they are reconstructed and writes the results files
+// of the formats that need the summary before the rows once every row has been reconstructed
+type spooledResultSink struct {
+	spool   *reconResultsSpool
+	formats []spooledResultsFormat
+}
+
+type spooledResultsFormat struct {
+	outputPath string
+	write      func(path string, rows reconResultRows, summary models.ReconSummary) error
+}
+
+func newSpooledResultSink() (*spooledResultSink, error) {
+	spool, err := newReconResultsSpool(constants.RECONSTRUCTION_SPILL_DIRECTORY)
+	if err != nil {
+		return nil, err
+	}
+	return &spooledResultSink{spool: spool}, nil
+}
+
+func (w *spooledResultSink) addFormat(outputPath string, write func(path string, rows reconResultRows, summary models.ReconSummary) error) {
+	w.formats = append(w.formats, spooledResultsFormat{outputPath: outputPath, write: write})
+}
+
+func (w *spooledResultSink) WriteRow(row models.FileSectionRow) error {
+	return w.spool.add(row)
+}
+
+func (w *spooledResultSink) Finish(summary models.ReconSummary) error {
+	defer w.spool.remove()
+
+	for _, format := range w.formats {
+		err := writeOutToFile(format.outputPath, func(path string) error {
+			return format.write(path, w.spool.rows, summary)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *spooledResultSink) Abort() {
+	w.spool.remove()
+}
+
+// reconResultsSpool keeps the rows of the results in a file, a json document each,
+// so they can be gone through again without holding them in memory
+type reconResultsSpool struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newReconResultsSpool(directory string) (*reconResultsSpool, error) {
+	file, err := os.CreateTemp(directory, "reconstruction-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("error on creating results spool: [%v]", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	return &reconResultsSpool{file: file, writer: writer, encoder: json.NewEncoder(writer)}, nil
+}
+
+func (s *reconResultsSpool) add(row models.FileSectionRow) error {
+	return s.encoder.Encode(row)
+}
+
+// rows goes through the rows of the spool from the first one
+func (s *reconResultsSpool) rows(each func(row models.FileSectionRow) error) error {
+	err := s.writer.Flush()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var row models.FileSectionRow
+		err = decoder.Decode(&row)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = each(row)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *reconResultsSpool) remove() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// reconResultsSaver replaces the results of any earlier attempt at the task with the results of its rows,
+// saving them constants.RECON_RESULTS_SAVE_BATCH_SIZE at a time.
+type reconResultsSaver struct {
+	ctx         context.Context
+	taskDetails models.ReconTaskDetails
+	batch       []models.ReconResult
+}
+
+func newReconResultsSaver(ctx context.Context, taskDetails models.ReconTaskDetails) (*reconResultsSaver, error) {
+	if taskDetails.Results != nil {
+		err := taskDetails.Results.DeleteReconResults(ctx, taskDetails.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &reconResultsSaver{
+		ctx:         ctx,
+		taskDetails: taskDetails,
+		batch:       make([]models.ReconResult, 0, constants.RECON_RESULTS_SAVE_BATCH_SIZE),
+	}, nil
+}
+
+func (s *reconResultsSaver) addRow(row models.FileSectionRow) error {
+	if s.taskDetails.Results == nil {
+		return nil
+	}
+
+	s.batch = append(s.batch, models.NewReconResult(s.taskDetails.ID, row))
+	if len(s.batch) < constants.RECON_RESULTS_SAVE_BATCH_SIZE {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush saves the rows that are waiting for a full batch
+func (s *reconResultsSaver) flush() error {
+	if s.taskDetails.Results == nil || len(s.batch) == 0 {
+		return nil
+	}
+
+	err := s.taskDetails.Results.SaveReconResults(s.ctx, s.taskDetails.ID, s.batch)
+	if err != nil {
+		return err
+	}
+	s.batch = s.batch[:0]
+	return nil
+}
@@ -0,0 +1,242 @@
+package reconstruction
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jung-kurt/gofpdf"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"strconv"
+	"strings"
+)
+
+const (
+	pdfLineHeight   = 5.0
+	pdfLabelWidth   = 70.0
+	pdfSectionSpace = 4.0
+)
+
+// pdfBreakColumns are the columns of the list of breaks and how wide they are in mm,
+// filling the width of a landscape A4 page inside its margins
+var pdfBreakColumns = []struct {
+	header string
+	width  float64
+}{
+	{header: "Row", width: 18},
+	{header: "Break", width: 45},
+	{header: "Comparison row", width: 27},
+	{header: "Values", width: 87},
+	{header: "Differences", width: 100},
+}
+
+// writeReconResultsPdf writes an audit report of the task for signing off,
+// with who ran it, the fingerprints of its files, how it was configured,
+// the summary of its results and the breaks listed over as many pages as they take.
+func writeReconResultsPdf(
+	outputPath string,
+	taskDetails models.ReconTaskDetails,
+	columnHeaders []string,
+	rows reconResultRows,
+	summary models.ReconSummary,
+) error {
+	report, err := newReconReport(taskDetails, columnHeaders, rows, summary)
+	if err != nil {
+		return err
+	}
+	return newReconResultsPdf(report).OutputFileAndClose(outputPath)
+}
+
+func newReconResultsPdf(report reconReport) *gofpdf.Fpdf {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	text := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetTitle(fmt.Sprintf("Reconciliation report %v", report.Task.ID), true)
+	pdf.SetAuthor(report.Task.UserID, true)
+	pdf.SetCreator("reconciler", true)
+	pdf.SetCreationDate(report.Summary.GeneratedAt)
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, pdfLineHeight, text(fmt.Sprintf("Reconciliation task %v", report.Task.ID)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, pdfLineHeight, fmt.Sprintf("Page %v of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, text(fmt.Sprintf("Reconciliation report %v", report.Task.ID)), "", 1, "L", false, 0, "")
+
+	runBy := report.Task.UserID
+	if len(runBy) == 0 {
+		runBy = "unknown"
+	}
+	writePdfSection(pdf, text, "Task", [][2]string{
+		{"Task ID", report.Task.ID},
+		{"Run by", runBy},
+		{"Created", formatReportTime(report.Task.CreatedAt)},
+		{"Reconciled", formatReportTime(report.Summary.GeneratedAt)},
+	})
+
+	writePdfSection(pdf, text, "Files", [][2]string{
+		{"Primary file SHA-256", fileFingerprint(report.Task.PrimaryFileID)},
+		{"Comparison file SHA-256", fileFingerprint(report.Task.ComparisonFileID)},
+	})
+
+	configuration := make([][2]string, 0, len(report.ComparisonPairs)+5)
+	for _, pair := range report.ComparisonPairs {
+		compared := fmt.Sprintf("%v against %v", pair.PrimaryColumn, pair.ComparisonColumn)
+		if pair.IsRowIdentifier {
+			compared += " (row identifier)"
+		}
+		configuration = append(configuration, [2]string{"Compared columns", compared})
+	}
+	configuration = append(configuration,
+		[2]string{"Case sensitive", strconv.FormatBool(report.Task.ReconConfig.ShouldReconciliationBeCaseSensitive)},
+		[2]string{"Ignore white space", strconv.FormatBool(report.Task.ReconConfig.ShouldIgnoreWhiteSpace)},
+		[2]string{"Check for duplicates in comparison file", strconv.FormatBool(report.Task.ReconConfig.ShouldCheckForDuplicateRecordsInComparisonFile)},
+		[2]string{"Reverse reconciliation", strconv.FormatBool(report.Task.ReconConfig.ShouldDoReverseReconciliation)},
+		[2]string{"Partitions", strconv.Itoa(report.Task.PartitionCount)},
+	)
+	writePdfSection(pdf, text, "Configuration", configuration)
+
+	statistics := [][2]string{{"Rows in primary file", strconv.FormatInt(report.Summary.PrimaryFileRows, 10)}}
+	if report.Summary.ComparisonFileRows != nil {
+		statistics = append(statistics, [2]string{"Rows in comparison file", strconv.FormatInt(*report.Summary.ComparisonFileRows, 10)})
+	}
+	for _, bar := range report.StatusChart {
+		statistics = append(statistics, [2]string{bar.Label, fmt.Sprintf("%.0f", bar.Value)})
+	}
+	for _, chart := range report.AmountCharts {
+		statistics = append(statistics, [2]string{fmt.Sprintf("Total of %v", chart.Column), formatReportAmount(chart.Total)})
+		for _, bar := range chart.Bars {
+			statistics = append(statistics, [2]string{fmt.Sprintf("Total of %v (%v)", chart.Column, bar.Label), formatReportAmount(bar.Value)})
+		}
+	}
+	writePdfSection(pdf, text, "Summary", statistics)
+
+	writePdfSignOff(pdf, text)
+
+	pdf.AddPage()
+	writePdfHeading(pdf, text, fmt.Sprintf("Breaks (%v)", len(report.Breaks)+report.BreaksLeftOut))
+	if report.BreaksLeftOut > 0 {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, pdfLineHeight, text(fmt.Sprintf("The first %v breaks are listed, the other %v are in the results file.", len(report.Breaks), report.BreaksLeftOut)), "", "L", false)
+	}
+	writePdfBreaks(pdf, text, report.Breaks)
+
+	return pdf
+}
+
+func writePdfHeading(pdf *gofpdf.Fpdf, text func(string) string, heading string) {
+	pdf.Ln(pdfSectionSpace)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, text(heading), "B", 1, "L", false, 0, "")
+	pdf.Ln(1)
+}
+
+// writePdfSection writes the heading and a row for each label and its value
+func writePdfSection(pdf *gofpdf.Fpdf, text func(string) string, heading string, rows [][2]string) {
+	writePdfHeading(pdf, text, heading)
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(pdfLabelWidth, pdfLineHeight, text(row[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, pdfLineHeight, text(row[1]), "", "L", false)
+	}
+}
+
+// writePdfSignOff leaves room for whoever prepares and reviews the reconciliation to sign it off
+func writePdfSignOff(pdf *gofpdf.Fpdf, text func(string) string) {
+	writePdfHeading(pdf, text, "Sign-off")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, role := range []string{"Prepared by", "Reviewed by"} {
+		pdf.Ln(pdfLineHeight)
+		for _, field := range []string{role, "Signature", "Date"} {
+			pdf.CellFormat(25, pdfLineHeight, text(field), "", 0, "L", false, 0, "")
+			pdf.CellFormat(60, pdfLineHeight, "", "B", 0, "L", false, 0, "")
+			pdf.CellFormat(5, pdfLineHeight, "", "", 0, "L", false, 0, "")
+		}
+		pdf.Ln(pdfLineHeight)
+	}
+}
+
+// writePdfBreaks lists the breaks a row each, starting a new page whenever a row doesn't fit
+// and repeating the headers at the top of every page
+func writePdfBreaks(pdf *gofpdf.Fpdf, text func(string) string, breaks []reportBreak) {
+	writePdfBreaksHeader(pdf, text)
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+
+	pdf.SetFont("Helvetica", "", 8)
+	for _, reportRow := range breaks {
+		values := make([]string, 0, len(reportRow.Cells))
+		for _, cell := range reportRow.Cells {
+			values = append(values, cell.Value)
+		}
+		cells := []string{
+			strconv.FormatUint(reportRow.RowNumber, 10),
+			reportRow.Kind,
+			reportRow.ComparisonFileRow,
+			strings.Join(values, " | "),
+			reportRow.Differences,
+		}
+
+		// the cells are translated into the code page of the font
+		// before they are measured, so their lines are split on bytes
+		lines := make([][][]byte, len(cells))
+		rowLines := 1
+		for i, cell := range cells {
+			lines[i] = pdf.SplitLines([]byte(text(cell)), pdfBreakColumns[i].width)
+			if len(lines[i]) > rowLines {
+				rowLines = len(lines[i])
+			}
+		}
+		rowHeight := float64(rowLines) * (pdfLineHeight - 1)
+
+		if pdf.GetY()+rowHeight > pageHeight-bottomMargin-5 {
+			pdf.AddPage()
+			writePdfBreaksHeader(pdf, text)
+			pdf.SetFont("Helvetica", "", 8)
+		}
+
+		leftMargin, y := pdf.GetXY()
+		x := leftMargin
+		for i, column := range pdfBreakColumns {
+			pdf.Rect(x, y, column.width, rowHeight, "D")
+			pdf.SetXY(x, y)
+			pdf.MultiCell(column.width, pdfLineHeight-1, string(bytes.Join(lines[i], []byte("\n"))), "", "L", false)
+			x += column.width
+		}
+		pdf.SetXY(leftMargin, y+rowHeight)
+	}
+}
+
+func writePdfBreaksHeader(pdf *gofpdf.Fpdf, text func(string) string) {
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(243, 243, 243)
+	for _, column := range pdfBreakColumns {
+		pdf.CellFormat(column.width, pdfLineHeight+1, text(column.header), "1", 0, "L", true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// reportDifferences describes how the row differs from the row it was matched to
+func reportDifferences(row models.FileSectionRow) string {
+	if row.ReconResult == recon_status.Successfull {
+		return ""
+	}
+
+	differences := make([]string, 0, len(row.ReconResultReasons))
+	for _, reason := range row.ReconResultReasons {
+		switch reason.Code {
+		case recon_reason_code.RowMismatchFound:
+			differences = append(differences, fmt.Sprintf("%v: %v against %v", reason.PrimaryColumn, reason.PrimaryValue, reason.ComparisonValue))
+		case recon_reason_code.NoMatchFound:
+			differences = append(differences, "No matching row in the comparison file")
+		}
+	}
+	return strings.Join(differences, "\n")
+}
@@ -0,0 +1,74 @@
+package reconstruction
+
+import (
+	"bytes"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"time"
+)
+
+var _ = Describe("WriteReconResultsPdf", func() {
+	matchedRow := uint64(1)
+	taskDetails := models.ReconTaskDetails{
+		ID:               "task_1",
+		UserID:           "month-end-team",
+		PrimaryFileID:    "PrimaryFile-e3111a2e7972e5c268a6bc76ba731112bf2ca01717c06b35425a89f5ff2ad37f",
+		ComparisonFileID: "ComparisonFile-098cdaa0920e4373cc94b1af8ebff18daff78d95a6b953834e67676d850a5376",
+		ComparisonPairs: []models.ComparisonPair{
+			{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 0, IsRowIdentifier: true},
+			{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 1},
+		},
+	}
+	summary := models.ReconSummary{PrimaryFileRows: 200, MismatchedRows: 200, GeneratedAt: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}
+
+	// enough breaks to fill a few pages, with values the fonts of the pdf can't all show
+	rows := make([]models.FileSectionRow, 0, 200)
+	for i := uint64(0); i < 200; i++ {
+		rows = append(rows, models.FileSectionRow{
+			RowNumber:            i,
+			ParsedColumnsFromRow: []string{fmt.Sprintf("TXN-%v", i), "1 000 €", "Café 中文"},
+			ReconResult:          recon_status.Failed,
+			MatchedRowNumber:     &matchedRow,
+			ReconResultReasons: []models.ReconReason{
+				{Code: recon_reason_code.RowMismatchFound, PrimaryColumn: "Amount", ComparisonColumn: "Amount", PrimaryValue: "1 000 €", ComparisonValue: "999 €"},
+			},
+		})
+	}
+	sections := []models.FileSection{{SectionSequenceNumber: 1, IsLastSection: true, ColumnHeaders: []string{"Id", "Amount", "Note"}, SectionRows: rows}}
+
+	Context("when the report is written", func() {
+		It("should write a pdf file", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.pdf")
+
+			Expect(writeReconResultsPdf(outputPath, taskDetails, sections[0].ColumnHeaders, sectionRows(sections), summary)).To(Succeed())
+
+			contents, err := os.ReadFile(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents[:5])).To(Equal("%PDF-"))
+		})
+
+		It("should say who ran the task, the hashes of its files and list every break over numbered pages", func() {
+			report, err := newReconReport(taskDetails, sections[0].ColumnHeaders, sectionRows(sections), summary)
+			Expect(err).NotTo(HaveOccurred())
+			pdf := newReconResultsPdf(report)
+			pdf.SetCompression(false)
+
+			var output bytes.Buffer
+			Expect(pdf.Output(&output)).To(Succeed())
+			contents := output.String()
+
+			Expect(contents).To(ContainSubstring("(month-end-team)"))
+			Expect(contents).To(ContainSubstring("(e3111a2e7972e5c268a6bc76ba731112bf2ca01717c06b35425a89f5ff2ad37f)"))
+			Expect(contents).To(ContainSubstring("(Breaks \\(200\\))"))
+			Expect(contents).To(ContainSubstring("(TXN-199 | 1 000 \x80 | Caf\xe9 ..)"))
+			Expect(contents).To(ContainSubstring(fmt.Sprintf("(Page 1 of %v)", pdf.PageCount())))
+			Expect(pdf.PageCount()).To(BeNumerically(">", 3))
+		})
+	})
+})
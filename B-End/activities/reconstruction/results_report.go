@@ -0,0 +1,242 @@
+package reconstruction
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the report carries its styles and scripts inline so it can be opened without a network
+//
+//go:embed templates/report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"amount":      formatReportAmount,
+	"time":        formatReportTime,
+	"fingerprint": fileFingerprint,
+}).Parse(reportTemplateText))
+
+// reconReport is what the html report of a task is filled in with
+type reconReport struct {
+	Task            models.ReconTaskDetails
+	Summary         models.ReconSummary
+	StatusChart     []reportBar
+	AmountCharts    []reportAmountChart
+	ComparisonPairs []reportComparisonPair
+	ColumnHeaders   []string
+	Breaks          []reportBreak
+	BreaksLeftOut   int
+}
+
+// reportBar is a bar of a chart, Width is how much of the chart it fills from 0 to 100
+type reportBar struct {
+	Label string
+	Value float64
+	Width float64
+}
+
+type reportAmountChart struct {
+	Column string
+	Total  float64
+	Bars   []reportBar
+}
+
+type reportComparisonPair struct {
+	PrimaryColumn    string
+	ComparisonColumn string
+	IsRowIdentifier  bool
+}
+
+// reportBreak is a row of the primary file that didn't match,
+// either because columns differ from the row it was matched to or because nothing matched it
+type reportBreak struct {
+	RowNumber         uint64
+	ComparisonFileRow string
+	Kind              string
+	Cells             []reportCell
+	Reasons           string
+	Differences       string
+}
+
+type reportCell struct {
+	Value      string
+	IsMismatch bool
+}
+
+// writeReconResultsReport writes a single html file with the summary of the task as charts,
+// how it was configured, the fingerprints of its files and a table of its breaks
+// that can be searched and sorted.
+func writeReconResultsReport(
+	outputPath string,
+	taskDetails models.ReconTaskDetails,
+	columnHeaders []string,
+	rows reconResultRows,
+	summary models.ReconSummary,
+) error {
+	report, err := newReconReport(taskDetails, columnHeaders, rows, summary)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = reportTemplate.Execute(file, report)
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// newReconReport fills in the report, holding on to no more than constants.RECON_REPORT_MAX_BREAKS of the breaks
+func newReconReport(taskDetails models.ReconTaskDetails, columnHeaders []string, rows reconResultRows, summary models.ReconSummary) (reconReport, error) {
+	report := reconReport{
+		Task:          taskDetails,
+		Summary:       summary,
+		StatusChart:   reportStatusChart(summary),
+		ColumnHeaders: columnHeaders,
+	}
+
+	for _, amountTotal := range summary.AmountTotals {
+		chart := reportAmountChart{Column: amountTotal.Column, Total: amountTotal.Total}
+		for _, status := range []recon_status.ReconciliationStatus{recon_status.Successfull, recon_status.Failed, recon_status.Pending} {
+			if total, exists := amountTotal.TotalsByStatus[status]; exists {
+				chart.Bars = append(chart.Bars, reportBar{Label: string(status), Value: total})
+			}
+		}
+		scaleReportBars(chart.Bars)
+		report.AmountCharts = append(report.AmountCharts, chart)
+	}
+
+	for _, pair := range taskDetails.ComparisonPairs {
+		report.ComparisonPairs = append(report.ComparisonPairs, reportComparisonPair{
+			PrimaryColumn:    reportColumnName(columnHeaders, pair.PrimaryFileColumnIndex),
+			ComparisonColumn: fmt.Sprintf("Column %v", pair.ComparisonFileColumnIndex+1),
+			IsRowIdentifier:  pair.IsRowIdentifier,
+		})
+	}
+
+	err := rows(func(row models.FileSectionRow) error {
+		if row.ReconResult == recon_status.Successfull {
+			return nil
+		}
+		if len(report.Breaks) >= constants.RECON_REPORT_MAX_BREAKS {
+			report.BreaksLeftOut++
+			return nil
+		}
+		report.Breaks = append(report.Breaks, newReportBreak(row, columnHeaders))
+		return nil
+	})
+	return report, err
+}
+
+// reportStatusChart has a bar for each way the rows came out,
+// leaving out the rows of the comparison file when they could not be counted
+func reportStatusChart(summary models.ReconSummary) []reportBar {
+	bars := []reportBar{
+		{Label: "Matched", Value: float64(summary.MatchedRows)},
+		{Label: "Values differ", Value: float64(summary.MismatchedRows)},
+		{Label: "Missing from comparison file", Value: float64(summary.MissingFromComparisonFile)},
+	}
+	if summary.MissingFromPrimaryFile != nil {
+		bars = append(bars, reportBar{Label: "Missing from primary file", Value: float64(*summary.MissingFromPrimaryFile)})
+	}
+	bars = append(bars, reportBar{Label: "Duplicates", Value: float64(summary.DuplicateRows)})
+	scaleReportBars(bars)
+	return bars
+}
+
+// scaleReportBars sizes the bars against the longest of them
+func scaleReportBars(bars []reportBar) {
+	longest := 0.0
+	for _, bar := range bars {
+		longest = math.Max(longest, math.Abs(bar.Value))
+	}
+	if longest == 0 {
+		return
+	}
+	for i := range bars {
+		bars[i].Width = math.Round(math.Abs(bars[i].Value)/longest*1000) / 10
+	}
+}
+
+func newReportBreak(row models.FileSectionRow, columnHeaders []string) reportBreak {
+	mismatchingColumns := make(map[string]bool)
+	for _, reason := range row.ReconResultReasons {
+		if reason.Code == recon_reason_code.RowMismatchFound {
+			mismatchingColumns[reason.PrimaryColumn] = true
+		}
+	}
+
+	reportRow := reportBreak{
+		RowNumber:   row.RowNumber,
+		Kind:        "Missing from comparison file",
+		Reasons:     reasonsText(row),
+		Differences: reportDifferences(row),
+	}
+	if row.MatchedRowNumber != nil {
+		reportRow.Kind = "Values differ"
+		reportRow.ComparisonFileRow = strconv.FormatUint(*row.MatchedRowNumber, 10)
+	}
+	for i, value := range row.ParsedColumnsFromRow {
+		reportRow.Cells = append(reportRow.Cells, reportCell{
+			Value:      value,
+			IsMismatch: mismatchingColumns[reportColumnName(columnHeaders, i)],
+		})
+	}
+	return reportRow
+}
+
+func reportColumnName(columnHeaders []string, columnIndex int) string {
+	if columnIndex < len(columnHeaders) {
+		return columnHeaders[columnIndex]
+	}
+	return fmt.Sprintf("Column %v", columnIndex+1)
+}
+
+// formatReportAmount writes the amount with two decimals and its thousands separated by commas
+func formatReportAmount(amount float64) string {
+	formatted := strconv.FormatFloat(math.Abs(amount), 'f', 2, 64)
+	whole, decimals, _ := strings.Cut(formatted, ".")
+
+	var separated strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			separated.WriteByte(',')
+		}
+		separated.WriteRune(digit)
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	return sign + separated.String() + "." + decimals
+}
+
+// fileFingerprint is the SHA-256 hash of the contents of a file, which its ID
+// carries after the purpose of the file
+func fileFingerprint(fileID string) string {
+	for _, purpose := range []file_purpose.FilePurposeType{file_purpose.PrimaryFile, file_purpose.ComparisonFile} {
+		fileID = strings.TrimPrefix(fileID, string(purpose)+"-")
+	}
+	return fileID
+}
+
+func formatReportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC1123)
+}
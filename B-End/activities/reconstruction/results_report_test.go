@@ -0,0 +1,119 @@
+package reconstruction
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"time"
+)
+
+var _ = Describe("WriteReconResultsReport", func() {
+	matchedRow := uint64(7)
+	taskDetails := models.ReconTaskDetails{
+		ID:               "task_1",
+		PrimaryFileID:    "PrimaryFile-e3111a2e7972e5c268a6bc76ba731112bf2ca01717c06b35425a89f5ff2ad37f",
+		ComparisonFileID: "ComparisonFile-098cdaa0920e4373cc94b1af8ebff18daff78d95a6b953834e67676d850a5376",
+		ComparisonPairs: []models.ComparisonPair{
+			{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 0, IsRowIdentifier: true},
+			{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 2},
+		},
+		ReconConfig: models.ReconciliationConfigs{ShouldIgnoreWhiteSpace: true},
+	}
+	summary := models.ReconSummary{
+		PrimaryFileRows:           3,
+		MatchedRows:               1,
+		MismatchedRows:            1,
+		MissingFromComparisonFile: 1,
+		AmountTotals: []models.AmountTotal{{
+			Column: "Amount",
+			Total:  1234567.5,
+			TotalsByStatus: map[recon_status.ReconciliationStatus]float64{
+				recon_status.Successfull: 1234000,
+				recon_status.Failed:      567.5,
+			},
+		}},
+		GeneratedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	sections := []models.FileSection{{
+		SectionSequenceNumber: 1,
+		IsLastSection:         true,
+		ColumnHeaders:         []string{"Id", "Amount"},
+		SectionRows: []models.FileSectionRow{
+			{RowNumber: 1, ParsedColumnsFromRow: []string{"1", "1234000"}, ReconResult: recon_status.Successfull, MatchedRowNumber: &matchedRow},
+			{
+				RowNumber:            2,
+				ParsedColumnsFromRow: []string{"<script>alert(1)</script>", "500"},
+				ReconResult:          recon_status.Failed,
+				MatchedRowNumber:     &matchedRow,
+				ReconResultReasons: []models.ReconReason{
+					{Code: recon_reason_code.RowMismatchFound, PrimaryColumn: "Amount", ComparisonColumn: "Amount", PrimaryValue: "500", ComparisonValue: "600", CounterpartRowNumber: &matchedRow},
+				},
+			},
+			{RowNumber: 3, ParsedColumnsFromRow: []string{"3", "67.5"}, ReconResult: recon_status.Failed, ReconResultReasons: []models.ReconReason{{Code: recon_reason_code.NoMatchFound}}},
+		},
+	}}
+
+	readReport := func() string {
+		outputPath := filepath.Join(testOutputDir(), "ReconResults.html")
+		Expect(writeReconResultsReport(outputPath, taskDetails, sections[0].ColumnHeaders, sectionRows(sections), summary)).To(Succeed())
+
+		contents, err := os.ReadFile(outputPath)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Context("when the report is written", func() {
+		It("should hold the fingerprints of the files and how the task was configured", func() {
+			report := readReport()
+
+			Expect(report).To(ContainSubstring(`<td class="fingerprint">e3111a2e7972e5c268a6bc76ba731112bf2ca01717c06b35425a89f5ff2ad37f</td>`))
+			Expect(report).To(ContainSubstring(`<td class="fingerprint">098cdaa0920e4373cc94b1af8ebff18daff78d95a6b953834e67676d850a5376</td>`))
+			Expect(report).To(ContainSubstring("<tr><td>Amount</td><td>Column 3</td><td>No</td></tr>"))
+			Expect(report).To(ContainSubstring("<tr><th>Ignore white space</th><td>true</td></tr>"))
+		})
+
+		It("should chart the statuses and the amount totals", func() {
+			report := readReport()
+
+			Expect(report).To(ContainSubstring(`<span class="chart-label">Matched</span><span class="chart-track"><div class="chart-bar" style="width: 100%"></div></span><span class="chart-value">1</span>`))
+			Expect(report).To(ContainSubstring("<h4>Amount: 1,234,567.50</h4>"))
+			Expect(report).To(ContainSubstring(`<span class="chart-value">567.50</span>`))
+		})
+
+		It("should only list the breaks, highlighting the cells that differ", func() {
+			report := readReport()
+
+			Expect(report).To(ContainSubstring(`<td>Values differ</td><td>7</td><td>&lt;script&gt;alert(1)&lt;/script&gt;</td><td class="mismatch">500</td>`))
+			Expect(report).To(ContainSubstring(`<td>Missing from comparison file</td><td></td><td>3</td><td>67.5</td>`))
+			Expect(report).NotTo(ContainSubstring("<td>1234000</td>"))
+			Expect(report).NotTo(ContainSubstring("<script>alert(1)</script>"))
+		})
+	})
+
+	Context("when there are more breaks than the report lists", func() {
+		It("should say how many were left out", func() {
+			maxBreaks := constants.RECON_REPORT_MAX_BREAKS
+			constants.RECON_REPORT_MAX_BREAKS = 1
+			defer func() { constants.RECON_REPORT_MAX_BREAKS = maxBreaks }()
+
+			report, err := newReconReport(taskDetails, sections[0].ColumnHeaders, sectionRows(sections), summary)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Breaks).To(HaveLen(1))
+			Expect(report.BreaksLeftOut).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("FormatReportAmount", func() {
+	It("should separate the thousands and keep two decimals", func() {
+		Expect(formatReportAmount(0)).To(Equal("0.00"))
+		Expect(formatReportAmount(999.999)).To(Equal("1,000.00"))
+		Expect(formatReportAmount(-1234567.891)).To(Equal("-1,234,567.89"))
+	})
+})
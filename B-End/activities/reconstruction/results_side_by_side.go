@@ -0,0 +1,107 @@
+package reconstruction
+
+import (
+	"fmt"
+	"reconciler.io/models"
+	"strconv"
+)
+
+const (
+	sideBySideComparisonColumnPrefix = "Comparison_"
+	sideBySideDiffersColumnPrefix    = "Differs_"
+)
+
+// sideBySideResultSink writes every row of the primary file next to the row of the comparison file
+// it was matched to, followed by its status and a flag for each compared column saying whether the two rows differ in it.
+// The comparison columns and the flags are left empty for rows that were not matched.
+type sideBySideResultSink struct {
+	file                  *csvResultsFile
+	comparedPairs         []models.ComparisonPair
+	comparisonColumnCount int
+}
+
+func newSideBySideResultSink(
+	outputPath string,
+	taskDetails models.ReconTaskDetails,
+	columnHeaders []string,
+	comparisonColumnHeaders []string,
+) (*sideBySideResultSink, error) {
+	comparedPairs := findComparedPairs(taskDetails.ComparisonPairs)
+
+	// sections reconciled before the headers of the comparison file were carried have none,
+	// its columns are then named by their position, taking it to be as wide as the primary file
+	if len(comparisonColumnHeaders) == 0 {
+		for i := range columnHeaders {
+			comparisonColumnHeaders = append(comparisonColumnHeaders, fmt.Sprintf("column_%d", i+1))
+		}
+	}
+
+	headers := make([]string, 0, len(columnHeaders)+len(comparisonColumnHeaders)+len(comparedPairs)+1)
+	headers = append(headers, columnHeaders...)
+	for _, header := range comparisonColumnHeaders {
+		headers = append(headers, sideBySideComparisonColumnPrefix+header)
+	}
+	headers = append(headers, "ReconResult")
+	for _, pair := range comparedPairs {
+		headers = append(headers, sideBySideDiffersColumnPrefix+reportColumnName(columnHeaders, pair.PrimaryFileColumnIndex))
+	}
+
+	file, err := createCsvResultsFile(outputPath, headers)
+	if err != nil {
+		return nil, err
+	}
+	return &sideBySideResultSink{file: file, comparedPairs: comparedPairs, comparisonColumnCount: len(comparisonColumnHeaders)}, nil
+}
+
+func (w *sideBySideResultSink) WriteRow(row models.FileSectionRow) error {
+	record := make([]string, 0, len(row.ParsedColumnsFromRow)+w.comparisonColumnCount+len(w.comparedPairs)+1)
+	record = append(record, row.ParsedColumnsFromRow...)
+	record = append(record, padColumns(row.MatchedColumnsFromRow, w.comparisonColumnCount)...)
+	record = append(record, string(row.ReconResult))
+	for _, pair := range w.comparedPairs {
+		record = append(record, columnDiffersFlag(row, pair))
+	}
+	return w.file.write(record)
+}
+
+func (w *sideBySideResultSink) Finish(summary models.ReconSummary) error {
+	return w.file.finish()
+}
+
+func (w *sideBySideResultSink) Abort() {
+	w.file.abort()
+}
+
+// findComparedPairs returns the comparison pairs that are compared once the row is found,
+// leaving out the ones that identify the row
+func findComparedPairs(comparisonPairs []models.ComparisonPair) []models.ComparisonPair {
+	comparedPairs := make([]models.ComparisonPair, 0, len(comparisonPairs))
+	for _, pair := range comparisonPairs {
+		if !pair.IsRowIdentifier {
+			comparedPairs = append(comparedPairs, pair)
+		}
+	}
+	return comparedPairs
+}
+
+// padColumns gives the columns of a row at least the number of columns of the file, empty when the row has none
+func padColumns(columns []string, columnCount int) []string {
+	if len(columns) >= columnCount {
+		return columns
+	}
+	padded := make([]string, columnCount)
+	copy(padded, columns)
+	return padded
+}
+
+// columnDiffersFlag says whether the row differs from the row it was matched to in the compared columns,
+// empty when it wasn't matched to a row
+func columnDiffersFlag(row models.FileSectionRow, pair models.ComparisonPair) string {
+	if row.MatchedColumnsFromRow == nil ||
+		pair.PrimaryFileColumnIndex >= len(row.ParsedColumnsFromRow) ||
+		pair.ComparisonFileColumnIndex >= len(row.MatchedColumnsFromRow) {
+		return ""
+	}
+	differs := row.ParsedColumnsFromRow[pair.PrimaryFileColumnIndex] != row.MatchedColumnsFromRow[pair.ComparisonFileColumnIndex]
+	return strconv.FormatBool(differs)
+}
@@ -0,0 +1,71 @@
+package reconstruction
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"os"
+	"path/filepath"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"reconciler.io/models/enums/results_format"
+)
+
+var _ = Describe("SideBySideResultSink", func() {
+	matchedRow := uint64(4)
+	taskDetails := models.ReconTaskDetails{
+		ComparisonPairs: []models.ComparisonPair{
+			{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 1, IsRowIdentifier: true},
+			{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 0},
+		},
+	}
+
+	readSideBySide := func(sections []models.FileSection) string {
+		outputPath := filepath.Join(testOutputDir(), "ReconResults.Csv")
+		task := taskDetails
+		task.ResultsFormats = []results_format.ResultsFormat{results_format.SideBySide}
+		_, err := reconstructSections(task, outputPath, sections)
+		Expect(err).NotTo(HaveOccurred())
+
+		contents, err := os.ReadFile(ResultsFilePathIn(outputPath, results_format.SideBySide))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Context("when the rows were matched to rows of the comparison file", func() {
+		It("should write each row next to the row it was matched to, flagging the columns that differ", func() {
+			sections := []models.FileSection{{
+				SectionSequenceNumber:   1,
+				IsLastSection:           true,
+				ColumnHeaders:           []string{"Id", "Amount"},
+				ComparisonColumnHeaders: []string{"Value", "Reference"},
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100"}, ReconResult: recon_status.Successfull, MatchedRowNumber: &matchedRow, MatchedColumnsFromRow: []string{"100", "7"}},
+					{RowNumber: 2, ParsedColumnsFromRow: []string{"8", "200"}, ReconResult: recon_status.Failed, MatchedRowNumber: &matchedRow, MatchedColumnsFromRow: []string{"250", "8"}},
+					{RowNumber: 3, ParsedColumnsFromRow: []string{"9", "300"}, ReconResult: recon_status.Failed, ReconResultReasons: []models.ReconReason{{Code: recon_reason_code.NoMatchFound}}},
+				},
+			}}
+
+			Expect(readSideBySide(sections)).To(Equal("Id,Amount,Comparison_Value,Comparison_Reference,ReconResult,Differs_Amount\n" +
+				"7,100,100,7,Successfull,false\n" +
+				"8,200,250,8,Failed,true\n" +
+				"9,300,,,Failed,\n"))
+		})
+	})
+
+	Context("when the sections don't carry the headers of the comparison file", func() {
+		It("should name the comparison columns by their position", func() {
+			sections := []models.FileSection{{
+				SectionSequenceNumber: 1,
+				IsLastSection:         true,
+				ColumnHeaders:         []string{"Id", "Amount"},
+				SectionRows: []models.FileSectionRow{
+					{RowNumber: 1, ParsedColumnsFromRow: []string{"7", "100"}, ReconResult: recon_status.Successfull, MatchedRowNumber: &matchedRow, MatchedColumnsFromRow: []string{"100", "7"}},
+				},
+			}}
+
+			Expect(readSideBySide(sections)).To(Equal("Id,Amount,Comparison_column_1,Comparison_column_2,ReconResult,Differs_Amount\n" +
+				"7,100,100,7,Successfull,false\n"))
+		})
+	})
+})
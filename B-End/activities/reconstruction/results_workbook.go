@@ -0,0 +1,202 @@
+package reconstruction
+
+import (
+	"fmt"
+	"os"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"time"
+)
+
+const (
+	summarySheetName               = "Summary"
+	matchedSheetName               = "Matched"
+	breaksSheetName                = "Breaks"
+	unmatchedInComparisonSheetName = "Unmatched in comparison"
+	comparisonFileRowColumnHeader  = "ComparisonFileRow"
+	reconResultReasonsColumnHeader = "ReconResultReasons"
+)
+
+// writeReconResultsWorkbook writes the results as a workbook for reviewers,
+// the summary first, then a sheet each for the rows that matched, the rows that matched
+// with columns that differ (with the differing cells highlighted),
+// and the rows no row of the comparison file was matched to.
+func writeReconResultsWorkbook(outputPath string, columnHeaders []string, rows reconResultRows, summary models.ReconSummary) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	workbook := newXlsxWriter(file)
+
+	err = writeSummarySheet(workbook, summary)
+	if err != nil {
+		return err
+	}
+
+	err = writeRowsSheet(workbook, matchedSheetName, append(append([]string{}, columnHeaders...), comparisonFileRowColumnHeader), rows,
+		func(row models.FileSectionRow) []xlsxCell {
+			if row.ReconResult != recon_status.Successfull {
+				return nil
+			}
+			return append(textCells(row.ParsedColumnsFromRow), comparisonFileRowCell(row))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = writeRowsSheet(workbook, breaksSheetName, append(append([]string{}, columnHeaders...), comparisonFileRowColumnHeader, reconResultReasonsColumnHeader), rows,
+		func(row models.FileSectionRow) []xlsxCell {
+			if row.ReconResult == recon_status.Successfull || row.MatchedRowNumber == nil {
+				return nil
+			}
+			cells := highlightMismatchingCells(textCells(row.ParsedColumnsFromRow), columnHeaders, row.ReconResultReasons)
+			return append(cells, comparisonFileRowCell(row), xlsxText(reasonsText(row)))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = writeRowsSheet(workbook, unmatchedInComparisonSheetName, append(append([]string{}, columnHeaders...), reconResultReasonsColumnHeader), rows,
+		func(row models.FileSectionRow) []xlsxCell {
+			if row.ReconResult == recon_status.Successfull || row.MatchedRowNumber != nil {
+				return nil
+			}
+			return append(textCells(row.ParsedColumnsFromRow), xlsxText(reasonsText(row)))
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workbook.close()
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// writeRowsSheet writes a row for every result rowCells gives cells for on the named sheet under the headers.
+// A sheet can only hold xlsxMaxRowsPerSheet rows, the rows that don't fit go on
+// sheets named after it, "Matched (2)", "Matched (3)"... each with the headers again
+func writeRowsSheet(
+	workbook *xlsxWriter,
+	name string,
+	headers []string,
+	rows reconResultRows,
+	rowCells func(row models.FileSectionRow) []xlsxCell,
+) error {
+	sheetNumber := 1
+	startSheet := func(sheetName string) error {
+		err := workbook.startSheet(sheetName)
+		if err != nil {
+			return err
+		}
+		return workbook.writeHeaderRow(headers)
+	}
+
+	err := startSheet(name)
+	if err != nil {
+		return err
+	}
+
+	return rows(func(row models.FileSectionRow) error {
+		cells := rowCells(row)
+		if cells == nil {
+			return nil
+		}
+
+		if workbook.rowCount >= xlsxMaxRowsPerSheet {
+			sheetNumber++
+			err := startSheet(fmt.Sprintf("%v (%v)", name, sheetNumber))
+			if err != nil {
+				return err
+			}
+		}
+		return workbook.writeRow(cells)
+	})
+}
+
+// writeSummarySheet writes each figure of the summary on a row of its own,
+// leaving out the ones that could not be counted
+func writeSummarySheet(workbook *xlsxWriter, summary models.ReconSummary) error {
+	err := workbook.startSheet(summarySheetName)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]xlsxCell{{xlsxText("PrimaryFileRows"), xlsxNumber(float64(summary.PrimaryFileRows))}}
+	if summary.ComparisonFileRows != nil {
+		rows = append(rows, []xlsxCell{xlsxText("ComparisonFileRows"), xlsxNumber(float64(*summary.ComparisonFileRows))})
+	}
+	rows = append(rows,
+		[]xlsxCell{xlsxText("MatchedRows"), xlsxNumber(float64(summary.MatchedRows))},
+		[]xlsxCell{xlsxText("MismatchedRows"), xlsxNumber(float64(summary.MismatchedRows))},
+		[]xlsxCell{xlsxText("MissingFromComparisonFile"), xlsxNumber(float64(summary.MissingFromComparisonFile))},
+	)
+	if summary.MissingFromPrimaryFile != nil {
+		rows = append(rows, []xlsxCell{xlsxText("MissingFromPrimaryFile"), xlsxNumber(float64(*summary.MissingFromPrimaryFile))})
+	}
+	rows = append(rows, []xlsxCell{xlsxText("DuplicateRows"), xlsxNumber(float64(summary.DuplicateRows))})
+
+	for _, amountTotal := range summary.AmountTotals {
+		rows = append(rows, []xlsxCell{xlsxText(fmt.Sprintf("Total of %v", amountTotal.Column)), xlsxNumber(amountTotal.Total)})
+		for _, status := range []recon_status.ReconciliationStatus{recon_status.Successfull, recon_status.Failed, recon_status.Pending} {
+			if total, exists := amountTotal.TotalsByStatus[status]; exists {
+				rows = append(rows, []xlsxCell{xlsxText(fmt.Sprintf("Total of %v (%v)", amountTotal.Column, status)), xlsxNumber(total)})
+			}
+		}
+	}
+
+	for _, column := range summary.TopMismatchingColumns {
+		rows = append(rows, []xlsxCell{xlsxText(fmt.Sprintf("Rows mismatching on %v", column.Column)), xlsxNumber(float64(column.Rows))})
+	}
+	rows = append(rows, []xlsxCell{xlsxText("GeneratedAt"), xlsxText(summary.GeneratedAt.Format(time.RFC3339))})
+
+	err = workbook.writeHeaderRow([]string{"Measure", "Value"})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err = workbook.writeRow(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func textCells(values []string) []xlsxCell {
+	cells := make([]xlsxCell, len(values))
+	for i, value := range values {
+		cells[i] = xlsxText(value)
+	}
+	return cells
+}
+
+func comparisonFileRowCell(row models.FileSectionRow) xlsxCell {
+	if row.MatchedRowNumber == nil {
+		return xlsxText("")
+	}
+	return xlsxNumber(float64(*row.MatchedRowNumber))
+}
+
+// highlightMismatchingCells highlights the cells of the columns
+// whose value differs from the row of the comparison file it was matched to
+func highlightMismatchingCells(cells []xlsxCell, columnHeaders []string, reasons []models.ReconReason) []xlsxCell {
+	for _, reason := range reasons {
+		if reason.Code != recon_reason_code.RowMismatchFound {
+			continue
+		}
+		for i, header := range columnHeaders {
+			if header == reason.PrimaryColumn && i < len(cells) {
+				cells[i].Style = xlsxHighlightedCell
+			}
+		}
+	}
+	return cells
+}
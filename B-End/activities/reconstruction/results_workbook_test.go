@@ -0,0 +1,221 @@
+package reconstruction
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io"
+	"path/filepath"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"reconciler.io/models/enums/results_format"
+	"time"
+)
+
+// workbookCell is a cell read back from a written workbook
+type workbookCell struct {
+	Reference  string `xml:"r,attr"`
+	Style      int    `xml:"s,attr"`
+	Type       string `xml:"t,attr"`
+	Number     string `xml:"v"`
+	InlineText string `xml:"is>t"`
+}
+
+func (c workbookCell) value() string {
+	if c.Type == "inlineStr" {
+		return c.InlineText
+	}
+	return c.Number
+}
+
+// readWorkbook returns the cells of each sheet of the workbook by sheet name and cell reference
+func readWorkbook(path string) (map[string]map[string]workbookCell, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	parts := make(map[string][]byte)
+	for _, file := range archive.File {
+		part, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		parts[file.Name], err = io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	err = xml.Unmarshal(parts["xl/workbook.xml"], &workbook)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := make(map[string]map[string]workbookCell)
+	for i, sheet := range workbook.Sheets {
+		var worksheet struct {
+			Cells []workbookCell `xml:"sheetData>row>c"`
+		}
+		err = xml.Unmarshal(parts[fmt.Sprintf("xl/worksheets/sheet%v.xml", i+1)], &worksheet)
+		if err != nil {
+			return nil, err
+		}
+
+		sheets[sheet.Name] = make(map[string]workbookCell)
+		for _, cell := range worksheet.Cells {
+			sheets[sheet.Name][cell.Reference] = cell
+		}
+	}
+	return sheets, nil
+}
+
+var _ = Describe("WriteReconResultsWorkbook", func() {
+	matchedTo := func(rowNumber uint64) *uint64 {
+		return &rowNumber
+	}
+
+	comparedRows := uint64(3)
+	comparisonFileRows := int64(3)
+	missingRows := int64(1)
+	summary := models.ReconSummary{
+		PrimaryFileRows:           3,
+		ComparisonFileRows:        &comparisonFileRows,
+		MatchedRows:               1,
+		MismatchedRows:            1,
+		MissingFromComparisonFile: 1,
+		MissingFromPrimaryFile:    &missingRows,
+		GeneratedAt:               time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	sections := []models.FileSection{{
+		SectionSequenceNumber: 1,
+		IsLastSection:         true,
+		ColumnHeaders:         []string{"Id", "Amount", "Currency"},
+		SectionRows: []models.FileSectionRow{
+			{RowNumber: 1, ParsedColumnsFromRow: []string{"1", "10", "USD"}, ReconResult: recon_status.Successfull, MatchedRowNumber: matchedTo(1)},
+			{
+				RowNumber:            2,
+				ParsedColumnsFromRow: []string{"2", "20", "<USD & co>"},
+				ReconResult:          recon_status.Failed,
+				MatchedRowNumber:     &comparedRows,
+				ReconResultReasons: []models.ReconReason{
+					{Code: recon_reason_code.RowMismatchFound, PrimaryColumn: "Currency", ComparisonColumn: "Currency", PrimaryValue: "<USD & co>", ComparisonValue: "EUR", CounterpartRowNumber: &comparedRows},
+				},
+			},
+			{RowNumber: 3, ParsedColumnsFromRow: []string{"4", "40", "EUR"}, ReconResult: recon_status.Failed, ReconResultReasons: []models.ReconReason{{Code: recon_reason_code.NoMatchFound}}},
+		},
+	}}
+
+	Context("when the results are written as a workbook", func() {
+		It("should put the summary, matched rows, breaks and unmatched rows on their own sheets", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.xlsx")
+
+			Expect(writeReconResultsWorkbook(outputPath, sections[0].ColumnHeaders, sectionRows(sections), summary)).To(Succeed())
+
+			sheets, err := readWorkbook(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sheets).To(HaveLen(4))
+
+			Expect(sheets["Summary"]["A2"].value()).To(Equal("PrimaryFileRows"))
+			Expect(sheets["Summary"]["B2"].value()).To(Equal("3"))
+			Expect(sheets["Summary"]["B2"].Type).To(BeEmpty())
+
+			Expect(sheets["Matched"]["A1"].Style).To(Equal(int(xlsxHeaderCell)))
+			Expect(sheets["Matched"]["A2"].value()).To(Equal("1"))
+			Expect(sheets["Matched"]["D2"].value()).To(Equal("1"))
+			Expect(sheets["Matched"]).NotTo(HaveKey("A3"))
+
+			Expect(sheets["Breaks"]["C2"].value()).To(Equal("<USD & co>"))
+			Expect(sheets["Breaks"]["D2"].value()).To(Equal("3"))
+			Expect(sheets["Breaks"]["E2"].value()).To(ContainSubstring("ComparisonFileColumn: [Currency]"))
+			Expect(sheets["Unmatched in comparison"]["A2"].value()).To(Equal("4"))
+			Expect(sheets["Unmatched in comparison"]["D2"].value()).To(Equal("no matching record found in the entire comparison file"))
+		})
+
+		It("should only highlight the cells that differ from the matched row", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.xlsx")
+
+			Expect(writeReconResultsWorkbook(outputPath, sections[0].ColumnHeaders, sectionRows(sections), summary)).To(Succeed())
+
+			sheets, err := readWorkbook(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sheets["Breaks"]["C2"].Style).To(Equal(int(xlsxHighlightedCell)))
+			Expect(sheets["Breaks"]["A2"].Style).To(Equal(int(xlsxPlainCell)))
+			Expect(sheets["Breaks"]["B2"].Style).To(Equal(int(xlsxPlainCell)))
+		})
+	})
+
+	Context("when there are more rows than fit on a sheet", func() {
+		It("should carry on with the rows on sheets named after it", func() {
+			maxRowsPerSheet := xlsxMaxRowsPerSheet
+			xlsxMaxRowsPerSheet = 3
+			defer func() { xlsxMaxRowsPerSheet = maxRowsPerSheet }()
+
+			matchedRows := make([]models.FileSectionRow, 0)
+			for rowNumber := uint64(1); rowNumber <= 5; rowNumber++ {
+				matchedRows = append(matchedRows, models.FileSectionRow{
+					RowNumber:            rowNumber,
+					ParsedColumnsFromRow: []string{fmt.Sprint(rowNumber), "10", "USD"},
+					ReconResult:          recon_status.Successfull,
+					MatchedRowNumber:     matchedTo(rowNumber),
+				})
+			}
+			manySections := []models.FileSection{{
+				SectionSequenceNumber: 1,
+				IsLastSection:         true,
+				ColumnHeaders:         sections[0].ColumnHeaders,
+				SectionRows:           matchedRows,
+			}}
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.xlsx")
+
+			Expect(writeReconResultsWorkbook(outputPath, sections[0].ColumnHeaders, sectionRows(manySections), summary)).To(Succeed())
+
+			sheets, err := readWorkbook(outputPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sheets).To(HaveKey("Matched (2)"))
+			Expect(sheets).To(HaveKey("Matched (3)"))
+			Expect(sheets).NotTo(HaveKey("Matched (4)"))
+
+			Expect(sheets["Matched"]["A3"].value()).To(Equal("2"))
+			Expect(sheets["Matched"]).NotTo(HaveKey("A4"))
+			Expect(sheets["Matched (2)"]["A1"].value()).To(Equal("Id"))
+			Expect(sheets["Matched (2)"]["A2"].value()).To(Equal("3"))
+			Expect(sheets["Matched (3)"]["A2"].value()).To(Equal("5"))
+			Expect(sheets["Matched (3)"]).NotTo(HaveKey("A3"))
+		})
+	})
+
+	Context("when the task writes its results in more than one format", func() {
+		It("should write each of them next to the results file", func() {
+			outputPath := filepath.Join(testOutputDir(), "ReconResults.Csv")
+
+			task := models.ReconTaskDetails{ResultsFormats: []results_format.ResultsFormat{results_format.Csv, results_format.Xlsx}}
+			_, err := reconstructSections(task, outputPath, sections)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(outputPath).To(BeAnExistingFile())
+			Expect(filepath.Join(filepath.Dir(outputPath), "ReconResults.xlsx")).To(BeAnExistingFile())
+			Expect(filepath.Join(filepath.Dir(outputPath), "ReconResults.xlsx.tmp")).NotTo(BeAnExistingFile())
+		})
+	})
+})
+
+var _ = Describe("XlsxColumnName", func() {
+	It("should name columns the way spreadsheets do", func() {
+		Expect(xlsxColumnName(0)).To(Equal("A"))
+		Expect(xlsxColumnName(25)).To(Equal("Z"))
+		Expect(xlsxColumnName(26)).To(Equal("AA"))
+		Expect(xlsxColumnName(701)).To(Equal("ZZ"))
+		Expect(xlsxColumnName(702)).To(Equal("AAA"))
+	})
+})
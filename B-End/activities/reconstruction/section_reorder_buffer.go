@@ -0,0 +1,223 @@
+package reconstruction
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reconciler.io/models"
+)
+
+// sectionReorderBuffer puts the reconciled sections of a file back in order as they arrive.
+// The sections of a partition are taken in sequence order as soon as they are contiguous
+// and the rows of every partition are merged back into the order they had in the original file.
+// Only the sections that arrived before their turn are held, the first maxHeldSections
+// of them in memory and the rest in files of the spill directory.
+type sectionReorderBuffer struct {
+	spillDirectory          string
+	spillPath               string
+	maxHeldSections         int
+	partitionCount          int
+	partitions              map[int]*reorderPartition
+	heldSections            map[reorderSectionKey]models.FileSection
+	spilledSections         map[reorderSectionKey]string
+	columnHeaders           []string
+	comparisonColumnHeaders []string
+}
+
+type reorderSectionKey struct {
+	partitionNumber       int
+	sectionSequenceNumber int
+}
+
+// reorderPartition is how far the rows of a partition have been merged,
+// section is the one its rows are being taken from
+type reorderPartition struct {
+	nextSectionSequenceNumber int
+	section                   *models.FileSection
+	nextRow                   int
+	isComplete                bool
+}
+
+func newSectionReorderBuffer(spillDirectory string, maxHeldSections int) *sectionReorderBuffer {
+	return &sectionReorderBuffer{
+		spillDirectory:  spillDirectory,
+		maxHeldSections: maxHeldSections,
+		partitions:      make(map[int]*reorderPartition),
+		heldSections:    make(map[reorderSectionKey]models.FileSection),
+		spilledSections: make(map[reorderSectionKey]string),
+	}
+}
+
+// add holds on to the section until its turn comes,
+// a section that was already added is ignored
+func (b *sectionReorderBuffer) add(section models.FileSection) error {
+	if section.PartitionCount > b.partitionCount {
+		b.partitionCount = section.PartitionCount
+	}
+	if len(b.columnHeaders) == 0 && len(section.ColumnHeaders) > 0 {
+		b.columnHeaders = section.ColumnHeaders
+	}
+	if len(b.comparisonColumnHeaders) == 0 && len(section.ComparisonColumnHeaders) > 0 {
+		b.comparisonColumnHeaders = section.ComparisonColumnHeaders
+	}
+
+	partition, exists := b.partitions[section.PartitionNumber]
+	if !exists {
+		partition = &reorderPartition{nextSectionSequenceNumber: 1}
+		b.partitions[section.PartitionNumber] = partition
+	}
+
+	key := reorderSectionKey{partitionNumber: section.PartitionNumber, sectionSequenceNumber: section.SectionSequenceNumber}
+	if section.SectionSequenceNumber < partition.nextSectionSequenceNumber || b.isHolding(key) {
+		return nil
+	}
+
+	if len(b.heldSections) < b.maxHeldSections {
+		b.heldSections[key] = section
+		return nil
+	}
+	return b.spill(key, section)
+}
+
+// release hands every row that is next in row order to each, for as long as
+// the next section of every partition has arrived
+func (b *sectionReorderBuffer) release(each func(row models.FileSectionRow) error) error {
+	// no row can be known to be next until every partition has started
+	if len(b.partitions) < b.expectedPartitions() {
+		return nil
+	}
+
+	for {
+		var lowest *reorderPartition
+		for partitionNumber, partition := range b.partitions {
+			for !partition.isComplete && (partition.section == nil || partition.nextRow >= len(partition.section.SectionRows)) {
+				if partition.section != nil && partition.section.IsLastSection {
+					partition.isComplete = true
+					partition.section = nil
+					break
+				}
+
+				section, found, err := b.take(reorderSectionKey{partitionNumber: partitionNumber, sectionSequenceNumber: partition.nextSectionSequenceNumber})
+				if err != nil {
+					return err
+				}
+
+				// the partition has to wait for its next section
+				if !found {
+					return nil
+				}
+				partition.section = &section
+				partition.nextRow = 0
+				partition.nextSectionSequenceNumber++
+			}
+
+			if partition.isComplete {
+				continue
+			}
+			if lowest == nil || partition.section.SectionRows[partition.nextRow].RowNumber < lowest.section.SectionRows[lowest.nextRow].RowNumber {
+				lowest = partition
+			}
+		}
+
+		// every partition is complete
+		if lowest == nil {
+			return nil
+		}
+
+		err := each(lowest.section.SectionRows[lowest.nextRow])
+		if err != nil {
+			return err
+		}
+		lowest.nextRow++
+	}
+}
+
+// isComplete reports whether every row of the file has been released
+func (b *sectionReorderBuffer) isComplete() bool {
+	if len(b.partitions) < b.expectedPartitions() {
+		return false
+	}
+	for _, partition := range b.partitions {
+		if !partition.isComplete {
+			return false
+		}
+	}
+	return true
+}
+
+// close removes the sections that were spilled
+func (b *sectionReorderBuffer) close() error {
+	if len(b.spillPath) == 0 {
+		return nil
+	}
+	return os.RemoveAll(b.spillPath)
+}
+
+// expectedPartitions is how many partitions the file was split into, a file that was not partitioned is a single one
+func (b *sectionReorderBuffer) expectedPartitions() int {
+	if b.partitionCount > 1 {
+		return b.partitionCount
+	}
+	return 1
+}
+
+func (b *sectionReorderBuffer) isHolding(key reorderSectionKey) bool {
+	if _, exists := b.heldSections[key]; exists {
+		return true
+	}
+	_, exists := b.spilledSections[key]
+	return exists
+}
+
+// spill writes the section to a file of its own until its turn comes
+func (b *sectionReorderBuffer) spill(key reorderSectionKey, section models.FileSection) error {
+	if len(b.spillPath) == 0 {
+		spillPath, err := os.MkdirTemp(b.spillDirectory, "reconstruction-")
+		if err != nil {
+			return fmt.Errorf("error on creating spill directory: [%v]", err)
+		}
+		b.spillPath = spillPath
+	}
+
+	path := filepath.Join(b.spillPath, fmt.Sprintf("%v-%v.json", key.partitionNumber, key.sectionSequenceNumber))
+	encoded, err := json.Marshal(section)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, encoded, 0600)
+	if err != nil {
+		return fmt.Errorf("error on spilling section: [%v], Error: %v", key.sectionSequenceNumber, err)
+	}
+	b.spilledSections[key] = path
+	return nil
+}
+
+// take gives back the section and stops holding it
+func (b *sectionReorderBuffer) take(key reorderSectionKey) (models.FileSection, bool, error) {
+	if section, exists := b.heldSections[key]; exists {
+		delete(b.heldSections, key)
+		return section, true, nil
+	}
+
+	path, exists := b.spilledSections[key]
+	if !exists {
+		return models.FileSection{}, false, nil
+	}
+
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return models.FileSection{}, false, fmt.Errorf("error on reading spilled section: [%v], Error: %v", key.sectionSequenceNumber, err)
+	}
+
+	var section models.FileSection
+	err = json.Unmarshal(encoded, &section)
+	if err != nil {
+		return models.FileSection{}, false, err
+	}
+
+	delete(b.spilledSections, key)
+	_ = os.Remove(path)
+	return section, true, nil
+}
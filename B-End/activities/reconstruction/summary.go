@@ -0,0 +1,157 @@
+package reconstruction
+
+import (
+	"math"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reconSummariser works out the summary of a task a row at a time, in row order.
+// Any column that is not a row identifier and only ever holds numbers is taken to be an amount column.
+type reconSummariser struct {
+	summary               models.ReconSummary
+	columnHeaders         []string
+	identifierColumns     map[int]bool
+	amountColumns         map[int]*models.AmountTotal
+	notAmountColumns      map[int]bool
+	matchedComparisonRows map[uint64]bool
+	mismatchesByColumn    map[string]int64
+}
+
+func newReconSummariser(taskDetails models.ReconTaskDetails, columnHeaders []string) *reconSummariser {
+	identifierColumns := make(map[int]bool)
+	for _, pair := range taskDetails.ComparisonPairs {
+		if pair.IsRowIdentifier {
+			identifierColumns[pair.PrimaryFileColumnIndex] = true
+		}
+	}
+
+	return &reconSummariser{
+		columnHeaders:         columnHeaders,
+		identifierColumns:     identifierColumns,
+		amountColumns:         make(map[int]*models.AmountTotal),
+		notAmountColumns:      make(map[int]bool),
+		matchedComparisonRows: make(map[uint64]bool),
+		mismatchesByColumn:    make(map[string]int64),
+	}
+}
+
+func (s *reconSummariser) addRow(row models.FileSectionRow) {
+	s.summary.PrimaryFileRows++
+
+	switch {
+	case row.ReconResult == recon_status.Successfull:
+		s.summary.MatchedRows++
+	case row.MatchedRowNumber != nil:
+		s.summary.MismatchedRows++
+	default:
+		s.summary.MissingFromComparisonFile++
+	}
+
+	if row.MatchedRowNumber != nil {
+		if s.matchedComparisonRows[*row.MatchedRowNumber] {
+			s.summary.DuplicateRows++
+		}
+		s.matchedComparisonRows[*row.MatchedRowNumber] = true
+	}
+
+	for _, reason := range row.ReconResultReasons {
+		if reason.Code == recon_reason_code.RowMismatchFound {
+			s.mismatchesByColumn[reason.PrimaryColumn]++
+		}
+	}
+
+	for i, value := range row.ParsedColumnsFromRow {
+		s.addAmount(i, value, row.ReconResult)
+	}
+}
+
+// addAmount adds the value to the totals of its column,
+// the column stops being an amount column as soon as it has a value that isn't a number
+func (s *reconSummariser) addAmount(columnIndex int, value string, status recon_status.ReconciliationStatus) {
+	if s.identifierColumns[columnIndex] || s.notAmountColumns[columnIndex] {
+		return
+	}
+
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	if len(value) == 0 {
+		return
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		s.notAmountColumns[columnIndex] = true
+		delete(s.amountColumns, columnIndex)
+		return
+	}
+
+	total, exists := s.amountColumns[columnIndex]
+	if !exists {
+		total = &models.AmountTotal{
+			Column:         s.columnName(columnIndex),
+			TotalsByStatus: make(map[recon_status.ReconciliationStatus]float64),
+		}
+		s.amountColumns[columnIndex] = total
+	}
+	total.Total += amount
+	total.TotalsByStatus[status] += amount
+}
+
+func (s *reconSummariser) columnName(columnIndex int) string {
+	if columnIndex < len(s.columnHeaders) {
+		return s.columnHeaders[columnIndex]
+	}
+	return "column_" + strconv.Itoa(columnIndex+1)
+}
+
+// finish returns the summary, counting the rows of the comparison file
+// nothing was matched to when the progress of the task knows how many rows it has
+func (s *reconSummariser) finish(progress *models.TaskProgress, now time.Time) models.ReconSummary {
+	summary := s.summary
+	summary.GeneratedAt = now
+
+	if progress != nil {
+		for _, fileProgress := range progress.Files {
+			if fileProgress.FilePurpose != file_purpose.ComparisonFile || !fileProgress.IsFullyRead {
+				continue
+			}
+			comparisonFileRows := fileProgress.RowsRead
+			missingFromPrimaryFile := comparisonFileRows - int64(len(s.matchedComparisonRows))
+			summary.ComparisonFileRows = &comparisonFileRows
+			summary.MissingFromPrimaryFile = &missingFromPrimaryFile
+		}
+	}
+
+	columnIndexes := make([]int, 0, len(s.amountColumns))
+	for columnIndex := range s.amountColumns {
+		columnIndexes = append(columnIndexes, columnIndex)
+	}
+	sort.Ints(columnIndexes)
+	summary.AmountTotals = make([]models.AmountTotal, 0, len(columnIndexes))
+	for _, columnIndex := range columnIndexes {
+		summary.AmountTotals = append(summary.AmountTotals, *s.amountColumns[columnIndex])
+	}
+
+	summary.TopMismatchingColumns = make([]models.ColumnMismatchCount, 0, len(s.mismatchesByColumn))
+	for column, rows := range s.mismatchesByColumn {
+		summary.TopMismatchingColumns = append(summary.TopMismatchingColumns, models.ColumnMismatchCount{Column: column, Rows: rows})
+	}
+	sort.Slice(summary.TopMismatchingColumns, func(i, j int) bool {
+		if summary.TopMismatchingColumns[i].Rows != summary.TopMismatchingColumns[j].Rows {
+			return summary.TopMismatchingColumns[i].Rows > summary.TopMismatchingColumns[j].Rows
+		}
+		return summary.TopMismatchingColumns[i].Column < summary.TopMismatchingColumns[j].Column
+	})
+	if len(summary.TopMismatchingColumns) > constants.RECON_SUMMARY_TOP_MISMATCHING_COLUMNS {
+		summary.TopMismatchingColumns = summary.TopMismatchingColumns[:constants.RECON_SUMMARY_TOP_MISMATCHING_COLUMNS]
+	}
+
+	return summary
+}
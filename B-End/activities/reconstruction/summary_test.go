@@ -0,0 +1,93 @@
+package reconstruction
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"time"
+)
+
+// summariseReconResults works out the summary of the sections a row at a time, the way the reconstruction does
+func summariseReconResults(taskDetails models.ReconTaskDetails, fileSections []models.FileSection) models.ReconSummary {
+	now := time.Now()
+	summariser := newReconSummariser(taskDetails, fileSections[0].ColumnHeaders)
+	Expect(sectionRows(fileSections)(func(row models.FileSectionRow) error {
+		summariser.addRow(row)
+		return nil
+	})).To(Succeed())
+	return summariser.finish(taskDetails.ProgressTracker.Snapshot(now), now)
+}
+
+var _ = Describe("SummariseReconResults", func() {
+	matchedTo := func(rowNumber uint64) *uint64 {
+		return &rowNumber
+	}
+
+	taskDetails := models.ReconTaskDetails{
+		ID: "task_1",
+		ComparisonPairs: []models.ComparisonPair{
+			{PrimaryFileColumnIndex: 0, ComparisonFileColumnIndex: 0, IsRowIdentifier: true},
+			{PrimaryFileColumnIndex: 1, ComparisonFileColumnIndex: 1},
+			{PrimaryFileColumnIndex: 2, ComparisonFileColumnIndex: 2},
+		},
+	}
+
+	amountDiff := models.ReconReason{Code: recon_reason_code.RowMismatchFound, PrimaryColumn: "Amount", ComparisonColumn: "Amount", PrimaryValue: "20", ComparisonValue: "25"}
+	currencyDiff := models.ReconReason{Code: recon_reason_code.RowMismatchFound, PrimaryColumn: "Currency", ComparisonColumn: "Currency", PrimaryValue: "USD", ComparisonValue: "EUR"}
+	sections := []models.FileSection{{
+		SectionSequenceNumber: 1,
+		IsLastSection:         true,
+		ColumnHeaders:         []string{"Id", "Amount", "Currency"},
+		SectionRows: []models.FileSectionRow{
+			{RowNumber: 0, ParsedColumnsFromRow: []string{"1", "1,000.50", "USD"}, ReconResult: recon_status.Successfull, MatchedRowNumber: matchedTo(0)},
+			{RowNumber: 1, ParsedColumnsFromRow: []string{"2", "20", "USD"}, ReconResult: recon_status.Failed, MatchedRowNumber: matchedTo(1), ReconResultReasons: []models.ReconReason{amountDiff, currencyDiff}},
+			{RowNumber: 2, ParsedColumnsFromRow: []string{"2", "30", "EUR"}, ReconResult: recon_status.Failed, MatchedRowNumber: matchedTo(1), ReconResultReasons: []models.ReconReason{amountDiff}},
+			{RowNumber: 3, ParsedColumnsFromRow: []string{"4", "", "EUR"}, ReconResult: recon_status.Failed, ReconResultReasons: []models.ReconReason{{Code: recon_reason_code.NoMatchFound}}},
+		},
+	}}
+
+	Context("when the comparison file was read by this service", func() {
+		It("should count the rows of both files and total the amount columns", func() {
+			task := taskDetails
+			task.ProgressTracker = models.NewTaskProgressTracker()
+			task.ProgressTracker.RecordSectionRead("ComparisonFile-1", file_purpose.ComparisonFile, 5, 100, 100, true)
+
+			summary := summariseReconResults(task, sections)
+
+			Expect(summary.PrimaryFileRows).To(Equal(int64(4)))
+			Expect(summary.MatchedRows).To(Equal(int64(1)))
+			Expect(summary.MismatchedRows).To(Equal(int64(2)))
+			Expect(summary.MissingFromComparisonFile).To(Equal(int64(1)))
+			Expect(summary.DuplicateRows).To(Equal(int64(1)))
+			Expect(*summary.ComparisonFileRows).To(Equal(int64(5)))
+			Expect(*summary.MissingFromPrimaryFile).To(Equal(int64(3)))
+
+			// the identifier column holds numbers too but is never an amount
+			Expect(summary.AmountTotals).To(Equal([]models.AmountTotal{{
+				Column: "Amount",
+				Total:  1050.5,
+				TotalsByStatus: map[recon_status.ReconciliationStatus]float64{
+					recon_status.Successfull: 1000.5,
+					recon_status.Failed:      50,
+				},
+			}}))
+			Expect(summary.TopMismatchingColumns).To(Equal([]models.ColumnMismatchCount{
+				{Column: "Amount", Rows: 2},
+				{Column: "Currency", Rows: 1},
+			}))
+		})
+	})
+
+	Context("when this service never read the comparison file", func() {
+		It("should leave out what it can't count", func() {
+			summary := summariseReconResults(taskDetails, sections)
+
+			Expect(summary.ComparisonFileRows).To(BeNil())
+			Expect(summary.MissingFromPrimaryFile).To(BeNil())
+			Expect(summary.PrimaryFileRows).To(Equal(int64(4)))
+		})
+	})
+})
@@ -0,0 +1,222 @@
+package reconstruction
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxMaxRowsPerSheet is the most rows a sheet can have, header row included
+var xlsxMaxRowsPerSheet = 1048576
+
+// xlsxCellStyle is the index of a cell format in the styles of the workbook
+type xlsxCellStyle int
+
+const (
+	xlsxPlainCell xlsxCellStyle = iota
+	xlsxHeaderCell
+	xlsxHighlightedCell
+)
+
+// xlsxStyles are a bold font for the header row and a light red fill for highlighted cells,
+// in the order of the xlsxCellStyle values
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FFFFC7CE"/><bgColor indexed="64"/></patternFill></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="0" fontId="0" fillId="2" borderId="0" xfId="0" applyFill="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+// xlsxCell is a cell of a row, numbers are written as numbers and everything else as text
+type xlsxCell struct {
+	Value    string
+	IsNumber bool
+	Style    xlsxCellStyle
+}
+
+func xlsxText(value string) xlsxCell {
+	return xlsxCell{Value: value}
+}
+
+func xlsxNumber(value float64) xlsxCell {
+	return xlsxCell{Value: strconv.FormatFloat(value, 'f', -1, 64), IsNumber: true}
+}
+
+// xlsxWriter writes a workbook a row at a time, one sheet after the other,
+// so only the row being written is ever held in memory.
+// Text is written inline into each cell rather than into a shared strings table,
+// which would have to hold every distinct value of the workbook.
+type xlsxWriter struct {
+	archive    *zip.Writer
+	sheet      *bufio.Writer
+	sheetNames []string
+	rowCount   int
+}
+
+func newXlsxWriter(w io.Writer) *xlsxWriter {
+	return &xlsxWriter{archive: zip.NewWriter(w)}
+}
+
+// startSheet finishes the sheet being written and starts the next one,
+// with its first row frozen so the headers stay in view
+func (w *xlsxWriter) startSheet(name string) error {
+	err := w.finishSheet()
+	if err != nil {
+		return err
+	}
+
+	w.sheetNames = append(w.sheetNames, name)
+	part, err := w.archive.Create(fmt.Sprintf("xl/worksheets/sheet%v.xml", len(w.sheetNames)))
+	if err != nil {
+		return err
+	}
+
+	w.sheet = bufio.NewWriter(part)
+	w.rowCount = 0
+	_, err = w.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<sheetData>`)
+	return err
+}
+
+func (w *xlsxWriter) writeRow(cells []xlsxCell) error {
+	if w.sheet == nil {
+		return fmt.Errorf("a sheet has to be started before its rows are written")
+	}
+
+	w.rowCount++
+	fmt.Fprintf(w.sheet, `<row r="%v">`, w.rowCount)
+	for i, cell := range cells {
+		reference := xlsxColumnName(i) + strconv.Itoa(w.rowCount)
+		if cell.IsNumber {
+			fmt.Fprintf(w.sheet, `<c r="%v" s="%v"><v>%v</v></c>`, reference, cell.Style, cell.Value)
+			continue
+		}
+
+		fmt.Fprintf(w.sheet, `<c r="%v" s="%v" t="inlineStr"><is><t xml:space="preserve">`, reference, cell.Style)
+		err := xml.EscapeText(w.sheet, []byte(cell.Value))
+		if err != nil {
+			return err
+		}
+		w.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+// writeHeaderRow writes the values as a row of bold text
+func (w *xlsxWriter) writeHeaderRow(headers []string) error {
+	cells := make([]xlsxCell, len(headers))
+	for i, header := range headers {
+		cells[i] = xlsxCell{Value: header, Style: xlsxHeaderCell}
+	}
+	return w.writeRow(cells)
+}
+
+func (w *xlsxWriter) finishSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+
+	_, err := w.sheet.WriteString(`</sheetData></worksheet>`)
+	if err != nil {
+		return err
+	}
+	err = w.sheet.Flush()
+	w.sheet = nil
+	return err
+}
+
+// close finishes the last sheet and writes the parts of the workbook that list its sheets
+func (w *xlsxWriter) close() error {
+	err := w.finishSheet()
+	if err != nil {
+		return err
+	}
+
+	var contentTypes, workbook, workbookRelationships string
+	for i, name := range w.sheetNames {
+		sheetNumber := i + 1
+		contentTypes += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%v.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, sheetNumber)
+		workbook += fmt.Sprintf(`<sheet name="%v" sheetId="%v" r:id="rId%v"/>`, xlsxAttribute(name), sheetNumber, sheetNumber)
+		workbookRelationships += fmt.Sprintf(`<Relationship Id="rId%v" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%v.xml"/>`, sheetNumber, sheetNumber)
+	}
+	stylesRelationshipID := len(w.sheetNames) + 1
+
+	parts := []struct {
+		name     string
+		contents string
+	}{
+		{
+			name: "[Content_Types].xml",
+			contents: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+				`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+				`<Default Extension="xml" ContentType="application/xml"/>` +
+				`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+				`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+				contentTypes +
+				`</Types>`,
+		},
+		{
+			name: "_rels/.rels",
+			contents: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+				`</Relationships>`,
+		},
+		{
+			name: "xl/workbook.xml",
+			contents: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+				`<sheets>` + workbook + `</sheets>` +
+				`</workbook>`,
+		},
+		{
+			name: "xl/_rels/workbook.xml.rels",
+			contents: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				workbookRelationships +
+				fmt.Sprintf(`<Relationship Id="rId%v" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesRelationshipID) +
+				`</Relationships>`,
+		},
+		{name: "xl/styles.xml", contents: xlsxStyles},
+	}
+
+	for _, part := range parts {
+		partWriter, err := w.archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(partWriter, part.contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.archive.Close()
+}
+
+// xlsxColumnName turns the index of a column into its letters, A to Z then AA onwards
+func xlsxColumnName(columnIndex int) string {
+	name := ""
+	for columnIndex >= 0 {
+		name = string(rune('A'+columnIndex%26)) + name
+		columnIndex = columnIndex/26 - 1
+	}
+	return name
+}
+
+func xlsxAttribute(value string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
@@ -1,6 +1,10 @@
 package constants
 
-import "time"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 var MAX_CHANNEL_BUFFER_SIZE = 100000000
 var FILE_SECTION_BATCH_SIZE = 100
@@ -11,3 +15,144 @@ var PRIMARY_FILE_SECTIONS_STREAM_NAME = "primary-file-sections-stream"
 var COMPARISON_FILE_SECTIONS_STREAM_NAME = "comparison-file-sections-stream"
 var FILE_RECONSTRUCTION_STREAM_NAME = "file-sections-to-be-reconstructed-stream"
 var DEFAULT_NATS_TIMEOUT_IN_MINUTES = time.Duration(2 * time.Minute)
+
+// MAX_CONCURRENT_SECTION_RECONCILIATIONS is how many sections are reconciled at the same time across all tasks,
+// a task reconciles up to MAX_CONCURRENT_SECTION_RECONCILIATIONS_PER_TASK of them unless it sets its own limit
+var MAX_CONCURRENT_SECTION_RECONCILIATIONS = envIntOrDefault("RECONCILER_MAX_CONCURRENT_SECTION_RECONCILIATIONS", 64)
+var MAX_CONCURRENT_SECTION_RECONCILIATIONS_PER_TASK = envIntOrDefault("RECONCILER_MAX_CONCURRENT_SECTION_RECONCILIATIONS_PER_TASK", 8)
+
+// STREAM_CODEC and STREAM_COMPRESSION pick how FileSections are encoded on the NATS streams this service creates,
+// a stream records its encoding and keeps it. codecs: json, msgpack. compression: none, zstd, s2.
+// msgpack with s2 cuts the size of the sections, once every reader of the streams can decode it
+var STREAM_CODEC = envOrDefault("RECONCILER_STREAM_CODEC", "json")
+var STREAM_COMPRESSION = envOrDefault("RECONCILER_STREAM_COMPRESSION", "none")
+
+// RESULT_SINK_STREAM_NAME is the stream the subjects of the stream result sinks of tasks are kept in,
+// the results are published on them encoded with RESULT_SINK_STREAM_CODEC and RESULT_SINK_STREAM_COMPRESSION
+// so that consumers outside the service can read them
+var RESULT_SINK_STREAM_NAME = envOrDefault("RECONCILER_RESULT_SINK_STREAM_NAME", "recon-results-stream")
+var RESULT_SINK_STREAM_CODEC = envOrDefault("RECONCILER_RESULT_SINK_STREAM_CODEC", "json")
+var RESULT_SINK_STREAM_COMPRESSION = envOrDefault("RECONCILER_RESULT_SINK_STREAM_COMPRESSION", "none")
+
+// DATABASE_DRIVER picks where tasks and their files are kept.
+// drivers: memory, sqlite, postgres. DATABASE_URL is the file of the sqlite database
+// or the connection string of the postgres database
+var DATABASE_DRIVER = envOrDefault("RECONCILER_DATABASE_DRIVER", "memory")
+var DATABASE_URL = envOrDefault("RECONCILER_DATABASE_URL", "./reconciler.db")
+
+// an update to a task that another service changed in the meantime
+// is tried again on the task as it is now, up to TASK_UPDATE_MAX_ATTEMPTS times in all
+var TASK_UPDATE_MAX_ATTEMPTS = 5
+
+// RECON_RESULTS_PAGE_SIZE is how many results a page has when the query doesn't say,
+// a page has at most RECON_RESULTS_MAX_PAGE_SIZE results
+// and the reconstruction saves the results of a task RECON_RESULTS_SAVE_BATCH_SIZE at a time
+var RECON_RESULTS_PAGE_SIZE = 100
+var RECON_RESULTS_MAX_PAGE_SIZE = 1000
+var RECON_RESULTS_SAVE_BATCH_SIZE = 1000
+
+// RECON_SUMMARY_TOP_MISMATCHING_COLUMNS is how many of the columns that differ most often the summary of a task lists
+var RECON_SUMMARY_TOP_MISMATCHING_COLUMNS = 10
+
+// RECON_REPORT_MAX_BREAKS is how many of the rows that didn't match the html and pdf reports of a task list,
+// the rest are only in the other results files
+var RECON_REPORT_MAX_BREAKS = 10000
+
+// the reconstruction of a task holds up to RECONSTRUCTION_REORDER_BUFFER_SECTIONS sections that arrived
+// before their turn in memory, the rest wait in files under RECONSTRUCTION_SPILL_DIRECTORY
+var RECONSTRUCTION_REORDER_BUFFER_SECTIONS = 100
+var RECONSTRUCTION_SPILL_DIRECTORY = envOrDefault("RECONCILER_RECONSTRUCTION_SPILL_DIRECTORY", os.TempDir())
+
+// FILE_STORE picks where the uploaded files and the results files of tasks are kept: local or s3.
+// The local store keeps them under FILE_STORE_DIRECTORY, the s3 store keeps them in S3_BUCKET
+// of the S3-compatible service (AWS S3, MinIO...) at S3_ENDPOINT, addressing the bucket by path.
+// The links to results files the s3 store hands out work for FILE_STORE_PRESIGNED_URL_EXPIRY
+var FILE_STORE = envOrDefault("RECONCILER_FILE_STORE", "local")
+var FILE_STORE_DIRECTORY = envOrDefault("RECONCILER_FILE_STORE_DIRECTORY", ".")
+var S3_ENDPOINT = envOrDefault("RECONCILER_S3_ENDPOINT", "https://s3.amazonaws.com")
+var S3_REGION = envOrDefault("RECONCILER_S3_REGION", "us-east-1")
+var S3_BUCKET = os.Getenv("RECONCILER_S3_BUCKET")
+var S3_ACCESS_KEY_ID = os.Getenv("RECONCILER_S3_ACCESS_KEY_ID")
+var S3_SECRET_ACCESS_KEY = os.Getenv("RECONCILER_S3_SECRET_ACCESS_KEY")
+var FILE_STORE_PRESIGNED_URL_EXPIRY = time.Duration(15 * time.Minute)
+
+// the checkpoints of tasks are kept in the database when there is one, each service only resumes
+// the tasks it checkpointed itself, telling its checkpoints apart by INSTANCE_ID which has to stay the
+// same across restarts. Without a database the checkpoints are json files under TASK_CHECKPOINTS_DIRECTORY
+var INSTANCE_ID = envOrDefault("RECONCILER_INSTANCE_ID", hostname())
+var TASK_CHECKPOINTS_DIRECTORY = "./checkpoints"
+var TASK_CHECKPOINT_FLUSH_INTERVAL = time.Duration(1 * time.Second)
+
+// TASK_EVENTS_BUFFER_SIZE is how many events a task event stream
+// can fall behind by before it starts missing events
+var TASK_EVENTS_BUFFER_SIZE = 256
+
+// PUBLIC_BASE_URL is where clients reach this service, used for the links sent out in notifications
+var PUBLIC_BASE_URL = envOrDefault("RECONCILER_PUBLIC_BASE_URL", "http://localhost:9090")
+
+// WEBHOOK_SIGNING_SECRET is the key webhook payloads are signed with (HMAC-SHA256), webhooks are not called without it.
+// A failed webhook call is retried up to WEBHOOK_MAX_ATTEMPTS times in all,
+// waiting twice as long after each attempt starting from WEBHOOK_INITIAL_BACKOFF.
+// Webhooks can't be called on loopback, link local or private addresses
+// unless RECONCILER_WEBHOOK_ALLOW_PRIVATE_HOSTS is true
+var WEBHOOK_SIGNING_SECRET = os.Getenv("RECONCILER_WEBHOOK_SIGNING_SECRET")
+var WEBHOOK_MAX_ATTEMPTS = 5
+var WEBHOOK_INITIAL_BACKOFF = time.Duration(1 * time.Second)
+var WEBHOOK_REQUEST_TIMEOUT = time.Duration(10 * time.Second)
+var WEBHOOK_ALLOW_PRIVATE_HOSTS = envOrDefault("RECONCILER_WEBHOOK_ALLOW_PRIVATE_HOSTS", "false") == "true"
+
+// emails are only sent when SMTP_HOST is set, SMTP_USERNAME turns on PLAIN auth.
+// The results file is attached when it is at most EMAIL_MAX_ATTACHMENT_BYTES, otherwise it is only linked.
+// EMAIL_TEMPLATES_DIRECTORY may hold a subject.tmpl and a body.tmpl that replace the default templates
+var SMTP_HOST = os.Getenv("RECONCILER_SMTP_HOST")
+var SMTP_PORT = envOrDefault("RECONCILER_SMTP_PORT", "587")
+var SMTP_USERNAME = os.Getenv("RECONCILER_SMTP_USERNAME")
+var SMTP_PASSWORD = os.Getenv("RECONCILER_SMTP_PASSWORD")
+var EMAIL_FROM = envOrDefault("RECONCILER_EMAIL_FROM", "reconciler@localhost")
+var EMAIL_TEMPLATES_DIRECTORY = os.Getenv("RECONCILER_EMAIL_TEMPLATES_DIRECTORY")
+var EMAIL_MAX_ATTACHMENT_BYTES = int64(5 * 1024 * 1024)
+var EMAIL_MAX_ATTEMPTS = 3
+var EMAIL_INITIAL_BACKOFF = time.Duration(5 * time.Second)
+
+func envOrDefault(key string, fallback string) string {
+	value := os.Getenv(key)
+	if len(value) == 0 {
+		return fallback
+	}
+	return value
+}
+
+// hostname is the name of the machine, or localhost when it has none
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil || len(name) == 0 {
+		return "localhost"
+	}
+	return name
+}
+
+// envIntOrDefault is the number in the environment variable, or the fallback when it is not set to a number
+func envIntOrDefault(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// SHUTDOWN_TIMEOUT is how long in flight requests and
+// section reconciliations each get to finish on shutdown
+var SHUTDOWN_TIMEOUT = time.Duration(30 * time.Second)
+
+// USE_TEMPORAL_WORKFLOWS runs each task as a Temporal workflow on the Temporal service at TEMPORAL_HOST_PORT
+// instead of in goroutines started by the handlers, when RECONCILER_USE_TEMPORAL_WORKFLOWS is true
+var USE_TEMPORAL_WORKFLOWS = envOrDefault("RECONCILER_USE_TEMPORAL_WORKFLOWS", "false") == "true"
+var TEMPORAL_HOST_PORT = envOrDefault("RECONCILER_TEMPORAL_HOST_PORT", "localhost:7233")
+var TEMPORAL_TASK_QUEUE = "reconciliation-tasks"
+var TEMPORAL_ACTIVITY_TIMEOUT = time.Duration(1 * time.Hour)
+var TEMPORAL_ACTIVITY_MAX_ATTEMPTS = 3
+
+// the long running activities of a task record a heartbeat every TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL,
+// temporal retries an activity elsewhere once it goes TEMPORAL_ACTIVITY_HEARTBEAT_TIMEOUT without one
+var TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL = time.Duration(10 * time.Second)
+var TEMPORAL_ACTIVITY_HEARTBEAT_TIMEOUT = time.Duration(1 * time.Minute)
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"reconciler.io/repositories"
+	"reconciler.io/utils"
+)
+
+// EmailRecipientsRequest is the list of addresses emailed when a task finishes.
+type EmailRecipientsRequest struct {
+	NotificationEmails []string
+}
+
+// SetTaskEmailRecipients
+// @Summary Replace the addresses that are emailed when the reconciliation task completes or fails
+// @Accept  json
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Param   recipients body EmailRecipientsRequest true "Email addresses"
+// @Success 200 {object} EmailRecipientsRequest
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/email-recipients [put]
+func SetTaskEmailRecipients(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	parsed, err := utils.ParseAndBindJsonToStruct(ctx, &EmailRecipientsRequest{})
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Failed parse json model", "details": err.Error()})
+		return
+	}
+	request := parsed.(*EmailRecipientsRequest)
+
+	err = taskDetailsRepository.SetEmailRecipients(ctx, taskID, request.NotificationEmails)
+
+	//error on update
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, request)
+}
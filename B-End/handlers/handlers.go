@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.temporal.io/sdk/client"
 	"log"
 	preprocessing "reconciler.io/activities/pre-processing"
 	"reconciler.io/activities/reconciliation"
@@ -12,10 +13,10 @@ import (
 	"reconciler.io/constants"
 	"reconciler.io/models"
 	"reconciler.io/models/enums/file_purpose"
-	"reconciler.io/models/enums/file_storage_locations"
-	"reconciler.io/models/enums/supported_file_extensions"
+	"reconciler.io/models/enums/task_status"
 	"reconciler.io/repositories"
 	"reconciler.io/utils"
+	"reconciler.io/workflows"
 )
 
 func StartReconciliation(ctx *gin.Context) {
@@ -40,11 +41,55 @@ func StartReconciliation(ctx *gin.Context) {
 		return
 	}
 
+	//both files have to be uploaded and the task can only be started once
+	if taskDetails.Status != task_status.Reading {
+		errorDetail := fmt.Sprintf("task with ID [%v] can not be started while it is [%v]", taskID, taskDetails.Status)
+		ctx.JSON(400, gin.H{"error": errorDetail})
+		return
+	}
+
+	//tasks are run as workflows when a temporal client is available
+	if temporalClient, ok := getTemporalClient(ctx); ok {
+		err = startReconTaskWorkflow(ctx, temporalClient, taskDetails)
+
+		// error on start
+		if err != nil {
+			ctx.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.JSON(201, gin.H{"ReconStartedForTaskID": taskID, "WorkflowID": workflows.ReconTaskWorkflowID(taskID)})
+		return
+	}
+
 	go BeginFileReconciliationProcesses(taskDetails, taskDetailsRepository, fileDetailsRepository)
 
 	ctx.JSON(201, gin.H{"ReconStartedForTaskID": taskID})
 }
 
+func getTemporalClient(ctx *gin.Context) (client.Client, bool) {
+	temporalClient, exists := ctx.Get("TemporalClient")
+	if !exists {
+		return nil, false
+	}
+	return temporalClient.(client.Client), true
+}
+
+func startReconTaskWorkflow(ctx context.Context, temporalClient client.Client, taskDetails models.ReconTaskDetails) error {
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        workflows.ReconTaskWorkflowID(taskDetails.ID),
+		TaskQueue: constants.TEMPORAL_TASK_QUEUE,
+	}
+
+	_, err := temporalClient.ExecuteWorkflow(ctx, workflowOptions, workflows.ReconTaskWorkflow, taskDetails.ID)
+
+	//error on start
+	if err != nil {
+		return fmt.Errorf("error on starting workflow for taskID [%s]: [%v]", taskDetails.ID, err)
+	}
+	return nil
+}
+
 // CreateReconciliationTask
 // @Summary Create a reconciliation task
 // @Accept  json
@@ -70,6 +115,32 @@ func CreateReconciliationTask(ctx *gin.Context) {
 		return
 	}
 
+	for _, webhook := range taskDetails.Webhooks {
+		err = webhook.Validate()
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "Validation Failure", "details": err.Error()})
+			return
+		}
+	}
+
+	err = models.ValidateEmailRecipients(taskDetails.NotificationEmails)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Validation Failure", "details": err.Error()})
+		return
+	}
+
+	err = models.ValidateResultsFormats(taskDetails.ResultsFormats)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Validation Failure", "details": err.Error()})
+		return
+	}
+
+	err = models.ValidateResultSinks(taskDetails.ResultSinks)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Validation Failure", "details": err.Error()})
+		return
+	}
+
 	taskID, err := repo.SaveTaskDetails(ctx, *taskDetails)
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": "InternalServerError", "details": err.Error()})
@@ -100,6 +171,16 @@ func GetReconciliationTaskStatus(c *gin.Context) {
 
 	c.JSON(200, status)
 }
+
+// GetSectionWorkerPoolStats
+// @Summary Get the active worker count and queue depth of the section reconciliation worker pool
+// @Produce  json
+// @Success 200 {object} reconciliation.SectionWorkerPoolStats
+// @Router  /workers [get]
+func GetSectionWorkerPoolStats(ctx *gin.Context) {
+	ctx.JSON(200, reconciliation.DefaultSectionWorkerPool.Stats())
+}
+
 func UploadPrimaryFile(ctx *gin.Context) {
 	//get the taskID for the recon status
 	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
@@ -158,8 +239,11 @@ func UploadPrimaryFile(ctx *gin.Context) {
 		return
 	}
 
-	//start reading the file asynchronously
-	go BeginFileReadingProcesses(*fileToBeRead, taskDetails)
+	//start reading the file asynchronously,
+	//workflows read the files themselves once reconciliation is started
+	if _, ok := getTemporalClient(ctx); !ok {
+		go BeginFileReadingProcesses(*fileToBeRead, taskDetails, taskDetailsRepository)
+	}
 
 	//start file reconciliation processes
 	//go BeginFileReconciliationProcesses(taskDetails, taskDetailsRepository, fileDetailsRepository)
@@ -168,27 +252,71 @@ func UploadPrimaryFile(ctx *gin.Context) {
 	ctx.JSON(200, fileToBeRead)
 }
 
-func BeginFileReadingProcesses(fileToRead models.FileToBeRead, taskInfo models.ReconTaskDetails) {
+func BeginFileReadingProcesses(
+	fileToRead models.FileToBeRead,
+	taskInfo models.ReconTaskDetails,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+) {
 	sectionSize := constants.FILE_SECTION_BATCH_SIZE
-	err := preprocessing.ReadFileIntoChannel(context.Background(), fileToRead, taskInfo, sectionSize)
+	err := preprocessing.ReadFileIntoChannel(taskInfo.Control.Context(), fileToRead, taskInfo, sectionSize)
 	if err != nil {
-		log.Fatalf("Error on reading PrimaryFile: %s", err.Error())
+		err = fmt.Errorf("error on reading file: [%v], Error: %v", fileToRead.ID, err)
+		failReconciliationTask(taskInfo.ID, taskDetailsRepo, err)
 	}
 }
 
-func BeginFileReconstructionProcesses(taskInfo models.ReconTaskDetails) {
+// BeginFileReconstructionProcesses writes out the results of the task as its sections are reconciled.
+// The task is only completed once reconciled is closed, a nil reconciled means every section already is.
+// Stopping the context stops the reconstruction without failing the task.
+func BeginFileReconstructionProcesses(
+	ctx context.Context,
+	taskInfo models.ReconTaskDetails,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	reconciled <-chan struct{},
+) {
 	// Recovery mechanism
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("BeginFileReconstructionProcesses goroutine panicked with error: %v", r)
+			failReconciliationTask(taskInfo.ID, taskDetailsRepo, fmt.Errorf("file reconstruction panicked: %v", r))
 		}
 	}()
-	prefix := fmt.Sprintf("ReconResults-%v", taskInfo.ID)
-	filePath := utils.GenerateFilePath(prefix, file_storage_locations.LocalFileSystem, taskInfo.UserID, supported_file_extensions.Csv)
+	filePath := reconstruction.ResultsFilePath(taskInfo)
+
+	summary, err := reconstruction.ReconstructFile(ctx, taskInfo, filePath)
+
+	//the task was cancelled or its reconciliation failed
+	if err != nil && ctx.Err() != nil {
+		log.Printf("Reconstruction of task [%v] stopped: %v", taskInfo.ID, err)
+		return
+	}
 
-	err := reconstruction.ReconstructFile(taskInfo.ID, taskInfo.FileToBeReconstructedChannel, filePath)
 	if err != nil {
-		log.Printf("Error on File Reconstruction: [%v]", err.Error())
+		failReconciliationTask(taskInfo.ID, taskDetailsRepo, fmt.Errorf("error on file reconstruction: %v", err))
+		return
+	}
+
+	//the last section may be written out
+	//before the reconciliation has wrapped up
+	if reconciled != nil {
+		select {
+		case <-reconciled:
+		case <-ctx.Done():
+			log.Printf("Reconstruction of task [%v] stopped: %v", taskInfo.ID, ctx.Err())
+			return
+		}
+	}
+
+	err = taskDetailsRepo.SaveReconSummary(context.Background(), taskInfo.ID, summary)
+	if err != nil {
+		failReconciliationTask(taskInfo.ID, taskDetailsRepo, fmt.Errorf("error on saving results summary: %v", err))
+		return
+	}
+
+	err = taskDetailsRepo.CompleteReconciliationTask(context.Background(), taskInfo.ID, filePath)
+	if err != nil {
+		log.Printf("Error on completing task: [%v]", err.Error())
+		return
 	}
 }
 
@@ -201,44 +329,100 @@ func BeginFileReconciliationProcesses(
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("BeginFileReconciliationProcesses goroutine panicked with error: %v", r)
+			failReconciliationTask(taskInfo.ID, taskDetailsRepo, fmt.Errorf("file reconciliation panicked: %v", r))
 		}
 	}()
 	//see if we have already begun reconciliation for this
-	if taskInfo.HasBegun {
-		log.Printf("Reconcialition already began for taskID [%s]", taskInfo.ID)
+	if taskInfo.Status != task_status.Reading {
+		log.Printf("Reconcialition can not begin for taskID [%s] while it is [%v]", taskInfo.ID, taskInfo.Status)
 		return
 	}
 
+	runFileReconciliationProcesses(taskInfo, taskDetailsRepo, fileDetailsRepo)
+}
+
+// runFileReconciliationProcesses does the actual reconciliation work
+// while the results are written out as the sections are reconciled.
+// It is also used to carry on with tasks that were interrupted by a restart.
+func runFileReconciliationProcesses(
+	taskInfo models.ReconTaskDetails,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	fileDetailsRepo *repositories.FileDetailsRepository,
+) {
 	//if the fileToRead passed in is a comparisonFile,
 	//we need to find the matching primaryFile and vice versa
 	primaryFile, comparisonFile, err := determinePrimaryAndComparisonFiles(taskInfo.ID, fileDetailsRepo)
 
 	//error on determining
 	if err != nil {
-		log.Printf(err.Error())
+		failReconciliationTask(taskInfo.ID, taskDetailsRepo, err)
+		return
+	}
+
+	//a task resumed after a restart may
+	//have already reconciled every section
+	if taskInfo.Status == task_status.Reconstructing {
+		BeginFileReconstructionProcesses(taskInfo.Control.Context(), taskInfo, taskDetailsRepo, nil)
 		return
 	}
 
 	//update the original recon tasks status
-	taskInfo.HasBegun = true
-	err = taskDetailsRepo.UpdateReconciliationTask(context.Background(), taskInfo)
+	err = taskDetailsRepo.TransitionReconciliationTask(context.Background(), taskInfo.ID, task_status.Reconciling)
 	if err != nil {
 		log.Printf("Error on updating recon task details: %s", err.Error())
 		return
 	}
 
-	//if it exists, then we can begin file reconciliation processes
-	//first we spawn a handler for the file reconstruction
-	go BeginFileReconstructionProcesses(taskInfo)
+	//the results are written out as the sections are reconciled,
+	//the reconstruction is stopped if the reconciliation fails
+	reconstructionCtx, stopReconstruction := context.WithCancel(taskInfo.Control.Context())
+	defer stopReconstruction()
+	reconciled := make(chan struct{})
+	reconstructed := make(chan struct{})
+	go func() {
+		defer close(reconstructed)
+		BeginFileReconstructionProcesses(reconstructionCtx, taskInfo, taskDetailsRepo, reconciled)
+	}()
 
 	//now we can start the reconciliation
-	err = reconciliation.BeginFileReconciliation(primaryFile, comparisonFile, taskInfo)
+	err = reconciliation.ReconcileFiles(taskInfo.Control.Context(), primaryFile, comparisonFile, taskInfo)
 
 	//error on reconciliation
 	if err != nil {
-		log.Printf("Error on Reconciliation Start: %s", err.Error())
+		failReconciliationTask(taskInfo.ID, taskDetailsRepo, fmt.Errorf("error on reconciliation: %v", err))
+		stopReconstruction()
+		<-reconstructed
+		return
+	}
+
+	//the last section has been reconciled,
+	//only the results are left to be written out
+	err = taskDetailsRepo.TransitionReconciliationTask(context.Background(), taskInfo.ID, task_status.Reconstructing)
+	if err != nil {
+		log.Printf("Error on updating recon task details: %s", err.Error())
+		stopReconstruction()
+		<-reconstructed
 		return
 	}
+
+	close(reconciled)
+	<-reconstructed
+}
+
+// failReconciliationTask marks the task as failed with the given error.
+// Errors caused by the service shutting down are only logged,
+// the task carries on after a restart.
+func failReconciliationTask(taskID string, taskDetailsRepo *repositories.TaskDetailsRepository, taskErr error) {
+	log.Printf("Task [%v] failed: %v", taskID, taskErr)
+
+	if reconciliation.DefaultSectionWorkerPool.IsDraining() {
+		return
+	}
+
+	err := taskDetailsRepo.FailReconciliationTask(context.Background(), taskID, taskErr)
+	if err != nil {
+		log.Printf("Error on marking task [%v] as failed: %v", taskID, err)
+	}
 }
 
 func determinePrimaryAndComparisonFiles(taskID string, fileDetailsRepo *repositories.FileDetailsRepository) (primaryFile models.FileToBeRead, comparisonFile models.FileToBeRead, err error) {
@@ -321,8 +505,11 @@ func UploadComparisonFile(ctx *gin.Context) {
 		return
 	}
 
-	//start reading the file asynchronously
-	go BeginFileReadingProcesses(*fileToBeRead, taskDetails)
+	//start reading the file asynchronously,
+	//workflows read the files themselves once reconciliation is started
+	if _, ok := getTemporalClient(ctx); !ok {
+		go BeginFileReadingProcesses(*fileToBeRead, taskDetails, taskDetailsRepository)
+	}
 
 	//return success
 	ctx.JSON(200, fileToBeRead)
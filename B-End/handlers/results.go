@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"reconciler.io/activities/reconstruction"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"reconciler.io/models/enums/results_format"
+	"reconciler.io/repositories"
+	"strconv"
+	"time"
+)
+
+// resultsDownloadFormat is a format the results file can be downloaded in
+type resultsDownloadFormat struct {
+	contentType string
+	extension   string
+	// writtenFrom is the format of the results file the download is made from
+	writtenFrom results_format.ResultsFormat
+	// write copies the results file to the response in the format
+	write func(w io.Writer, resultsFile io.Reader) error
+	// isConverted is set when the results file is converted to the format on the way out
+	isConverted bool
+}
+
+var resultsDownloadFormats = map[string]resultsDownloadFormat{
+	"csv": {contentType: "text/csv", extension: "csv", writtenFrom: results_format.Csv, write: copyResultsFile},
+	"json": {
+		contentType: "application/json",
+		extension:   "json",
+		writtenFrom: results_format.Csv,
+		write:       writeResultsFileAsJSON,
+		isConverted: true,
+	},
+	"xlsx": {
+		contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		extension:   "xlsx",
+		writtenFrom: results_format.Xlsx,
+		write:       copyResultsFile,
+	},
+	"html": {contentType: "text/html; charset=utf-8", extension: "html", writtenFrom: results_format.Html, write: copyResultsFile},
+	"pdf":  {contentType: "application/pdf", extension: "pdf", writtenFrom: results_format.Pdf, write: copyResultsFile},
+	"jsonl": {
+		contentType: "application/x-ndjson",
+		extension:   "jsonl",
+		writtenFrom: results_format.JsonLines,
+		write:       copyResultsFile,
+	},
+	"side-by-side": {
+		contentType: "text/csv",
+		extension:   "side-by-side.csv",
+		writtenFrom: results_format.SideBySide,
+		write:       copyResultsFile,
+	},
+}
+
+// GetReconciliationResults
+// @Summary Get the result of each reconciled row of the task, a page at a time
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Param   status query string false "Only results with this status (Successfull, Failed or Pending)"
+// @Param   reason query string false "Only results with a reason containing this text, ignoring case"
+// @Param   reason_code query string false "Only results with a reason of this code (RowMatchFound, RowMismatchFound or NoMatchFound)"
+// @Param   column query string false "Only results whose value in this column differs from the matched row"
+// @Param   value query string false "Only results with this exact value"
+// @Param   offset query int false "How many matching results to skip"
+// @Param   limit query int false "How many results the page has, at most 1000"
+// @Success 200 {object} models.ReconResultsPage
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/results [get]
+func GetReconciliationResults(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	reconResultsRepository := ctx.MustGet("ReconResultsRepository").(repositories.ReconResultsRepository)
+	taskID := ctx.Param("id")
+
+	_, err := taskDetailsRepository.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := parseReconResultsQuery(ctx, taskID)
+
+	//error on parsing the filters
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := reconResultsRepository.QueryReconResults(ctx, query)
+
+	//error on query
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, page)
+}
+
+func parseReconResultsQuery(ctx *gin.Context, taskID string) (models.ReconResultsQuery, error) {
+	query := models.ReconResultsQuery{
+		TaskID:     taskID,
+		Status:     recon_status.ReconciliationStatus(ctx.Query("status")),
+		Reason:     ctx.Query("reason"),
+		ReasonCode: recon_reason_code.ReconReasonCode(ctx.Query("reason_code")),
+		Column:     ctx.Query("column"),
+		Value:      ctx.Query("value"),
+		Limit:      constants.RECON_RESULTS_PAGE_SIZE,
+	}
+
+	switch query.Status {
+	case "", recon_status.Successfull, recon_status.Failed, recon_status.Pending:
+	default:
+		return query, fmt.Errorf("unknown status: [%v]", query.Status)
+	}
+
+	switch query.ReasonCode {
+	case "", recon_reason_code.RowMatchFound, recon_reason_code.RowMismatchFound, recon_reason_code.NoMatchFound:
+	default:
+		return query, fmt.Errorf("unknown reason code: [%v]", query.ReasonCode)
+	}
+
+	var err error
+	if offset, isSet := ctx.GetQuery("offset"); isSet {
+		query.Offset, err = strconv.Atoi(offset)
+		if err != nil || query.Offset < 0 {
+			return query, fmt.Errorf("offset must be a whole number of at least 0: [%v]", offset)
+		}
+	}
+
+	if limit, isSet := ctx.GetQuery("limit"); isSet {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > constants.RECON_RESULTS_MAX_PAGE_SIZE {
+			return query, fmt.Errorf("limit must be a whole number from 1 to %v: [%v]", constants.RECON_RESULTS_MAX_PAGE_SIZE, limit)
+		}
+	}
+
+	return query, nil
+}
+
+// GetReconciliationSummary
+// @Summary Get the totals of how the rows of a completed reconciliation task came out
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Success 200 {object} models.ReconSummary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router  /tasks/{id}/summary [get]
+func GetReconciliationSummary(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	taskDetails, err := taskDetailsRepository.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	//the summary is only worked out once the results are reconstructed
+	if taskDetails.Summary == nil {
+		ctx.JSON(404, gin.H{"error": fmt.Sprintf("task with ID [%v] has no summary yet", taskID)})
+		return
+	}
+
+	ctx.JSON(200, taskDetails.Summary)
+}
+
+// DownloadReconciliationResults
+// @Summary Download the results file of a completed reconciliation task
+// @Produce  text/csv
+// @Produce  json
+// @Produce  application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce  html
+// @Produce  application/pdf
+// @Produce  application/x-ndjson
+// @Param   id path string true "Task ID"
+// @Param   format query string false "csv (the default), json, jsonl, xlsx, html, pdf or side-by-side"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router  /tasks/{id}/results/download [get]
+func DownloadReconciliationResults(ctx *gin.Context) {
+	taskID := ctx.Param("id")
+
+	taskDetails, format, resultsFileKey, isFound := findResultsFile(ctx)
+	if !isFound {
+		return
+	}
+
+	resultsFile, err := taskDetails.Files.Get(ctx, resultsFileKey)
+
+	//the results file is gone from the file store
+	if errors.Is(err, models.ErrFileNotFound) {
+		ctx.JSON(404, gin.H{"error": fmt.Sprintf("results file of task with ID [%v] can not be found", taskID)})
+		return
+	}
+
+	//error on reading from the file store
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	defer resultsFile.Close()
+
+	fileName := fmt.Sprintf("ReconResults-%v.%v", taskID, format.extension)
+	ctx.Header("Content-Type", format.contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", fileName))
+	ctx.Status(200)
+
+	// the headers are already sent, all we can do is stop
+	err = format.write(ctx.Writer, resultsFile)
+	if err != nil {
+		log.Printf("error on streaming results of task: [%v], Error: %v", taskID, err)
+	}
+}
+
+// GetReconciliationResultsURL
+// @Summary Get a link to download the results file of a completed reconciliation task from the file store (S3 only)
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Param   format query string false "csv (the default), xlsx, html, pdf, jsonl or side-by-side"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router  /tasks/{id}/results/url [get]
+func GetReconciliationResultsURL(ctx *gin.Context) {
+	taskID := ctx.Param("id")
+
+	taskDetails, format, resultsFileKey, isFound := findResultsFile(ctx)
+	if !isFound {
+		return
+	}
+
+	//the link is to the results file as it is kept, it can't be converted on the way
+	if format.isConverted {
+		ctx.JSON(400, gin.H{"error": fmt.Sprintf("results of task with ID [%v] can only be downloaded as [%v] through the service", taskID, ctx.Query("format"))})
+		return
+	}
+
+	url, err := taskDetails.Files.PresignedURL(ctx, resultsFileKey, constants.FILE_STORE_PRESIGNED_URL_EXPIRY)
+
+	//the file store only serves its files through the service
+	if errors.Is(err, models.ErrPresignedURLNotSupported) {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	//error on signing the link
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"url":       url,
+		"expiresAt": time.Now().Add(constants.FILE_STORE_PRESIGNED_URL_EXPIRY),
+	})
+}
+
+// findResultsFile looks up the key of the results file of the task in the requested format,
+// when there is none the response has already been written
+func findResultsFile(ctx *gin.Context) (models.ReconTaskDetails, resultsDownloadFormat, string, bool) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	formatName := ctx.DefaultQuery("format", "csv")
+	format, isSupported := resultsDownloadFormats[formatName]
+	if !isSupported {
+		ctx.JSON(400, gin.H{"error": fmt.Sprintf("unsupported results format: [%v]", formatName)})
+		return models.ReconTaskDetails{}, format, "", false
+	}
+
+	taskDetails, err := taskDetailsRepository.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return models.ReconTaskDetails{}, format, "", false
+	}
+
+	//the results file is only written once the task completes
+	if len(taskDetails.ResultsFilePath) == 0 {
+		ctx.JSON(404, gin.H{"error": fmt.Sprintf("task with ID [%v] has no results yet", taskID)})
+		return models.ReconTaskDetails{}, format, "", false
+	}
+
+	//the task was never asked to write its results in this format
+	if !taskDetails.WritesResultsIn(format.writtenFrom) {
+		ctx.JSON(404, gin.H{"error": fmt.Sprintf("task with ID [%v] has no %v results", taskID, format.writtenFrom)})
+		return models.ReconTaskDetails{}, format, "", false
+	}
+
+	return taskDetails, format, reconstruction.ResultsFilePathIn(taskDetails.ResultsFilePath, format.writtenFrom), true
+}
+
+func copyResultsFile(w io.Writer, resultsFile io.Reader) error {
+	_, err := io.Copy(w, resultsFile)
+	return err
+}
+
+// writeResultsFileAsJSON writes the rows of the results file as a json array of objects
+// keyed by the column headers, a row at a time.
+func writeResultsFileAsJSON(w io.Writer, resultsFile io.Reader) error {
+	reader := csv.NewReader(resultsFile)
+	reader.FieldsPerRecord = -1
+
+	_, err := io.WriteString(w, "[")
+	if err != nil {
+		return err
+	}
+
+	// an empty results file has no headers either
+	headers, err := reader.Read()
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	for rowCount := 0; err == nil; rowCount++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		object := resultsRowAsObject(headers, row)
+		if rowCount > 0 {
+			object = append([]byte(","), object...)
+		}
+		_, err = w.Write(object)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+// resultsRowAsObject keys the values of the row by their column header,
+// keeping the order of the columns
+func resultsRowAsObject(headers []string, row []string) []byte {
+	object := []byte("{")
+	for i, value := range row {
+		header := fmt.Sprintf("Column%v", i+1)
+		if i < len(headers) {
+			header = headers[i]
+		}
+
+		encodedHeader, _ := json.Marshal(header)
+		encodedValue, _ := json.Marshal(value)
+		if i > 0 {
+			object = append(object, ',')
+		}
+		object = append(object, encodedHeader...)
+		object = append(object, ':')
+		object = append(object, encodedValue...)
+	}
+	return append(object, '}')
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reconciler.io/activities/partitioning"
+	"reconciler.io/activities/reconciliation"
+	"reconciler.io/constants"
+	"reconciler.io/repositories"
+	"reconciler.io/utils"
+)
+
+// DrainTasks is run once the service has stopped accepting requests.
+// Sections that are being reconciled are given until the context is done to finish,
+// every other section of an unfinished task is picked up again from the checkpoints after a restart.
+// The topics of finished tasks are deleted and every NATS connection is closed,
+// which also deletes the consumers that interrupted sections left behind.
+func DrainTasks(
+	ctx context.Context,
+	checkpointRepo *repositories.TaskCheckpointRepository,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	fileDetailsRepo *repositories.FileDetailsRepository,
+) error {
+	var errs []error
+
+	//let the running section reconciliations finish
+	err := reconciliation.DefaultSectionWorkerPool.Drain(ctx)
+	if err != nil {
+		log.Printf("Section reconciliations still running at the shutdown deadline: [%v]", err)
+		errs = append(errs, fmt.Errorf("error on draining section reconciliations: [%v]", err))
+	}
+
+	//the deadline may have passed already,
+	//the clean up still needs a little time
+	cleanUpCtx := utils.NewContextWithDefaultTimeout()
+
+	tasks, err := taskDetailsRepo.GetAllReconciliationTasks(cleanUpCtx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error on loading tasks: [%v]", err))
+	}
+	completedTasks := make(map[string]bool)
+	partitionCounts := make(map[string]int)
+	for _, task := range tasks {
+		completedTasks[task.ID] = task.Status.IsTerminal()
+		partitionCounts[task.ID] = task.PartitionCount
+	}
+
+	//the files of unfinished tasks keep their topics
+	//so that the task can carry on after a restart
+	files, err := fileDetailsRepo.GetAllFileDetails(cleanUpCtx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error on loading files: [%v]", err))
+	}
+	for _, file := range files {
+		if file.ReadFileResultsStream == nil {
+			continue
+		}
+
+		if completedTasks[file.ReconciliationTaskID] {
+			err = partitioning.DeleteFileTopics(cleanUpCtx, file, partitionCounts[file.ReconciliationTaskID])
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		err = file.ReadFileResultsStream.Close(cleanUpCtx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error on closing stream for file: [%v], Error: %v", file.ID, err))
+		}
+	}
+
+	for _, task := range tasks {
+		if task.FileToBeReconstructedChannel == nil || !completedTasks[task.ID] {
+			continue
+		}
+
+		topicName := fmt.Sprintf("Reconstruct-%v", task.ID)
+		err = task.FileToBeReconstructedChannel.DeleteStreamTopic(cleanUpCtx, constants.FILE_RECONSTRUCTION_STREAM_NAME, topicName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error on deleting topic: [%v], Error: %v", topicName, err))
+		}
+	}
+
+	//the tasks share the one connection they send their sections to be reconstructed over
+	err = taskDetailsRepo.CloseTaskStreams(cleanUpCtx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	//write out any checkpoints that are still waiting to be persisted
+	err = checkpointRepo.Flush()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error on flushing task checkpoints: [%v]", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"log"
+	"reconciler.io/activities/partitioning"
+	"reconciler.io/models"
+	"reconciler.io/repositories"
+	"reconciler.io/utils"
+	"reconciler.io/workflows"
+)
+
+// CancelReconciliationTask
+// @Summary Cancel a reconciliation task and clean up its streams
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/cancel [post]
+func CancelReconciliationTask(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	fileDetailsRepository := ctx.MustGet("FileDetailsRepository").(*repositories.FileDetailsRepository)
+	taskID := ctx.Param("id")
+
+	taskDetails, err := taskDetailsRepository.CancelReconciliationTask(ctx, taskID)
+
+	// error on cancel
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	//the workflow of the task may be running its activities on another worker
+	if temporalClient, ok := getTemporalClient(ctx); ok {
+		cancelReconTaskWorkflow(ctx, temporalClient, taskID)
+	}
+
+	//once reconciliation has begun the activities clean up
+	//after themselves, before that only the file topics exist
+	if !taskDetails.HasBegun {
+		go cleanUpFilesOfCancelledTask(taskDetails, fileDetailsRepository)
+	}
+
+	ctx.JSON(200, gin.H{"CancelledTaskID": taskID})
+}
+
+// PauseReconciliationTask
+// @Summary Pause a reconciliation task, sections already being reconciled are finished
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/pause [post]
+func PauseReconciliationTask(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	err := taskDetailsRepository.PauseReconciliationTask(ctx, taskID)
+
+	// error on pause
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"PausedTaskID": taskID})
+}
+
+// ResumeReconciliationTask
+// @Summary Resume a paused reconciliation task
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/resume [post]
+func ResumeReconciliationTask(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	err := taskDetailsRepository.ResumeReconciliationTask(ctx, taskID)
+
+	// error on resume
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"ResumedTaskID": taskID})
+}
+
+func cleanUpFilesOfCancelledTask(taskDetails models.ReconTaskDetails, fileDetailsRepo *repositories.FileDetailsRepository) {
+	for _, getFile := range []func(context.Context, string) (models.FileToBeRead, error){
+		fileDetailsRepo.GetPrimaryFileDetailsForTask,
+		fileDetailsRepo.GetComparisonFileDetailsForTask,
+	} {
+		file, err := getFile(context.Background(), taskDetails.ID)
+
+		//the file was never uploaded
+		if err != nil {
+			continue
+		}
+
+		err = partitioning.DeleteFileTopics(utils.NewContextWithDefaultTimeout(), file, taskDetails.PartitionCount)
+		if err != nil {
+			log.Printf("Error on cleaning up cancelled task: [%v], Error: %v", taskDetails.ID, err)
+		}
+	}
+}
+
+// cancelReconTaskWorkflow cancels the workflow running the task, if it was ever started
+func cancelReconTaskWorkflow(ctx context.Context, temporalClient client.Client, taskID string) {
+	err := temporalClient.CancelWorkflow(ctx, workflows.ReconTaskWorkflowID(taskID), "")
+
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		log.Printf("Error on cancelling workflow of task: [%v], Error: %v", taskID, err)
+	}
+}
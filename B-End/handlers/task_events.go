@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"io"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/repositories"
+	"time"
+)
+
+// StreamReconciliationTaskEvents
+// @Summary Stream the lifecycle and progress events of a reconciliation task as Server-Sent Events
+// @Produce  text/event-stream
+// @Param   id path string true "Task ID"
+// @Success 200 {object} models.TaskEvent
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/events [get]
+func StreamReconciliationTaskEvents(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	//subscribe before looking the task up so no event is missed in between
+	events, unsubscribe := taskDetailsRepository.SubscribeToTaskEvents(taskID)
+	defer unsubscribe()
+
+	taskDetails, err := taskDetailsRepository.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	streamTaskEvents(ctx.Request.Context(), taskDetails, events, func(event models.TaskEvent) error {
+		ctx.SSEvent(string(event.Type), event)
+		ctx.Writer.Flush()
+		return ctx.Request.Context().Err()
+	})
+}
+
+// StreamReconciliationTaskEventsOverWebSocket
+// @Summary Stream the lifecycle and progress events of a reconciliation task over a WebSocket
+// @Param   id path string true "Task ID"
+// @Success 101 {object} models.TaskEvent
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/events/ws [get]
+func StreamReconciliationTaskEventsOverWebSocket(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	//subscribe before looking the task up so no event is missed in between
+	events, unsubscribe := taskDetailsRepository.SubscribeToTaskEvents(taskID)
+	defer unsubscribe()
+
+	taskDetails, err := taskDetailsRepository.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	webSocketServer := websocket.Server{
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+
+			//the client never sends anything, reading
+			//only tells us when it has gone away
+			connCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				_, _ = io.Copy(io.Discard, conn)
+				cancel()
+			}()
+
+			streamTaskEvents(connCtx, taskDetails, events, func(event models.TaskEvent) error {
+				return websocket.JSON.Send(conn, event)
+			})
+		},
+	}
+	webSocketServer.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// streamTaskEvents sends the current state of the task followed by its events
+// until the task finishes, the client goes away or the event streams are closed on shutdown.
+func streamTaskEvents(
+	ctx context.Context,
+	taskDetails models.ReconTaskDetails,
+	events <-chan models.TaskEvent,
+	send func(event models.TaskEvent) error,
+) {
+	currentState := models.TaskEvent{
+		TaskID:     taskDetails.ID,
+		Type:       task_event_type.ForStatus(taskDetails.Status),
+		Status:     taskDetails.Status,
+		Error:      taskDetails.Error,
+		Progress:   taskDetails.Progress,
+		OccurredAt: time.Now(),
+	}
+
+	err := send(currentState)
+
+	//the client has gone or there is nothing more to come
+	if err != nil || currentState.Type.IsFinal() {
+		return
+	}
+
+	for {
+		select {
+		case event, isOpen := <-events:
+			if !isOpen {
+				return
+			}
+
+			err = send(event)
+			if err != nil || event.Type.IsFinal() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
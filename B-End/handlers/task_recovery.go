@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	preprocessing "reconciler.io/activities/pre-processing"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/task_status"
+	"reconciler.io/repositories"
+)
+
+// ResumeIncompleteTasks restores every unfinished checkpointed task into the repositories
+// and carries on with the tasks that were still running when the service stopped.
+// Files that were not fully read carry on reading after the last published section,
+// and reconciliation replays the durable NATS streams skipping sections that were already reconciled.
+func ResumeIncompleteTasks(
+	checkpointRepo *repositories.TaskCheckpointRepository,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	fileDetailsRepo *repositories.FileDetailsRepository,
+) error {
+	ctx := context.Background()
+	checkpoints, err := checkpointRepo.GetAllTaskCheckpoints(ctx)
+
+	//error on loading checkpoints
+	if err != nil {
+		return fmt.Errorf("error on loading task checkpoints: %v", err)
+	}
+
+	for _, checkpoint := range checkpoints {
+		//finished tasks are never resumed, their checkpoints
+		//are only left over from before they were deleted on finishing
+		if checkpoint.IsComplete || checkpoint.TaskDetails.Status.IsTerminal() {
+			err = checkpointRepo.DeleteTaskCheckpoint(ctx, checkpoint.TaskDetails.ID)
+			if err != nil {
+				log.Printf("Error on deleting checkpoint of finished task: [%v], Error: %v", checkpoint.TaskDetails.ID, err)
+			}
+			continue
+		}
+
+		taskInfo, filesToBeRead, err := restoreTaskFromCheckpoint(ctx, checkpoint, taskDetailsRepo, fileDetailsRepo)
+
+		//one broken task should not stop the others from resuming
+		if err != nil {
+			log.Printf("Error on restoring task: [%v], Error: %v", checkpoint.TaskDetails.ID, err)
+			continue
+		}
+
+		//tasks run as workflows are resumed by temporal retrying their activities
+		if taskInfo.Status.IsTerminal() || constants.USE_TEMPORAL_WORKFLOWS {
+			continue
+		}
+
+		log.Printf("Resuming incomplete task: [%v]", taskInfo.ID)
+
+		for _, fileToBeRead := range filesToBeRead {
+			if checkpoint.Files[fileToBeRead.ID].IsFullyRead {
+				continue
+			}
+			go BeginFileReadingProcesses(fileToBeRead, taskInfo, taskDetailsRepo)
+		}
+
+		if taskInfo.Status == task_status.Reconciling || taskInfo.Status == task_status.Reconstructing {
+			go func(taskInfo models.ReconTaskDetails) {
+				// Recovery mechanism
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("ResumeIncompleteTasks goroutine panicked with error: %v", r)
+					}
+				}()
+				runFileReconciliationProcesses(taskInfo, taskDetailsRepo, fileDetailsRepo)
+			}(taskInfo)
+		}
+	}
+
+	return nil
+}
+
+func restoreTaskFromCheckpoint(
+	ctx context.Context,
+	checkpoint models.TaskCheckpoint,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	fileDetailsRepo *repositories.FileDetailsRepository,
+) (models.ReconTaskDetails, []models.FileToBeRead, error) {
+	taskInfo, err := taskDetailsRepo.RestoreTaskDetails(ctx, checkpoint.TaskDetails)
+
+	//error on restore
+	if err != nil {
+		return models.ReconTaskDetails{}, nil, err
+	}
+
+	//the task finished after its checkpoint was taken
+	if taskInfo.Status.IsTerminal() {
+		return taskInfo, nil, nil
+	}
+
+	filesToBeRead := make([]models.FileToBeRead, 0)
+	for _, fileCheckpoint := range checkpoint.Files {
+		//partitions are not uploaded files,
+		//they are re-created by the partitioner
+		if fileCheckpoint.File == nil {
+			continue
+		}
+
+		fileToBeRead := *fileCheckpoint.File
+		fileToBeRead.FilePath = fileCheckpoint.FilePath
+
+		fileToBeRead, err = preprocessing.ReconnectFileStream(ctx, fileToBeRead)
+
+		//error on reconnecting
+		if err != nil {
+			return models.ReconTaskDetails{}, nil, err
+		}
+
+		_, err = fileDetailsRepo.SaveFileToBeRead(ctx, fileToBeRead)
+
+		//error on save
+		if err != nil {
+			return models.ReconTaskDetails{}, nil, err
+		}
+
+		filesToBeRead = append(filesToBeRead, fileToBeRead)
+	}
+
+	return taskInfo, filesToBeRead, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"reconciler.io/models"
+	"reconciler.io/repositories"
+	"reconciler.io/utils"
+)
+
+// AddTaskWebhook
+// @Summary Register a webhook that is called when the reconciliation task changes
+// @Accept  json
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Param   webhook body models.Webhook true "Webhook URL and the events it is called for"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} map[string]string
+// @Router  /tasks/{id}/webhooks [post]
+func AddTaskWebhook(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	taskID := ctx.Param("id")
+
+	parsed, err := utils.ParseAndBindJsonToStruct(ctx, &models.Webhook{})
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": "Failed parse json model", "details": err.Error()})
+		return
+	}
+
+	// the ID is always given out by the task
+	webhook := *parsed.(*models.Webhook)
+	webhook.ID = ""
+
+	webhook, err = taskDetailsRepository.AddWebhook(ctx, taskID, webhook)
+
+	//error on register
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(201, webhook)
+}
+
+// GetTaskWebhookDeliveries
+// @Summary Get the log of every webhook call made for the reconciliation task
+// @Produce  json
+// @Param   id path string true "Task ID"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router  /tasks/{id}/webhooks/deliveries [get]
+func GetTaskWebhookDeliveries(ctx *gin.Context) {
+	taskDetailsRepository := ctx.MustGet("TaskDetailsRepository").(*repositories.TaskDetailsRepository)
+	deliveryRepository := ctx.MustGet("WebhookDeliveryRepository").(*repositories.WebhookDeliveryRepository)
+	taskID := ctx.Param("id")
+
+	_, err := taskDetailsRepository.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := deliveryRepository.GetWebhookDeliveriesForTask(ctx, taskID)
+
+	//error on retrieving the deliveries
+	if err != nil {
+		ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, deliveries)
+}
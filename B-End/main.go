@@ -1,18 +1,118 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"go.temporal.io/sdk/client"
+	"os"
+	"os/signal"
+	"reconciler.io/constants"
 	"reconciler.io/handlers"
+	"reconciler.io/models"
+	"reconciler.io/notifications"
 	"reconciler.io/repositories"
 	"reconciler.io/servers/http"
+	"reconciler.io/workflows"
+	"syscall"
 )
 
 // @title Reconciliation Service API
 // @version 1.0
 // @description This is the API for the reconciliation service.
 func main() {
-	fileDetailsRepo := repositories.NewFileDetailsRepository()
-	taskDetailsRepo := repositories.NewTaskDetailsRepository()
+	//keep the tasks, their files and results in memory unless a database is configured
+	var taskDetailsStore repositories.TaskDetailsStore = repositories.NewInMemoryTaskDetailsStore()
+	var fileDetailsStore repositories.FileDetailsStore = repositories.NewInMemoryFileDetailsStore()
+	var reconResultsRepo repositories.ReconResultsRepository = repositories.NewInMemoryReconResultsRepository()
+	var resultTables models.ResultTableWriter
+	var checkpointStore repositories.TaskCheckpointStore
+	var webhookDeliveryStore repositories.WebhookDeliveryStore = repositories.NewInMemoryWebhookDeliveryStore()
+	if constants.DATABASE_DRIVER != "memory" {
+		database, err := repositories.OpenSQLDatabase(context.Background(), constants.DATABASE_DRIVER, constants.DATABASE_URL)
+
+		//error on opening the database
+		if err != nil {
+			fmt.Printf("unable to open database: %s", err.Error())
+			return
+		}
+		defer database.Close()
+
+		taskDetailsStore = repositories.NewSQLTaskDetailsStore(database)
+		fileDetailsStore = repositories.NewSQLFileDetailsStore(database)
+		reconResultsRepo = repositories.NewSQLReconResultsRepository(database)
+		resultTables = repositories.NewSQLResultTableWriter(database)
+		checkpointStore = repositories.NewSQLTaskCheckpointStore(database, constants.INSTANCE_ID)
+		webhookDeliveryStore = repositories.NewSQLWebhookDeliveryStore(database)
+	} else {
+		fileCheckpointStore, err := repositories.NewFileTaskCheckpointStore(constants.TASK_CHECKPOINTS_DIRECTORY)
+
+		//error on creating the checkpoints directory
+		if err != nil {
+			fmt.Printf("unable to load task checkpoints: %s", err.Error())
+			return
+		}
+		checkpointStore = fileCheckpointStore
+	}
+
+	checkpointRepo, err := repositories.NewTaskCheckpointRepositoryWithStore(checkpointStore)
+
+	//error on loading checkpoints
+	if err != nil {
+		fmt.Printf("unable to load task checkpoints: %s", err.Error())
+		return
+	}
+
+	//publish the results of tasks with stream result sinks in an encoding consumers outside the service can read
+	resultStreams, err := models.NewStreamProviderWithEncoding(constants.NATS_URL, models.StreamEncoding{
+		Codec:       constants.RESULT_SINK_STREAM_CODEC,
+		Compression: constants.RESULT_SINK_STREAM_COMPRESSION,
+	})
+
+	//error on connecting to the result streams
+	if err != nil {
+		fmt.Printf("unable to connect to result streams: %s", err.Error())
+		return
+	}
+	defer resultStreams.Close(context.Background())
+
+	//keep the uploaded files and the results files on the local disk or in S3
+	files, err := models.NewFileStore()
+
+	//error on setting up the file store
+	if err != nil {
+		fmt.Printf("unable to set up file store: %s", err.Error())
+		return
+	}
+
+	fileDetailsRepo := repositories.NewFileDetailsRepositoryWithStore(fileDetailsStore, checkpointRepo)
+	taskDetailsRepo := repositories.NewTaskDetailsRepositoryWithStore(taskDetailsStore, checkpointRepo)
+	taskDetailsRepo.RecordResultsIn(reconResultsRepo)
+	taskDetailsRepo.SendResultSinksTo(resultTables, resultStreams)
+	taskDetailsRepo.StoreFilesIn(files)
+	webhookDeliveryRepo := repositories.NewWebhookDeliveryRepositoryWithStore(webhookDeliveryStore)
+
+	//call the webhooks of tasks as they change
+	webhookNotifier := notifications.NewWebhookNotifier(taskDetailsRepo, webhookDeliveryRepo)
+	taskDetailsRepo.OnTaskEvent(webhookNotifier.HandleTaskEvent)
+
+	//email the recipients of tasks when they finish, if there is somewhere to send email through
+	var emailNotifier *notifications.EmailNotifier
+	if len(constants.SMTP_HOST) > 0 {
+		emailNotifier, err = notifications.NewEmailNotifier(taskDetailsRepo)
+
+		//error on setting up email
+		if err != nil {
+			fmt.Printf("unable to set up email notifications: %s", err.Error())
+			return
+		}
+		taskDetailsRepo.OnTaskEvent(emailNotifier.HandleTaskEvent)
+	}
+
+	//pick up any tasks that were interrupted by a restart
+	err = handlers.ResumeIncompleteTasks(checkpointRepo, taskDetailsRepo, fileDetailsRepo)
+	if err != nil {
+		fmt.Printf("unable to resume incomplete tasks: %s", err.Error())
+	}
 
 	//set up a gin server
 	server := http.NewRestApiServer()
@@ -20,20 +120,92 @@ func main() {
 	// Apply the repository middleware to the router
 	server.Use(repositories.FileDetailsRepositoryMiddleware(fileDetailsRepo))
 	server.Use(repositories.TaskDetailsRepositoryMiddleware(taskDetailsRepo))
+	server.Use(repositories.ReconResultsRepositoryMiddleware(reconResultsRepo))
+	server.Use(repositories.WebhookDeliveryRepositoryMiddleware(webhookDeliveryRepo))
+
+	//let the task event streams go on shutdown
+	server.OnShutdown(taskDetailsRepo.CloseTaskEventStreams)
+
+	//run tasks as temporal workflows if enabled
+	stopTemporalWorker := func() {}
+	if constants.USE_TEMPORAL_WORKFLOWS {
+		temporalClient, err := client.Dial(client.Options{HostPort: constants.TEMPORAL_HOST_PORT})
+
+		//error on connecting to temporal
+		if err != nil {
+			fmt.Printf("unable to connect to temporal: %s", err.Error())
+			return
+		}
+		defer temporalClient.Close()
+
+		reconTaskWorker := workflows.NewReconTaskWorker(temporalClient, taskDetailsRepo, fileDetailsRepo)
+		err = reconTaskWorker.Start()
+
+		//error on worker start
+		if err != nil {
+			fmt.Printf("unable to start temporal worker: %s", err.Error())
+			return
+		}
+		stopTemporalWorker = reconTaskWorker.Stop
+
+		server.UseTemporalClient(temporalClient)
+	}
 
 	//register the routes and handlers
 	server.POST("/tasks", handlers.CreateReconciliationTask)
 	server.POST("/tasks/:id/primary-file", handlers.UploadPrimaryFile)
 	server.POST("/tasks/:id/comparison-file", handlers.UploadComparisonFile)
 	server.POST("/tasks/:id/start-reconciliation", handlers.StartReconciliation)
+	server.POST("/tasks/:id/cancel", handlers.CancelReconciliationTask)
+	server.POST("/tasks/:id/pause", handlers.PauseReconciliationTask)
+	server.POST("/tasks/:id/resume", handlers.ResumeReconciliationTask)
 	server.GET("/tasks/:id", handlers.GetReconciliationTaskStatus)
+	server.GET("/tasks/:id/events", handlers.StreamReconciliationTaskEvents)
+	server.GET("/tasks/:id/events/ws", handlers.StreamReconciliationTaskEventsOverWebSocket)
+	server.POST("/tasks/:id/webhooks", handlers.AddTaskWebhook)
+	server.GET("/tasks/:id/webhooks/deliveries", handlers.GetTaskWebhookDeliveries)
+	server.PUT("/tasks/:id/email-recipients", handlers.SetTaskEmailRecipients)
+	server.GET("/tasks/:id/results", handlers.GetReconciliationResults)
+	server.GET("/tasks/:id/summary", handlers.GetReconciliationSummary)
+	server.GET("/tasks/:id/results/download", handlers.DownloadReconciliationResults)
+	server.GET("/tasks/:id/results/url", handlers.GetReconciliationResultsURL)
+	server.GET("/workers", handlers.GetSectionWorkerPoolStats)
+
+	//stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Start the server
-	err := server.Run(":9090")
+	// Start the server, this returns once
+	// it has stopped accepting new requests
+	err = server.RunUntil(ctx, ":9090", constants.SHUTDOWN_TIMEOUT)
 
 	//error on server start
 	if err != nil {
 		fmt.Printf("unable to run server: %s", err.Error())
-		return
+	}
+
+	//no new workflow activities are picked up from here on
+	stopTemporalWorker()
+
+	//let the running tasks finish or checkpoint before exiting
+	drainCtx, cancel := context.WithTimeout(context.Background(), constants.SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	err = handlers.DrainTasks(drainCtx, checkpointRepo, taskDetailsRepo, fileDetailsRepo)
+	if err != nil {
+		fmt.Printf("unable to cleanly drain tasks: %s", err.Error())
+	}
+
+	//give the webhooks of tasks that finished while draining a chance to go out
+	err = webhookNotifier.Close(drainCtx)
+	if err != nil {
+		fmt.Printf("unable to finish calling webhooks: %s", err.Error())
+	}
+
+	if emailNotifier != nil {
+		err = emailNotifier.Close(drainCtx)
+		if err != nil {
+			fmt.Printf("unable to finish sending emails: %s", err.Error())
+		}
 	}
 }
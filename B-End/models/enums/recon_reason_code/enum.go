@@ -0,0 +1,9 @@
+package recon_reason_code
+
+type ReconReasonCode string
+
+const (
+	RowMatchFound    ReconReasonCode = "RowMatchFound"
+	RowMismatchFound ReconReasonCode = "RowMismatchFound"
+	NoMatchFound     ReconReasonCode = "NoMatchFound"
+)
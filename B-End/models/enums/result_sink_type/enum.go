@@ -0,0 +1,10 @@
+package result_sink_type
+
+type ResultSinkType string
+
+const (
+	// Sql inserts the results into a table of the database of the service
+	Sql ResultSinkType = "sql"
+	// Stream publishes the results on a NATS subject
+	Stream ResultSinkType = "stream"
+)
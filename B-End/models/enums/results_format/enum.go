@@ -0,0 +1,14 @@
+package results_format
+
+type ResultsFormat string
+
+const (
+	Csv  ResultsFormat = "csv"
+	Xlsx ResultsFormat = "xlsx"
+	Html ResultsFormat = "html"
+	Pdf  ResultsFormat = "pdf"
+	// JsonLines is a json document of the result of every row, a line each
+	JsonLines ResultsFormat = "jsonl"
+	// SideBySide is a csv of every row next to the row it was matched to
+	SideBySide ResultsFormat = "side-by-side"
+)
@@ -0,0 +1,35 @@
+package task_event_type
+
+import "reconciler.io/models/enums/task_status"
+
+type TaskEventType string
+
+const (
+	TaskStatusChanged TaskEventType = "TaskStatusChanged"
+	TaskPaused        TaskEventType = "TaskPaused"
+	TaskResumed       TaskEventType = "TaskResumed"
+	TaskCompleted     TaskEventType = "TaskCompleted"
+	TaskFailed        TaskEventType = "TaskFailed"
+	TaskCancelled     TaskEventType = "TaskCancelled"
+	FileReadCompleted TaskEventType = "FileReadCompleted"
+	SectionReconciled TaskEventType = "SectionReconciled"
+)
+
+// IsFinal is true for the events after which a task publishes nothing else.
+func (t TaskEventType) IsFinal() bool {
+	return t == TaskCompleted || t == TaskFailed || t == TaskCancelled
+}
+
+// ForStatus is the event published when a task moves to the given status.
+func ForStatus(status task_status.TaskStatus) TaskEventType {
+	switch status {
+	case task_status.Completed:
+		return TaskCompleted
+	case task_status.Failed:
+		return TaskFailed
+	case task_status.Cancelled:
+		return TaskCancelled
+	default:
+		return TaskStatusChanged
+	}
+}
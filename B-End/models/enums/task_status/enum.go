@@ -0,0 +1,19 @@
+package task_status
+
+type TaskStatus string
+
+const (
+	Created        TaskStatus = "Created"
+	AwaitingFiles  TaskStatus = "AwaitingFiles"
+	Reading        TaskStatus = "Reading"
+	Reconciling    TaskStatus = "Reconciling"
+	Reconstructing TaskStatus = "Reconstructing"
+	Completed      TaskStatus = "Completed"
+	Failed         TaskStatus = "Failed"
+	Cancelled      TaskStatus = "Cancelled"
+)
+
+// IsTerminal is true for the statuses a task never leaves.
+func (s TaskStatus) IsTerminal() bool {
+	return s == Completed || s == Failed || s == Cancelled
+}
@@ -0,0 +1,9 @@
+package webhook_delivery_status
+
+type WebhookDeliveryStatus string
+
+const (
+	Pending   WebhookDeliveryStatus = "Pending"
+	Delivered WebhookDeliveryStatus = "Delivered"
+	Failed    WebhookDeliveryStatus = "Failed"
+)
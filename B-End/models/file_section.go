@@ -5,17 +5,31 @@ import (
 	"reconciler.io/models/enums/recon_status"
 )
 
+// FileSection is a batch of rows from a file as it travels through the streams.
+// ComparisonPairs and ReconConfig belong to the task, so they are never put on the wire
+// and are filled back in from the task details by the consumer.
+// ColumnHeaders only travel on the first section of a file (or partition),
+// and once the section has been reconciled so do the ComparisonColumnHeaders of the comparison file.
 type FileSection struct {
-	ID                    string
-	TaskID                string
-	FileID                string
-	SectionSequenceNumber int
-	OriginalFilePurpose   file_purpose.FilePurposeType
-	SectionRows           []FileSectionRow
-	ComparisonPairs       []ComparisonPair
-	ColumnHeaders         []string
-	ReconConfig           ReconciliationConfigs
-	IsLastSection         bool
+	ID                      string
+	TaskID                  string
+	FileID                  string
+	SectionSequenceNumber   int
+	OriginalFilePurpose     file_purpose.FilePurposeType
+	SectionRows             []FileSectionRow
+	ComparisonPairs         []ComparisonPair      `json:"-"`
+	ColumnHeaders           []string              `json:",omitempty"`
+	ComparisonColumnHeaders []string              `json:",omitempty"`
+	ReconConfig             ReconciliationConfigs `json:"-"`
+	IsLastSection           bool
+	PartitionNumber         int `json:",omitempty"`
+	PartitionCount          int `json:",omitempty"`
+}
+
+// AttachTaskMetadata fills in the task level fields that are not sent over the streams.
+func (s *FileSection) AttachTaskMetadata(taskDetails ReconTaskDetails) {
+	s.ComparisonPairs = taskDetails.ComparisonPairs
+	s.ReconConfig = taskDetails.ReconConfig
 }
 
 func (s *FileSection) AllRowsAreReconciled() bool {
@@ -27,10 +41,14 @@ func (s *FileSection) AllRowsAreReconciled() bool {
 	return true
 }
 
+// FileSectionRow is a row of a file. Once the row has been matched to a row of the comparison file
+// MatchedRowNumber is that row and MatchedColumnsFromRow are its columns.
 type FileSectionRow struct {
-	RowNumber            uint64
-	RawData              string
-	ParsedColumnsFromRow []string
-	ReconResult          recon_status.ReconciliationStatus
-	ReconResultReasons   []string
+	RowNumber             uint64
+	RawData               string `json:",omitempty"`
+	ParsedColumnsFromRow  []string
+	ReconResult           recon_status.ReconciliationStatus
+	ReconResultReasons    []ReconReason
+	MatchedRowNumber      *uint64  `json:",omitempty"`
+	MatchedColumnsFromRow []string `json:",omitempty"`
 }
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reconciler.io/constants"
+	"reconciler.io/models/enums/file_storage_locations"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrFileNotFound is returned when the store has no file with the key
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrPresignedURLNotSupported is returned by stores whose files can't be linked to from outside the service
+var ErrPresignedURLNotSupported = errors.New("the file store does not hand out links to its files")
+
+// FileStore keeps the uploaded files and the results files of tasks.
+// Files are known by a key, a path separated by slashes that is the same whichever store keeps them.
+type FileStore interface {
+	Location() file_storage_locations.FileStorageLocation
+	// Put stores the content under the key, replacing any file already there.
+	// A file that can't be stored in full is not stored at all.
+	Put(ctx context.Context, key string, content io.Reader) error
+	// Get opens the file with the key for reading, the caller closes it
+	Get(ctx context.Context, key string) (*StoredFile, error)
+	// Delete removes the file with the key, a file that is not there is already deleted
+	Delete(ctx context.Context, key string) error
+	// List returns the files whose key starts with the prefix, in the order of their keys
+	List(ctx context.Context, prefix string) ([]StoredFileInfo, error)
+	// PresignedURL is a link the file can be downloaded from without going through the service,
+	// which stops working once expiresIn has passed
+	PresignedURL(ctx context.Context, key string, expiresIn time.Duration) (string, error)
+}
+
+// StoredFile is the content of a file of a FileStore as it is read
+type StoredFile struct {
+	io.ReadCloser
+	Size int64
+}
+
+type StoredFileInfo struct {
+	Key        string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// NewFileStore creates the store picked by constants.FILE_STORE.
+func NewFileStore() (FileStore, error) {
+	switch constants.FILE_STORE {
+	case "local":
+		return NewLocalFileStore(constants.FILE_STORE_DIRECTORY), nil
+	case "s3":
+		return NewS3FileStore(S3FileStoreConfig{
+			Endpoint:        constants.S3_ENDPOINT,
+			Region:          constants.S3_REGION,
+			Bucket:          constants.S3_BUCKET,
+			AccessKeyID:     constants.S3_ACCESS_KEY_ID,
+			SecretAccessKey: constants.S3_SECRET_ACCESS_KEY,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported file store: [%v]", constants.FILE_STORE)
+	}
+}
+
+// cleanFileKey is the key without a leading ./ or repeated slashes,
+// keys that are absolute or climb out of the store can't be used
+func cleanFileKey(key string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(key, "\\", "/"))
+	if len(key) == 0 || cleaned == "." || strings.HasPrefix(cleaned, "/") || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid file key: [%v]", key)
+	}
+	return cleaned, nil
+}
+
+// LocalFileStore keeps the files under a directory of the local disk, a key is the path of its file in the directory.
+type LocalFileStore struct {
+	directory string
+}
+
+func NewLocalFileStore(directory string) *LocalFileStore {
+	return &LocalFileStore{directory: directory}
+}
+
+func (s *LocalFileStore) Location() file_storage_locations.FileStorageLocation {
+	return file_storage_locations.LocalFileSystem
+}
+
+// filePath is where the file with the key is on the disk
+func (s *LocalFileStore) filePath(key string) (string, error) {
+	cleaned, err := cleanFileKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.directory, filepath.FromSlash(cleaned)), nil
+}
+
+// Put writes the file next to where it goes and only moves it into place once it is complete
+func (s *LocalFileStore) Put(ctx context.Context, key string, content io.Reader) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("error on creating directory of file: [%v], Error: %v", key, err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error on creating file: [%v], Error: %v", key, err)
+	}
+
+	_, err = io.Copy(file, content)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filePath)
+	}
+
+	// failed to write, don't leave the partial file behind
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("error on writing file: [%v], Error: %v", key, err)
+	}
+	return nil
+}
+
+func (s *LocalFileStore) Get(ctx context.Context, key string) (*StoredFile, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: [%v]", ErrFileNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &StoredFile{ReadCloser: file, Size: fileInfo.Size()}, nil
+}
+
+func (s *LocalFileStore) Delete(ctx context.Context, key string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error on deleting file: [%v], Error: %v", key, err)
+	}
+	return nil
+}
+
+// List walks the directory of the prefix, the files being written by Put are left out
+func (s *LocalFileStore) List(ctx context.Context, prefix string) ([]StoredFileInfo, error) {
+	// the files of the prefix are all under the directory it names
+	directoryKey := path.Dir(strings.ReplaceAll(prefix, "\\", "/"))
+	directory := s.directory
+	if directoryKey != "." {
+		var err error
+		directory, err = s.filePath(directoryKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]StoredFileInfo, 0)
+	err := filepath.WalkDir(directory, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// nothing was ever stored under the prefix
+			if errors.Is(err, fs.ErrNotExist) && filePath == directory {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(s.directory, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relativePath)
+		if !strings.HasPrefix(key, strings.TrimPrefix(prefix, "./")) {
+			return nil
+		}
+
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, StoredFileInfo{Key: key, Size: fileInfo.Size(), ModifiedAt: fileInfo.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error on listing files: [%v], Error: %v", prefix, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+	return files, nil
+}
+
+// PresignedURL is not supported, the files on the disk of the service are only reached through the service
+func (s *LocalFileStore) PresignedURL(ctx context.Context, key string, expiresIn time.Duration) (string, error) {
+	return "", ErrPresignedURLNotSupported
+}
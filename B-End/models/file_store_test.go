@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reconciler.io/models/enums/file_storage_locations"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileStoreKeepsFilesUnderItsDirectory(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	store := NewLocalFileStore(directory)
+	assert.Equal(t, file_storage_locations.LocalFileSystem, store.Location())
+
+	assert.NoError(t, store.Put(ctx, "users/user_1/primary.csv", strings.NewReader("Id,Amount\n1,10\n")))
+	assert.NoError(t, store.Put(ctx, "./users/user_1/comparison.csv", strings.NewReader("Id,Amount\n")))
+	assert.NoError(t, store.Put(ctx, "users/user_10/primary.csv", strings.NewReader("Id\n")))
+	assert.FileExists(t, filepath.Join(directory, "users", "user_1", "primary.csv"))
+
+	file, err := store.Get(ctx, "users/user_1/primary.csv")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+	assert.Equal(t, "Id,Amount\n1,10\n", string(content))
+	assert.Equal(t, int64(15), file.Size)
+
+	files, err := store.List(ctx, "users/user_1/")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, "users/user_1/comparison.csv", files[0].Key)
+	assert.Equal(t, "users/user_1/primary.csv", files[1].Key)
+
+	files, err = store.List(ctx, "users/user_1")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	files, err = store.List(ctx, "users/nobody/")
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.NoError(t, store.Delete(ctx, "users/user_1/primary.csv"))
+	assert.NoError(t, store.Delete(ctx, "users/user_1/primary.csv"))
+	_, err = store.Get(ctx, "users/user_1/primary.csv")
+	assert.True(t, errors.Is(err, ErrFileNotFound))
+
+	_, err = store.PresignedURL(ctx, "users/user_1/comparison.csv", time.Minute)
+	assert.ErrorIs(t, err, ErrPresignedURLNotSupported)
+}
+
+func TestLocalFileStoreOnlyUsesKeysInsideItsDirectory(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalFileStore(t.TempDir())
+
+	for _, key := range []string{"", ".", "../outside.csv", "users/../../outside.csv", "/etc/outside.csv"} {
+		assert.Error(t, store.Put(ctx, key, strings.NewReader("")), key)
+	}
+}
+
+func TestLocalFileStoreLeavesNothingBehindWhenTheContentCantBeRead(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	store := NewLocalFileStore(directory)
+
+	err := store.Put(ctx, "results.csv", io.MultiReader(strings.NewReader("Id\n"), failingReader{}))
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(directory)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"reconciler.io/models/enums/recon_reason_code"
+)
+
+// ReconReason is why a row was given its recon result.
+// The columns and values are only set for a RowMismatchFound, where they are the column that differs
+// in each file and its value in each file. CounterpartRowNumber is the row of the comparison file
+// the row was matched to, nil when there is no such row.
+type ReconReason struct {
+	Code                 recon_reason_code.ReconReasonCode
+	PrimaryColumn        string  `json:",omitempty"`
+	ComparisonColumn     string  `json:",omitempty"`
+	PrimaryValue         string  `json:",omitempty"`
+	ComparisonValue      string  `json:",omitempty"`
+	CounterpartRowNumber *uint64 `json:",omitempty"`
+}
+
+// Text is how the reason of the given row of the primary file reads in the results file.
+func (r ReconReason) Text(rowNumber uint64) string {
+	switch r.Code {
+	case recon_reason_code.RowMatchFound:
+		return fmt.Sprintf(
+			"RowMatchFound. \n"+
+				"PrimaryFile Row: [%v] \n"+
+				"ComparisonFile Row: [%v] \n",
+			rowNumber,
+			r.counterpartRow(),
+		)
+	case recon_reason_code.RowMismatchFound:
+		return fmt.Sprintf(
+			"RowMismatchFound. \n"+
+				"PrimaryFileRow: [%v] PrimaryFileColumn: [%v] \n"+
+				"ComparisonFileRow: [%v] ComparisonFileColumn: [%v] \n"+
+				"PrimaryFile value: [%v] \n"+
+				"ComparisonFile value: [%v]\n",
+			rowNumber,
+			r.PrimaryColumn,
+			r.counterpartRow(),
+			r.ComparisonColumn,
+			r.PrimaryValue,
+			r.ComparisonValue,
+		)
+	case recon_reason_code.NoMatchFound:
+		return "no matching record found in the entire comparison file"
+	default:
+		return string(r.Code)
+	}
+}
+
+func (r ReconReason) counterpartRow() string {
+	if r.CounterpartRowNumber == nil {
+		return ""
+	}
+	return fmt.Sprint(*r.CounterpartRowNumber)
+}
+
+// ReconReasonTexts are the reasons of the given row of the primary file as they read in the results file.
+func ReconReasonTexts(rowNumber uint64, reasons []ReconReason) []string {
+	texts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		texts = append(texts, reason.Text(rowNumber))
+	}
+	return texts
+}
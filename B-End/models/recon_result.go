@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"strings"
+)
+
+// ReconResultRecorder is how the reconstruction keeps the result of every row of a task,
+// so that the results can be looked through without reading the results file.
+type ReconResultRecorder interface {
+	// DeleteReconResults removes the results of an earlier attempt at reconstructing the task.
+	DeleteReconResults(ctx context.Context, taskID string) error
+	SaveReconResults(ctx context.Context, taskID string, results []ReconResult) error
+}
+
+// ReconResult is how one row of the primary file was reconciled.
+// MatchedRowNumber is the row of the comparison file it was matched to,
+// nil when no row of the comparison file has the same identifiers.
+type ReconResult struct {
+	TaskID           string
+	RowNumber        uint64
+	Status           recon_status.ReconciliationStatus
+	Reasons          []ReconReason
+	MatchedRowNumber *uint64 `json:",omitempty"`
+	Values           []string
+}
+
+// NewReconResult is the result of a reconciled row of the task.
+func NewReconResult(taskID string, row FileSectionRow) ReconResult {
+	return ReconResult{
+		TaskID:           taskID,
+		RowNumber:        row.RowNumber,
+		Status:           row.ReconResult,
+		Reasons:          row.ReconResultReasons,
+		MatchedRowNumber: row.MatchedRowNumber,
+		Values:           row.ParsedColumnsFromRow,
+	}
+}
+
+// Mismatches are the reasons of the result that are a column differing from the matched row.
+func (r ReconResult) Mismatches() []ReconReason {
+	mismatches := make([]ReconReason, 0)
+	for _, reason := range r.Reasons {
+		if reason.Code == recon_reason_code.RowMismatchFound {
+			mismatches = append(mismatches, reason)
+		}
+	}
+	return mismatches
+}
+
+// HasDiffInColumn reports whether the value of the given column differs from the matched row,
+// the column can be named as it is in either file.
+func (r ReconResult) HasDiffInColumn(column string) bool {
+	for _, mismatch := range r.Mismatches() {
+		if mismatch.PrimaryColumn == column || mismatch.ComparisonColumn == column {
+			return true
+		}
+	}
+	return false
+}
+
+// HasValue reports whether the row, or the row it was matched to
+// in one of the columns that differ, has exactly the given value.
+func (r ReconResult) HasValue(value string) bool {
+	for _, searchableValue := range r.SearchableValues() {
+		if searchableValue == value {
+			return true
+		}
+	}
+	return false
+}
+
+// HasReasonCode reports whether one of the reasons of the result has the given code.
+func (r ReconResult) HasReasonCode(code recon_reason_code.ReconReasonCode) bool {
+	for _, reason := range r.Reasons {
+		if reason.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ReasonCodes are the codes of the reasons of the result.
+func (r ReconResult) ReasonCodes() []recon_reason_code.ReconReasonCode {
+	codes := make([]recon_reason_code.ReconReasonCode, 0, len(r.Reasons))
+	for _, reason := range r.Reasons {
+		codes = append(codes, reason.Code)
+	}
+	return codes
+}
+
+// HasReasonContaining reports whether the text of one of the reasons of the result
+// (see ReconReason.Text) contains the given text, ignoring case.
+func (r ReconResult) HasReasonContaining(text string) bool {
+	text = strings.ToLower(text)
+	for _, reasonText := range ReconReasonTexts(r.RowNumber, r.Reasons) {
+		if strings.Contains(strings.ToLower(reasonText), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchableValues are the values a result can be found by.
+func (r ReconResult) SearchableValues() []string {
+	values := append([]string{}, r.Values...)
+	for _, mismatch := range r.Mismatches() {
+		values = append(values, mismatch.PrimaryValue, mismatch.ComparisonValue)
+	}
+	return values
+}
+
+// DiffColumns are the names of the columns that differ, as they are in either file.
+func (r ReconResult) DiffColumns() []string {
+	mismatches := r.Mismatches()
+	columns := make([]string, 0, 2*len(mismatches))
+	for _, mismatch := range mismatches {
+		columns = append(columns, mismatch.PrimaryColumn, mismatch.ComparisonColumn)
+	}
+	return columns
+}
+
+// ReconResultsQuery picks out results of a task, the empty filters match every result.
+// Column matches results with a difference in that column,
+// Value matches results that have that value (see ReconResult.HasValue),
+// Reason matches results with a reason containing that text (see ReconResult.HasReasonContaining)
+// and ReasonCode results with a reason of that code.
+type ReconResultsQuery struct {
+	TaskID     string
+	Status     recon_status.ReconciliationStatus
+	Column     string
+	Value      string
+	Reason     string
+	ReasonCode recon_reason_code.ReconReasonCode
+	Offset     int
+	Limit      int
+}
+
+// ReconResultsPage is a page of the results that match a query, in row order.
+// TotalCount is how many results match the query across every page.
+type ReconResultsPage struct {
+	Results    []ReconResult
+	TotalCount int
+	Offset     int
+	Limit      int
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"reconciler.io/models/enums/recon_status"
+	"time"
+)
+
+// ReconSummary is how a reconciliation came out overall, worked out while the results are reconstructed.
+// ComparisonFileRows and MissingFromPrimaryFile are nil when the service that reconstructed the task
+// did not read the comparison file itself, so it could not count its rows.
+type ReconSummary struct {
+	PrimaryFileRows    int64
+	ComparisonFileRows *int64 `json:",omitempty"`
+	MatchedRows        int64
+	// MismatchedRows were matched to a row of the comparison file but have columns that differ
+	MismatchedRows int64
+	// MissingFromComparisonFile are rows of the primary file that no row of the comparison file was matched to
+	MissingFromComparisonFile int64
+	// MissingFromPrimaryFile are rows of the comparison file that no row of the primary file was matched to
+	MissingFromPrimaryFile *int64 `json:",omitempty"`
+	// DuplicateRows are rows of the primary file matched to a row of the comparison file
+	// that an earlier row of the primary file was matched to as well
+	DuplicateRows         int64
+	AmountTotals          []AmountTotal
+	TopMismatchingColumns []ColumnMismatchCount
+	GeneratedAt           time.Time
+}
+
+// AmountTotal is the sum of a column holding only numbers, over all the rows and the rows of each status.
+type AmountTotal struct {
+	Column         string
+	Total          float64
+	TotalsByStatus map[recon_status.ReconciliationStatus]float64
+}
+
+// ColumnMismatchCount is how many rows have a value in the column that differs from the row they were matched to.
+type ColumnMismatchCount struct {
+	Column string
+	Rows   int64
+}
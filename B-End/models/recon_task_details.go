@@ -1,13 +1,146 @@
 package models
 
+import (
+	"fmt"
+	"reconciler.io/models/enums/results_format"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/task_status"
+	"time"
+)
+
 type ReconTaskDetails struct {
 	ID                           string
 	UserID                       string
 	IsDone                       bool
 	HasBegun                     bool
+	IsPaused                     bool
+	Status                       task_status.TaskStatus
+	Phases                       map[task_status.TaskStatus]*TaskPhase `json:",omitempty"`
+	Error                        string                                `json:",omitempty"`
+	Progress                     *TaskProgress                         `json:",omitempty"`
+	CreatedAt                    time.Time
+	UpdatedAt                    time.Time
+	Version                      int
 	ComparisonPairs              []ComparisonPair
 	ReconConfig                  ReconciliationConfigs
-	FileToBeReconstructedChannel StreamProvider `json:"-"`
+	MaxConcurrentSections        int
+	PartitionCount               int
+	FileToBeReconstructedChannel StreamProvider         `json:"-"`
+	Checkpoints                  TaskCheckpointRecorder `json:"-"`
+	Control                      *TaskControl           `json:"-"`
+	ProgressTracker              *TaskProgressTracker   `json:"-"`
+	Events                       TaskEventPublisher     `json:"-"`
+	Results                      ReconResultRecorder    `json:"-"`
+	ResultTables                 ResultTableWriter      `json:"-"`
+	ResultStreams                StreamProvider         `json:"-"`
+	Files                        FileStore              `json:"-"`
 	PrimaryFileID                string
 	ComparisonFileID             string
+	ResultsFormats               []results_format.ResultsFormat `json:",omitempty"`
+	ResultSinks                  []ResultSinkConfig             `json:",omitempty"`
+	ResultsFilePath              string                         `json:",omitempty"`
+	Summary                      *ReconSummary                  `json:",omitempty"`
+	Webhooks                     []Webhook                      `json:",omitempty"`
+	NotificationEmails           []string                       `json:",omitempty"`
+}
+
+// TaskPhase is when a task entered and left one of its statuses.
+// EndedAt is nil while the task is still in that status.
+type TaskPhase struct {
+	StartedAt time.Time
+	EndedAt   *time.Time `json:",omitempty"`
+}
+
+// taskStatusTransitions are the statuses a task can move to from each status.
+// Any task that has not finished can fail or be cancelled.
+var taskStatusTransitions = map[task_status.TaskStatus][]task_status.TaskStatus{
+	task_status.Created:        {task_status.AwaitingFiles, task_status.Reading},
+	task_status.AwaitingFiles:  {task_status.Reading},
+	task_status.Reading:        {task_status.Reconciling},
+	task_status.Reconciling:    {task_status.Reconstructing},
+	task_status.Reconstructing: {task_status.Completed},
+}
+
+// CanTransitionTo reports whether the task is allowed to move to the given status.
+func (t *ReconTaskDetails) CanTransitionTo(status task_status.TaskStatus) bool {
+	if t.Status.IsTerminal() {
+		return false
+	}
+	if status == task_status.Failed || status == task_status.Cancelled {
+		return true
+	}
+	for _, allowed := range taskStatusTransitions[t.Status] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the task to the given status, closing the phase of
+// the status it is leaving and opening the phase of the new one.
+func (t *ReconTaskDetails) TransitionTo(status task_status.TaskStatus, now time.Time) error {
+	if !t.CanTransitionTo(status) {
+		return fmt.Errorf("task with ID [%v] can not move from [%v] to [%v]", t.ID, t.Status, status)
+	}
+
+	// copies of the task handed out earlier share the
+	// old phases, so they are copied rather than changed
+	phases := make(map[task_status.TaskStatus]*TaskPhase, len(t.Phases)+1)
+	for phaseStatus, phase := range t.Phases {
+		phaseCopy := *phase
+		phases[phaseStatus] = &phaseCopy
+	}
+	if phase, exists := phases[t.Status]; exists && phase.EndedAt == nil {
+		phase.EndedAt = &now
+	}
+
+	phases[status] = &TaskPhase{StartedAt: now}
+	if status.IsTerminal() {
+		phases[status].EndedAt = &now
+	}
+
+	t.Status = status
+	t.Phases = phases
+	t.UpdatedAt = now
+
+	t.SetStatusFlags()
+	if status.IsTerminal() {
+		t.IsPaused = false
+	}
+	return nil
+}
+
+// SetStatusFlags sets IsDone and HasBegun from the status and phases of the task,
+// the flags are only kept for clients that still look at them.
+func (t *ReconTaskDetails) SetStatusFlags() {
+	_, hasReconciled := t.Phases[task_status.Reconciling]
+	t.HasBegun = hasReconciled
+	t.IsDone = t.Status == task_status.Completed
+}
+
+// Fail moves the task to Failed and records why.
+func (t *ReconTaskDetails) Fail(err error, now time.Time) error {
+	transitionErr := t.TransitionTo(task_status.Failed, now)
+	if transitionErr != nil {
+		return transitionErr
+	}
+	t.Error = err.Error()
+	return nil
+}
+
+// PublishEvent publishes an event of the task along with how far the task has got.
+func (t *ReconTaskDetails) PublishEvent(eventType task_event_type.TaskEventType, fileID string, sectionSequenceNumber int) {
+	if t.Events == nil {
+		return
+	}
+	now := time.Now()
+	t.Events.Publish(TaskEvent{
+		TaskID:                t.ID,
+		Type:                  eventType,
+		FileID:                fileID,
+		SectionSequenceNumber: sectionSequenceNumber,
+		Progress:              t.ProgressTracker.Snapshot(now),
+		OccurredAt:            now,
+	})
 }
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"reconciler.io/models/enums/task_status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconTaskDetailsTransitionsThroughEveryPhase(t *testing.T) {
+	task := ReconTaskDetails{ID: "task_1", Status: task_status.Created}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	statuses := []task_status.TaskStatus{
+		task_status.AwaitingFiles,
+		task_status.Reading,
+		task_status.Reconciling,
+		task_status.Reconstructing,
+		task_status.Completed,
+	}
+	for i, status := range statuses {
+		assert.NoError(t, task.TransitionTo(status, start.Add(time.Duration(i+1)*time.Minute)))
+	}
+
+	assert.Equal(t, task_status.Completed, task.Status)
+	assert.True(t, task.IsDone)
+	assert.True(t, task.HasBegun)
+
+	reconciling := task.Phases[task_status.Reconciling]
+	assert.Equal(t, start.Add(3*time.Minute), reconciling.StartedAt)
+	assert.Equal(t, start.Add(4*time.Minute), *reconciling.EndedAt)
+	assert.Equal(t, start.Add(5*time.Minute), *task.Phases[task_status.Completed].EndedAt)
+}
+
+func TestReconTaskDetailsRejectsInvalidTransitions(t *testing.T) {
+	task := ReconTaskDetails{ID: "task_1", Status: task_status.Created}
+
+	assert.Error(t, task.TransitionTo(task_status.Reconciling, time.Now()))
+	assert.Equal(t, task_status.Created, task.Status)
+
+	assert.NoError(t, task.TransitionTo(task_status.Cancelled, time.Now()))
+	assert.Error(t, task.TransitionTo(task_status.Reading, time.Now()))
+	assert.Error(t, task.Fail(errors.New("too late"), time.Now()))
+	assert.Empty(t, task.Error)
+}
+
+func TestReconTaskDetailsFailRecordsTheError(t *testing.T) {
+	task := ReconTaskDetails{ID: "task_1", Status: task_status.Reading}
+	copyBeforeFailing := task
+
+	assert.NoError(t, task.Fail(errors.New("file not found"), time.Now()))
+	assert.Equal(t, task_status.Failed, task.Status)
+	assert.Equal(t, "file not found", task.Error)
+	assert.Nil(t, copyBeforeFailing.Phases)
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"reconciler.io/constants"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/result_sink_type"
+	"regexp"
+	"strings"
+)
+
+// ResultSinkConfig is somewhere besides its results files that the results of a task are sent to as they are reconstructed.
+// Table is the table of the database a sql sink inserts the results into,
+// Subject is the NATS subject a stream sink publishes them on.
+type ResultSinkConfig struct {
+	Type    result_sink_type.ResultSinkType
+	Table   string `json:",omitempty"`
+	Subject string `json:",omitempty"`
+}
+
+// ResultTableWriter writes the results of tasks into tables of the database for other services to read.
+type ResultTableWriter interface {
+	// PrepareResultTable creates the table if it doesn't exist yet
+	// and removes the results of any earlier attempt at the task from it.
+	PrepareResultTable(ctx context.Context, table string, taskID string) error
+	// InsertResults inserts the results into the table, the values of each
+	// keyed by the column headers of the primary file.
+	InsertResults(ctx context.Context, table string, columnHeaders []string, results []ReconResult) error
+}
+
+// resultTableNamePattern is a table name that can be used in a query without quoting
+var resultTableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// serviceTables are the tables the service keeps its own records in
+var serviceTables = map[string]bool{
+	"schema_migrations": true,
+	"recon_task_ids":    true,
+	"recon_tasks":       true,
+	"files_to_be_read":  true,
+	"recon_results":     true,
+}
+
+// serviceSubjectPrefixes start the subjects the service streams the sections of its files and tasks on
+var serviceSubjectPrefixes = []string{
+	string(file_purpose.PrimaryFile) + "-",
+	string(file_purpose.ComparisonFile) + "-",
+	"Reconstruct-",
+}
+
+// ValidateResultSinks checks every sink says where its results go, and that no two send them to the same place.
+func ValidateResultSinks(sinks []ResultSinkConfig) error {
+	seen := make(map[ResultSinkConfig]bool, len(sinks))
+	for _, sink := range sinks {
+		var err error
+		switch sink.Type {
+		case result_sink_type.Sql:
+			err = validateResultTable(sink.Table)
+		case result_sink_type.Stream:
+			err = validateResultSubject(sink.Subject)
+		default:
+			err = fmt.Errorf("results can not be sent to a [%v] sink", sink.Type)
+		}
+		if err != nil {
+			return err
+		}
+
+		if seen[sink] {
+			return fmt.Errorf("results are sent to the %v sink [%v%v] more than once", sink.Type, sink.Table, sink.Subject)
+		}
+		seen[sink] = true
+	}
+	return nil
+}
+
+func validateResultTable(table string) error {
+	if constants.DATABASE_DRIVER == "memory" {
+		return fmt.Errorf("results can only be sent to a table when the service has a database")
+	}
+	if !resultTableNamePattern.MatchString(table) {
+		return fmt.Errorf("result table [%v] must start with a letter or underscore and have only letters, digits and underscores", table)
+	}
+	if serviceTables[strings.ToLower(table)] {
+		return fmt.Errorf("result table [%v] is one of the tables of the service", table)
+	}
+	return nil
+}
+
+// validateResultSubject checks the subject is a single subject rather than a wildcard,
+// and that it is neither a subject of NATS itself ($JS, $SYS...) nor one of the subjects of the service.
+// Its results summary is published on the subject under it
+func validateResultSubject(subject string) error {
+	if len(subject) == 0 {
+		return fmt.Errorf("results can not be published without a subject")
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if len(token) == 0 || token == "*" || token == ">" || strings.ContainsAny(token, " \t\r\n") {
+			return fmt.Errorf("results can not be published on subject [%v]", subject)
+		}
+	}
+	if strings.HasPrefix(subject, "$") {
+		return fmt.Errorf("results can not be published on subject [%v] reserved by NATS", subject)
+	}
+	for _, prefix := range serviceSubjectPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return fmt.Errorf("results can not be published on subject [%v] used by the service", subject)
+		}
+	}
+	return nil
+}
+
+// ResultSummarySubject is the subject the summary of the results published on the subject is published on,
+// once every result has been.
+func ResultSummarySubject(subject string) string {
+	return subject + ".summary"
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reconciler.io/constants"
+	"reconciler.io/models/enums/result_sink_type"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResultSinks(t *testing.T) {
+	driver := constants.DATABASE_DRIVER
+	constants.DATABASE_DRIVER = "sqlite"
+	defer func() { constants.DATABASE_DRIVER = driver }()
+
+	assert.NoError(t, ValidateResultSinks(nil))
+	assert.NoError(t, ValidateResultSinks([]ResultSinkConfig{
+		{Type: result_sink_type.Sql, Table: "settlement_results"},
+		{Type: result_sink_type.Stream, Subject: "recon.results.settlements"},
+		{Type: result_sink_type.Stream, Subject: "recon.results.audit"},
+	}))
+
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: "kafka", Subject: "results"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Sql, Table: "results; DROP TABLE recon_tasks"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Sql, Table: "Recon_Tasks"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "recon.*"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "recon..results"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "$JS.API.STREAM.DELETE.recon-results-stream"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "$SYS.REQ.SERVER.PING"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "Reconstruct-task_2"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "PrimaryFile-1-Partition-0"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "ComparisonFile-1"}}))
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{
+		{Type: result_sink_type.Sql, Table: "settlement_results"},
+		{Type: result_sink_type.Sql, Table: "settlement_results"},
+	}))
+}
+
+func TestResultsCanOnlyBeSentToATableWithADatabase(t *testing.T) {
+	driver := constants.DATABASE_DRIVER
+	constants.DATABASE_DRIVER = "memory"
+	defer func() { constants.DATABASE_DRIVER = driver }()
+
+	assert.Error(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Sql, Table: "settlement_results"}}))
+	assert.NoError(t, ValidateResultSinks([]ResultSinkConfig{{Type: result_sink_type.Stream, Subject: "recon.results"}}))
+}
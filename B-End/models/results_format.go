@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"reconciler.io/models/enums/results_format"
+)
+
+// resultsFormats are the formats the results of a task can be written in
+var resultsFormats = map[results_format.ResultsFormat]bool{
+	results_format.Csv:        true,
+	results_format.Xlsx:       true,
+	results_format.Html:       true,
+	results_format.Pdf:        true,
+	results_format.SideBySide: true,
+	results_format.JsonLines:  true,
+}
+
+// ValidateResultsFormats checks every format can be written, and is only asked for once.
+func ValidateResultsFormats(formats []results_format.ResultsFormat) error {
+	seen := make(map[results_format.ResultsFormat]bool, len(formats))
+	for _, format := range formats {
+		if !resultsFormats[format] {
+			return fmt.Errorf("results can not be written as [%v]", format)
+		}
+		if seen[format] {
+			return fmt.Errorf("results format [%v] is asked for more than once", format)
+		}
+		seen[format] = true
+	}
+	return nil
+}
+
+// ResultsFormatsToWrite returns the formats the results of the task are written in,
+// only the csv when the task didn't pick any.
+func (t ReconTaskDetails) ResultsFormatsToWrite() []results_format.ResultsFormat {
+	if len(t.ResultsFormats) == 0 {
+		return []results_format.ResultsFormat{results_format.Csv}
+	}
+	return t.ResultsFormats
+}
+
+// WritesResultsIn reports whether the results of the task are written in the given format.
+func (t ReconTaskDetails) WritesResultsIn(format results_format.ResultsFormat) bool {
+	for _, resultsFormat := range t.ResultsFormatsToWrite() {
+		if resultsFormat == format {
+			return true
+		}
+	}
+	return false
+}
+
+// MainResultsFormat is the format of the file at ResultsFilePath.
+// It is the csv whenever the task writes one, since the emails and json downloads are read from it.
+func (t ReconTaskDetails) MainResultsFormat() results_format.ResultsFormat {
+	if t.WritesResultsIn(results_format.Csv) {
+		return results_format.Csv
+	}
+	return t.ResultsFormatsToWrite()[0]
+}
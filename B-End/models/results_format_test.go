@@ -0,0 +1,33 @@
+package models
+
+import (
+	"reconciler.io/models/enums/results_format"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultsAreOnlyWrittenAsCsvWhenNoFormatIsPicked(t *testing.T) {
+	task := ReconTaskDetails{ID: "task_1"}
+
+	assert.Equal(t, []results_format.ResultsFormat{results_format.Csv}, task.ResultsFormatsToWrite())
+	assert.True(t, task.WritesResultsIn(results_format.Csv))
+	assert.False(t, task.WritesResultsIn(results_format.Xlsx))
+	assert.Equal(t, results_format.Csv, task.MainResultsFormat())
+}
+
+func TestMainResultsFormatIsTheCsvWheneverItIsWritten(t *testing.T) {
+	alongside := ReconTaskDetails{ResultsFormats: []results_format.ResultsFormat{results_format.Xlsx, results_format.Csv}}
+	inPlace := ReconTaskDetails{ResultsFormats: []results_format.ResultsFormat{results_format.Xlsx}}
+
+	assert.Equal(t, results_format.Csv, alongside.MainResultsFormat())
+	assert.Equal(t, results_format.Xlsx, inPlace.MainResultsFormat())
+	assert.False(t, inPlace.WritesResultsIn(results_format.Csv))
+}
+
+func TestValidateResultsFormats(t *testing.T) {
+	assert.NoError(t, ValidateResultsFormats(nil))
+	assert.NoError(t, ValidateResultsFormats([]results_format.ResultsFormat{results_format.Csv, results_format.Xlsx, results_format.Pdf}))
+	assert.Error(t, ValidateResultsFormats([]results_format.ResultsFormat{"docx"}))
+	assert.Error(t, ValidateResultsFormats([]results_format.ResultsFormat{results_format.Xlsx, results_format.Xlsx}))
+}
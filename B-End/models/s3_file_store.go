@@ -0,0 +1,462 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reconciler.io/models/enums/file_storage_locations"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+	// s3MaxPresignedURLExpiry is the longest a presigned URL can work for
+	s3MaxPresignedURLExpiry = 7 * 24 * time.Hour
+)
+
+// S3FileStoreConfig is the S3-compatible service and bucket an S3FileStore keeps its files in.
+// HTTPClient is the client the requests are sent with, the default client when nil.
+type S3FileStoreConfig struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	HTTPClient      *http.Client
+}
+
+// S3FileStore keeps the files as the objects of a bucket of an S3-compatible service (AWS S3, MinIO...),
+// the key of a file is the key of its object. The bucket is addressed by path so that any service
+// reached by an endpoint of its own works, and requests are signed with AWS Signature Version 4.
+// A file is stored with a single request, so it can be at most 5 GiB.
+type S3FileStore struct {
+	endpoint *url.URL
+	bucket   string
+	signer   s3Signer
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3FileStore(config S3FileStoreConfig) (*S3FileStore, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint [%v] is not an http(s) URL", config.Endpoint)
+	}
+	if len(config.Bucket) == 0 {
+		return nil, fmt.Errorf("S3 file store has no bucket")
+	}
+	if len(config.Region) == 0 || len(config.AccessKeyID) == 0 || len(config.SecretAccessKey) == 0 {
+		return nil, fmt.Errorf("S3 file store needs a region, an access key ID and a secret access key")
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &S3FileStore{
+		endpoint: endpoint,
+		bucket:   config.Bucket,
+		signer: s3Signer{
+			accessKeyID:     config.AccessKeyID,
+			secretAccessKey: config.SecretAccessKey,
+			region:          config.Region,
+		},
+		client: client,
+		now:    time.Now,
+	}, nil
+}
+
+func (s *S3FileStore) Location() file_storage_locations.FileStorageLocation {
+	return file_storage_locations.S3FileSystem
+}
+
+// objectURL is the URL of the object with the key, or of the bucket when the key is empty
+func (s *S3FileStore) objectURL(key string, query url.Values) *url.URL {
+	objectPath := strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.bucket + "/" + key
+	if len(key) == 0 {
+		objectPath = strings.TrimSuffix(objectPath, "/")
+	}
+
+	objectURL := *s.endpoint
+	objectURL.Path = objectPath
+	objectURL.RawPath = s3Escape(objectPath, false)
+	objectURL.RawQuery = s3CanonicalQuery(query)
+	return &objectURL
+}
+
+// send signs the request and sends it, giving back the response only when the service says it succeeded
+func (s *S3FileStore) send(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.signRequest(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrFileNotFound
+	}
+	return nil, readS3Error(resp)
+}
+
+func (s *S3FileStore) Put(ctx context.Context, key string, content io.Reader) error {
+	cleaned, err := cleanFileKey(key)
+	if err != nil {
+		return err
+	}
+
+	body, size, err := sizedContent(content)
+	if err != nil {
+		return fmt.Errorf("error on writing file: [%v], Error: %v", key, err)
+	}
+	defer body.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(cleaned, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	// without a length the body would be sent in chunks, which S3 only takes when each is signed
+	req.ContentLength = size
+	req.Body = http.NoBody
+	if size > 0 {
+		req.Body = io.NopCloser(body)
+	}
+
+	resp, err := s.send(req, s3UnsignedPayload)
+	if err != nil {
+		return fmt.Errorf("error on writing file: [%v], Error: %v", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3FileStore) Get(ctx context.Context, key string) (*StoredFile, error) {
+	cleaned, err := cleanFileKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(cleaned, nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.send(req, s3UnsignedPayload)
+	if errors.Is(err, ErrFileNotFound) {
+		return nil, fmt.Errorf("%w: [%v]", ErrFileNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error on reading file: [%v], Error: %v", key, err)
+	}
+	return &StoredFile{ReadCloser: resp.Body, Size: resp.ContentLength}, nil
+}
+
+func (s *S3FileStore) Delete(ctx context.Context, key string) error {
+	cleaned, err := cleanFileKey(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(cleaned, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.send(req, s3UnsignedPayload)
+	if errors.Is(err, ErrFileNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error on deleting file: [%v], Error: %v", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3ListBucketResult is the page of objects a ListObjectsV2 request returns
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List asks for the objects a page at a time until the service says there are no more
+func (s *S3FileStore) List(ctx context.Context, prefix string) ([]StoredFileInfo, error) {
+	files := make([]StoredFileInfo, 0)
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {strings.TrimPrefix(prefix, "./")}}
+		if len(continuationToken) > 0 {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL("", query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.send(req, s3UnsignedPayload)
+		if err != nil {
+			return nil, fmt.Errorf("error on listing files: [%v], Error: %v", prefix, err)
+		}
+
+		var page s3ListBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error on listing files: [%v], Error: %v", prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			files = append(files, StoredFileInfo{Key: object.Key, Size: object.Size, ModifiedAt: object.LastModified})
+		}
+		if !page.IsTruncated || len(page.NextContinuationToken) == 0 {
+			return files, nil
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+func (s *S3FileStore) PresignedURL(ctx context.Context, key string, expiresIn time.Duration) (string, error) {
+	cleaned, err := cleanFileKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expiresIn < time.Second || expiresIn > s3MaxPresignedURLExpiry {
+		return "", fmt.Errorf("presigned URLs can work for a second up to %v, not [%v]", s3MaxPresignedURLExpiry, expiresIn)
+	}
+	return s.signer.presign(s.objectURL(cleaned, nil), expiresIn, s.now()), nil
+}
+
+// sizedContent gives the content along with how long it is, content that can't be
+// measured without reading it is read into a temporary file first
+func sizedContent(content io.Reader) (io.ReadCloser, int64, error) {
+	if seeker, isSeeker := content.(io.Seeker); isSeeker {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, 0, err
+			}
+			_, err = seeker.Seek(start, io.SeekStart)
+			if err != nil {
+				return nil, 0, err
+			}
+			return io.NopCloser(content), end - start, nil
+		}
+	}
+
+	spool, err := os.CreateTemp("", "file-store-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	removeSpool := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+
+	size, err := io.Copy(spool, content)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeSpool()
+		return nil, 0, err
+	}
+	return &spooledContent{File: spool, remove: removeSpool}, size, nil
+}
+
+// spooledContent is removed once it has been read
+type spooledContent struct {
+	*os.File
+	remove func()
+}
+
+func (c *spooledContent) Close() error {
+	c.remove()
+	return nil
+}
+
+// readS3Error reads the code and message of the error the service responded with
+func readS3Error(resp *http.Response) error {
+	var s3Error struct {
+		Code    string
+		Message string
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(body, &s3Error) != nil || len(s3Error.Code) == 0 {
+		return fmt.Errorf("S3 responded with status [%v]", resp.StatusCode)
+	}
+	return fmt.Errorf("S3 responded with status [%v]: %v %v", resp.StatusCode, s3Error.Code, s3Error.Message)
+}
+
+// s3Signer signs requests to S3 with AWS Signature Version 4
+type s3Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	region          string
+}
+
+// signRequest signs the host and every header of the request, along with the hash of its payload
+func (s s3Signer) signRequest(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalHeaders, signedHeaders := s3CanonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(now)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%v Credential=%v/%v,SignedHeaders=%v,Signature=%v",
+		s3Algorithm,
+		s.accessKeyID,
+		scope,
+		signedHeaders,
+		s.signature(now, scope, canonicalRequest),
+	))
+}
+
+// presign is the URL with the signature of a GET of it in its query, which works for expiresIn
+func (s s3Signer) presign(u *url.URL, expiresIn time.Duration, now time.Time) string {
+	now = now.UTC()
+	scope := s.scope(now)
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.accessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiresIn/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		s3CanonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, scope, canonicalRequest))
+
+	presigned := *u
+	presigned.RawQuery = s3CanonicalQuery(query)
+	return presigned.String()
+}
+
+func (s s3Signer) scope(now time.Time) string {
+	return now.Format(s3DateFormat) + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s s3Signer) signature(now time.Time, scope string, canonicalRequest string) string {
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, now.Format(s3TimeFormat), scope, hex.EncodeToString(hashedRequest[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format(s3DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalHeaders lists the host and the headers of the request by their lower-cased name,
+// a line each, along with their names separated by semicolons
+func s3CanonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// s3CanonicalQuery is the query sorted by name with every name and value escaped
+func s3CanonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameters := make([]string, 0, len(query))
+	for _, name := range names {
+		values := append([]string{}, query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parameters = append(parameters, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parameters, "&")
+}
+
+// s3Escape percent-encodes every byte but the unreserved characters,
+// and the slashes unless they are escaped too
+func s3Escape(value string, escapeSlash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		isUnreserved := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~'
+		if isUnreserved || (c == '/' && !escapeSlash) {
+			escaped.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&escaped, "%%%02X", c)
+	}
+	return escaped.String()
+}
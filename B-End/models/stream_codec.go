@@ -0,0 +1,254 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/nats-io/nats.go"
+	"github.com/vmihailenco/msgpack/v5"
+	"strings"
+	"sync"
+)
+
+// headers used to tell consumers how a message was encoded,
+// so every consumer can decode whatever the publisher chose
+const (
+	StreamCodecHeader       = "Reconciler-Codec"
+	StreamCompressionHeader = "Reconciler-Compression"
+)
+
+const (
+	JsonStreamCodec    = "json"
+	MsgPackStreamCodec = "msgpack"
+
+	NoStreamCompression   = "none"
+	ZstdStreamCompression = "zstd"
+	S2StreamCompression   = "s2"
+)
+
+// StreamCodec turns messages into bytes and back.
+type StreamCodec interface {
+	Name() string
+	Marshal(data interface{}) ([]byte, error)
+	Unmarshal(encoded []byte, out interface{}) error
+}
+
+// StreamCompressor compresses already encoded messages.
+type StreamCompressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// StreamEncoding is the codec and compression a StreamProvider publishes with.
+type StreamEncoding struct {
+	Codec       string
+	Compression string
+}
+
+// describeStreamEncoding is the description a stream records its encoding in
+func describeStreamEncoding(encoding StreamEncoding) string {
+	return fmt.Sprintf("codec=%v compression=%v", encoding.Codec, encoding.Compression)
+}
+
+// parseStreamEncoding reads the encoding out of the description of a stream,
+// streams created before they recorded one are published to as plain json
+func parseStreamEncoding(description string) StreamEncoding {
+	encoding := StreamEncoding{Codec: JsonStreamCodec, Compression: NoStreamCompression}
+	for _, field := range strings.Fields(description) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "codec":
+			encoding.Codec = value
+		case "compression":
+			encoding.Compression = value
+		}
+	}
+	return encoding
+}
+
+var (
+	streamCodecs = map[string]StreamCodec{
+		JsonStreamCodec:    jsonStreamCodec{},
+		MsgPackStreamCodec: msgPackStreamCodec{},
+	}
+	streamCompressors = map[string]StreamCompressor{
+		NoStreamCompression:   noStreamCompressor{},
+		ZstdStreamCompression: &zstdStreamCompressor{},
+		S2StreamCompression:   s2StreamCompressor{},
+	}
+	streamCodecsMutex sync.RWMutex
+)
+
+// RegisterStreamCodec makes a codec available to every StreamProvider.
+func RegisterStreamCodec(codec StreamCodec) {
+	streamCodecsMutex.Lock()
+	defer streamCodecsMutex.Unlock()
+	streamCodecs[codec.Name()] = codec
+}
+
+// RegisterStreamCompressor makes a compressor available to every StreamProvider.
+func RegisterStreamCompressor(compressor StreamCompressor) {
+	streamCodecsMutex.Lock()
+	defer streamCodecsMutex.Unlock()
+	streamCompressors[compressor.Name()] = compressor
+}
+
+// EncodeStreamMessage encodes and compresses data and returns the
+// headers a consumer needs to decode it again.
+func EncodeStreamMessage(encoding StreamEncoding, data interface{}) ([]byte, nats.Header, error) {
+	codec, compressor, err := lookupStreamEncoding(encoding.Codec, encoding.Compression)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoded, err := codec.Marshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compressed, err := compressor.Compress(encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := nats.Header{}
+	header.Set(StreamCodecHeader, codec.Name())
+	header.Set(StreamCompressionHeader, compressor.Name())
+	return compressed, header, nil
+}
+
+// DecodeStreamMessage decodes a message using the codec and compression named in its headers.
+// Messages without headers were published before codecs existed and are plain json.
+func DecodeStreamMessage(header nats.Header, data []byte, out interface{}) error {
+	codecName := JsonStreamCodec
+	compressionName := NoStreamCompression
+	if header != nil {
+		if value := header.Get(StreamCodecHeader); value != "" {
+			codecName = value
+		}
+		if value := header.Get(StreamCompressionHeader); value != "" {
+			compressionName = value
+		}
+	}
+
+	codec, compressor, err := lookupStreamEncoding(codecName, compressionName)
+	if err != nil {
+		return err
+	}
+
+	decompressed, err := compressor.Decompress(data)
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(decompressed, out)
+}
+
+func lookupStreamEncoding(codecName string, compressionName string) (StreamCodec, StreamCompressor, error) {
+	streamCodecsMutex.RLock()
+	defer streamCodecsMutex.RUnlock()
+
+	if codecName == "" {
+		codecName = JsonStreamCodec
+	}
+	if compressionName == "" {
+		compressionName = NoStreamCompression
+	}
+
+	codec, exists := streamCodecs[codecName]
+	if !exists {
+		return nil, nil, fmt.Errorf("unsupported stream codec: [%v]", codecName)
+	}
+
+	compressor, exists := streamCompressors[compressionName]
+	if !exists {
+		return nil, nil, fmt.Errorf("unsupported stream compression: [%v]", compressionName)
+	}
+
+	return codec, compressor, nil
+}
+
+type jsonStreamCodec struct{}
+
+func (jsonStreamCodec) Name() string { return JsonStreamCodec }
+
+func (jsonStreamCodec) Marshal(data interface{}) ([]byte, error) { return json.Marshal(data) }
+
+func (jsonStreamCodec) Unmarshal(encoded []byte, out interface{}) error {
+	return json.Unmarshal(encoded, out)
+}
+
+// msgPackStreamCodec reads the json struct tags so that
+// fields left out of json messages are left out here too
+type msgPackStreamCodec struct{}
+
+func (msgPackStreamCodec) Name() string { return MsgPackStreamCodec }
+
+func (msgPackStreamCodec) Marshal(data interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	err := encoder.Encode(data)
+	return buffer.Bytes(), err
+}
+
+func (msgPackStreamCodec) Unmarshal(encoded []byte, out interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(encoded))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(out)
+}
+
+type noStreamCompressor struct{}
+
+func (noStreamCompressor) Name() string { return NoStreamCompression }
+
+func (noStreamCompressor) Compress(data []byte) ([]byte, error) { return data, nil }
+
+func (noStreamCompressor) Decompress(data []byte) ([]byte, error) { return data, nil }
+
+type s2StreamCompressor struct{}
+
+func (s2StreamCompressor) Name() string { return S2StreamCompression }
+
+func (s2StreamCompressor) Compress(data []byte) ([]byte, error) { return s2.Encode(nil, data), nil }
+
+func (s2StreamCompressor) Decompress(data []byte) ([]byte, error) { return s2.Decode(nil, data) }
+
+// zstdStreamCompressor lazily creates a single encoder and decoder,
+// both are safe for concurrent use through EncodeAll and DecodeAll
+type zstdStreamCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	once    sync.Once
+	err     error
+}
+
+func (c *zstdStreamCompressor) Name() string { return ZstdStreamCompression }
+
+func (c *zstdStreamCompressor) Compress(data []byte) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+func (c *zstdStreamCompressor) Decompress(data []byte) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return c.decoder.DecodeAll(data, nil)
+}
+
+func (c *zstdStreamCompressor) init() error {
+	c.once.Do(func() {
+		c.encoder, c.err = zstd.NewWriter(nil)
+		if c.err != nil {
+			return
+		}
+		c.decoder, c.err = zstd.NewReader(nil)
+	})
+	return c.err
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_status"
+	"testing"
+)
+
+func TestStreamMessageRoundTrip(t *testing.T) {
+	section := FileSection{
+		ID:                    "1234",
+		TaskID:                "task_1",
+		FileID:                "random-file-id",
+		SectionSequenceNumber: 1,
+		OriginalFilePurpose:   file_purpose.PrimaryFile,
+		SectionRows: []FileSectionRow{
+			{
+				RowNumber:            3,
+				ParsedColumnsFromRow: []string{"TXN-001", "100"},
+				ReconResult:          recon_status.Pending,
+				ReconResultReasons:   []ReconReason{},
+			},
+		},
+		ColumnHeaders: []string{"id", "amount"},
+		IsLastSection: true,
+	}
+
+	for _, codec := range []string{JsonStreamCodec, MsgPackStreamCodec} {
+		for _, compression := range []string{NoStreamCompression, ZstdStreamCompression, S2StreamCompression} {
+			encoding := StreamEncoding{Codec: codec, Compression: compression}
+
+			encoded, header, err := EncodeStreamMessage(encoding, &section)
+			assert.NoError(t, err)
+			assert.Equal(t, codec, header.Get(StreamCodecHeader))
+			assert.Equal(t, compression, header.Get(StreamCompressionHeader))
+
+			var decoded FileSection
+			err = DecodeStreamMessage(header, encoded, &decoded)
+			assert.NoError(t, err)
+			assert.Equal(t, section, decoded, "codec: %v, compression: %v", codec, compression)
+		}
+	}
+}
+
+func TestStreamMessageLeavesOutTaskMetadata(t *testing.T) {
+	section := FileSection{
+		ID:              "1234",
+		ComparisonPairs: []ComparisonPair{{PrimaryFileColumnIndex: 1, IsRowIdentifier: true}},
+		ReconConfig:     ReconciliationConfigs{ShouldIgnoreWhiteSpace: true},
+	}
+
+	for _, codec := range []string{JsonStreamCodec, MsgPackStreamCodec} {
+		encoded, header, err := EncodeStreamMessage(StreamEncoding{Codec: codec}, &section)
+		assert.NoError(t, err)
+
+		var decoded FileSection
+		err = DecodeStreamMessage(header, encoded, &decoded)
+		assert.NoError(t, err)
+		assert.Nil(t, decoded.ComparisonPairs)
+		assert.Equal(t, ReconciliationConfigs{}, decoded.ReconConfig)
+
+		decoded.AttachTaskMetadata(ReconTaskDetails{
+			ComparisonPairs: section.ComparisonPairs,
+			ReconConfig:     section.ReconConfig,
+		})
+		assert.Equal(t, section, decoded)
+	}
+}
+
+func TestDecodeStreamMessageWithoutHeadersIsJson(t *testing.T) {
+	var decoded FileSection
+	err := DecodeStreamMessage(nil, []byte(`{"ID":"1234","SectionSequenceNumber":2}`), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", decoded.ID)
+	assert.Equal(t, 2, decoded.SectionSequenceNumber)
+}
+
+func TestUnsupportedStreamEncoding(t *testing.T) {
+	_, _, err := EncodeStreamMessage(StreamEncoding{Codec: "xml"}, FileSection{})
+	assert.Error(t, err)
+}
+
+func TestStreamEncodingIsRecordedInTheStreamDescription(t *testing.T) {
+	encoding := StreamEncoding{Codec: MsgPackStreamCodec, Compression: S2StreamCompression}
+	assert.Equal(t, encoding, parseStreamEncoding(describeStreamEncoding(encoding)))
+
+	// streams created before they recorded an encoding are plain json
+	assert.Equal(t, StreamEncoding{Codec: JsonStreamCodec, Compression: NoStreamCompression}, parseStreamEncoding(""))
+}
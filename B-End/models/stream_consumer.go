@@ -1,13 +1,25 @@
 package models
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"time"
 )
 
+// fetchTimeout is how long FetchNext waits for a message before giving up,
+// fetchPollInterval is how often FetchNextWithContext checks its context while waiting
+var fetchTimeout = 30 * time.Second
+var fetchPollInterval = 1 * time.Second
+
+// ErrFetchTimeout is returned when no FileSection arrived within the fetch timeout
+var ErrFetchTimeout = errors.New("timed out waiting for the next FileSection")
+
 type StreamConsumer interface {
 	FetchNext() (*FileSection, error)
+	FetchNextWithContext(ctx context.Context) (*FileSection, error)
 }
 
 type NatsFileSectionsStreamConsumer struct {
@@ -21,14 +33,37 @@ func NewFileSectionsStreamConsumer(consumer jetstream.Consumer) *NatsFileSection
 }
 
 func (sc *NatsFileSectionsStreamConsumer) FetchNext() (*FileSection, error) {
-	msg, err := sc.natsConsumer.Next()
+	return sc.FetchNextWithContext(context.Background())
+}
 
-	if err != nil {
-		err := fmt.Errorf("error getting Next FileSection: %v", err)
-		return nil, err
+// FetchNextWithContext waits for the next FileSection like FetchNext
+// but stops waiting as soon as the context is done.
+func (sc *NatsFileSectionsStreamConsumer) FetchNextWithContext(ctx context.Context) (*FileSection, error) {
+	giveUpAt := time.Now().Add(fetchTimeout)
+	for {
+		msg, err := sc.natsConsumer.Next(jetstream.FetchMaxWait(fetchPollInterval))
+
+		if err == nil {
+			return decodeFileSection(msg)
+		}
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("error getting Next FileSection: %w", ctx.Err())
+		}
+
+		if !errors.Is(err, nats.ErrTimeout) {
+			err := fmt.Errorf("error getting Next FileSection: %v", err)
+			return nil, err
+		}
+
+		if time.Now().After(giveUpAt) {
+			return nil, fmt.Errorf("error getting Next FileSection: %w", ErrFetchTimeout)
+		}
 	}
+}
 
-	err = msg.Ack()
+func decodeFileSection(msg jetstream.Msg) (*FileSection, error) {
+	err := msg.Ack()
 
 	if err != nil {
 		err := fmt.Errorf("error on ACK of FileSection: %v", err)
@@ -37,10 +72,10 @@ func (sc *NatsFileSectionsStreamConsumer) FetchNext() (*FileSection, error) {
 
 	var fileSection FileSection
 
-	// Unmarshal the JSON data into the Person struct
-	err = json.Unmarshal([]byte(msg.Data()), &fileSection)
+	// Decode the data using the codec the publisher used
+	err = DecodeStreamMessage(msg.Headers(), msg.Data(), &fileSection)
 	if err != nil {
-		err := fmt.Errorf("error unmarshaling JSON: %v", err)
+		err := fmt.Errorf("error decoding FileSection: %v", err)
 		return nil, err
 	}
 
@@ -2,10 +2,12 @@ package models
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"reconciler.io/constants"
+	"sync"
 )
 
 type StreamProvider interface {
@@ -14,32 +16,65 @@ type StreamProvider interface {
 	PublishToTopic(ctx context.Context, topicName string, data interface{}) error
 	CreateStreamConsumer(ctx context.Context, streamName, topicName, consumerName string) (StreamConsumer, error)
 	DeleteStreamConsumer(ctx context.Context, streamName string, consumerName string) error
+	Close(ctx context.Context) error
 }
 
 type NatsStreamProvider struct {
-	channel jetstream.JetStream
+	conn      *nats.Conn
+	channel   jetstream.JetStream
+	encoding  StreamEncoding
+	consumers map[string]string
+	// topicEncodings are the encodings of the streams the topics published to are on
+	topicEncodings map[string]StreamEncoding
+	isClosed       bool
+	mu             sync.Mutex
 }
 
+// NewStreamProvider connects to NATS and publishes
+// using the configured default stream encoding.
 func NewStreamProvider(natsUrl string) (StreamProvider, error) {
-	connected, err := connect(natsUrl)
+	return NewStreamProviderWithEncoding(natsUrl, StreamEncoding{
+		Codec:       constants.STREAM_CODEC,
+		Compression: constants.STREAM_COMPRESSION,
+	})
+}
+
+// NewStreamProviderWithEncoding connects to NATS and creates streams with the given encoding.
+// Each stream records the encoding it was created with and is always published to with it,
+// so services configured with different encodings can share it.
+// Consumers always decode using the encoding recorded in each message's headers.
+func NewStreamProviderWithEncoding(natsUrl string, encoding StreamEncoding) (StreamProvider, error) {
+	_, _, err := lookupStreamEncoding(encoding.Codec, encoding.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, connected, err := connect(natsUrl)
 	if err != nil {
 		return nil, err
 	}
-	streamChannel := NatsStreamProvider{channel: connected}
+	streamChannel := NatsStreamProvider{
+		conn:           nc,
+		channel:        connected,
+		encoding:       encoding,
+		consumers:      make(map[string]string),
+		topicEncodings: make(map[string]StreamEncoding),
+	}
 	return &streamChannel, nil
 }
 
-func connect(natsUrl string) (jetstream.JetStream, error) {
+func connect(natsUrl string) (*nats.Conn, jetstream.JetStream, error) {
 	nc, err := nats.Connect(natsUrl)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	js, err := jetstream.New(nc)
 	if err != nil {
-		return nil, err
+		nc.Close()
+		return nil, nil, err
 	}
-	return js, nil
+	return nc, js, nil
 }
 
 func (sc *NatsStreamProvider) SetupStream(ctx context.Context, streamName string, topicName string) error {
@@ -48,8 +83,11 @@ func (sc *NatsStreamProvider) SetupStream(ctx context.Context, streamName string
 
 	// If the stream exists
 	if err == nil {
+		config := stream.CachedInfo().Config
+		sc.rememberTopicEncoding(topicName, parseStreamEncoding(config.Description))
+
 		// Check if the subject is already part of the stream
-		for _, subject := range stream.CachedInfo().Config.Subjects {
+		for _, subject := range config.Subjects {
 			if subject == topicName {
 				// Subject already exists, no need to update the stream
 				return nil
@@ -57,10 +95,8 @@ func (sc *NatsStreamProvider) SetupStream(ctx context.Context, streamName string
 		}
 
 		// Update the stream to add the new subject
-		_, err = sc.channel.UpdateStream(ctx, jetstream.StreamConfig{
-			Name:     streamName,
-			Subjects: append(stream.CachedInfo().Config.Subjects, topicName),
-		})
+		config.Subjects = append(config.Subjects, topicName)
+		_, err = sc.channel.UpdateStream(ctx, config)
 		if err != nil {
 			return fmt.Errorf("failed to update stream: %w", err)
 		}
@@ -68,13 +104,16 @@ func (sc *NatsStreamProvider) SetupStream(ctx context.Context, streamName string
 	}
 
 	// If the stream does not exist, create a new one
+	// that records the encoding it is published to with
 	_, err = sc.channel.CreateStream(ctx, jetstream.StreamConfig{
-		Name:     streamName,
-		Subjects: []string{topicName},
+		Name:        streamName,
+		Subjects:    []string{topicName},
+		Description: describeStreamEncoding(sc.encoding),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create stream: %w", err)
 	}
+	sc.rememberTopicEncoding(topicName, sc.encoding)
 
 	return nil
 }
@@ -90,10 +129,9 @@ func (sc *NatsStreamProvider) DeleteStreamTopic(ctx context.Context, streamName
 	}
 
 	// Update the stream to delete the  subject
-	_, err = sc.channel.UpdateStream(ctx, jetstream.StreamConfig{
-		Name:     streamName,
-		Subjects: deleteByValue(stream.CachedInfo().Config.Subjects, topicName),
-	})
+	config := stream.CachedInfo().Config
+	config.Subjects = deleteByValue(config.Subjects, topicName)
+	_, err = sc.channel.UpdateStream(ctx, config)
 
 	// failed to delete
 	if err != nil {
@@ -104,6 +142,41 @@ func (sc *NatsStreamProvider) DeleteStreamTopic(ctx context.Context, streamName
 	return nil
 }
 
+// topicEncoding is the encoding of the stream the topic is on,
+// looked up on the stream the first time the topic is published to
+func (sc *NatsStreamProvider) topicEncoding(ctx context.Context, topicName string) (StreamEncoding, error) {
+	sc.mu.Lock()
+	encoding, exists := sc.topicEncodings[topicName]
+	sc.mu.Unlock()
+	if exists {
+		return encoding, nil
+	}
+
+	streamName, err := sc.channel.StreamNameBySubject(ctx, topicName)
+
+	//error on finding the stream
+	if err != nil {
+		return StreamEncoding{}, fmt.Errorf("failed to find the stream of topic [%v]: %w", topicName, err)
+	}
+
+	stream, err := sc.channel.Stream(ctx, streamName)
+
+	//error on retrieving the stream
+	if err != nil {
+		return StreamEncoding{}, fmt.Errorf("failed to get stream [%v]: %w", streamName, err)
+	}
+
+	encoding = parseStreamEncoding(stream.CachedInfo().Config.Description)
+	sc.rememberTopicEncoding(topicName, encoding)
+	return encoding, nil
+}
+
+func (sc *NatsStreamProvider) rememberTopicEncoding(topicName string, encoding StreamEncoding) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.topicEncodings[topicName] = encoding
+}
+
 func deleteByValue(subjects []string, name string) []string {
 	for i, v := range subjects {
 		if v == name {
@@ -118,17 +191,25 @@ func (sc *NatsStreamProvider) PublishToTopic(
 	topicName string,
 	data interface{},
 ) error {
-	// Convert the FileToBeRead to JSON
-	jsonData, err := json.Marshal(data)
+	encoding, err := sc.topicEncoding(ctx, topicName)
+	if err != nil {
+		return err
+	}
+
+	// Encode the data with this stream's codec
+	encodedData, header, err := EncodeStreamMessage(encoding, data)
 	if err != nil {
 		return err
 	}
 
 	// Publish the message to a subject
-	_, err = sc.channel.Publish(
+	_, err = sc.channel.PublishMsg(
 		ctx,
-		topicName,
-		jsonData,
+		&nats.Msg{
+			Subject: topicName,
+			Header:  header,
+			Data:    encodedData,
+		},
 	)
 	return err
 }
@@ -150,6 +231,13 @@ func (sc *NatsStreamProvider) CreateStreamConsumer(
 	if err != nil {
 		return nil, err
 	}
+
+	// remember the consumer so that it
+	// can be cleaned up if the provider is closed
+	sc.mu.Lock()
+	sc.consumers[consumerName] = streamName
+	sc.mu.Unlock()
+
 	return NewFileSectionsStreamConsumer(cons), nil
 }
 
@@ -163,5 +251,46 @@ func (sc *NatsStreamProvider) DeleteStreamConsumer(
 		streamName,
 		consumerName,
 	)
+
+	// the consumer is gone either way
+	if err == nil || errors.Is(err, jetstream.ErrConsumerNotFound) {
+		sc.mu.Lock()
+		delete(sc.consumers, consumerName)
+		sc.mu.Unlock()
+	}
 	return err
 }
+
+// Close deletes the consumers created through this provider that are still around
+// and then drains the NATS connection. Closing an already closed provider does nothing.
+func (sc *NatsStreamProvider) Close(ctx context.Context) error {
+	sc.mu.Lock()
+	if sc.isClosed {
+		sc.mu.Unlock()
+		return nil
+	}
+	sc.isClosed = true
+
+	consumers := make(map[string]string, len(sc.consumers))
+	for consumerName, streamName := range sc.consumers {
+		consumers[consumerName] = streamName
+	}
+	sc.mu.Unlock()
+
+	var errs []error
+	for consumerName, streamName := range consumers {
+		err := sc.DeleteStreamConsumer(ctx, streamName, consumerName)
+
+		// failed to delete
+		if err != nil && !errors.Is(err, jetstream.ErrConsumerNotFound) {
+			errs = append(errs, fmt.Errorf("failed to delete consumer [%v]: %w", consumerName, err))
+		}
+	}
+
+	// failed to drain
+	if err := sc.conn.Drain(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain connection: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"context"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/stretchr/testify/assert"
 	"reconciler.io/models/enums/file_purpose"
 	"testing"
@@ -56,3 +58,51 @@ func TestNatsStreamProviderIntegration(t *testing.T) {
 	expectedFileSection := msg
 	assert.Equal(t, expectedFileSection, *fileSection)
 }
+
+func TestStreamsArePublishedToWithTheEncodingTheyRecorded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	compact, err := NewStreamProviderWithEncoding("nats://localhost:4222", StreamEncoding{Codec: MsgPackStreamCodec, Compression: S2StreamCompression})
+	assert.NoError(t, err)
+	defer compact.Close(ctx)
+	plain, err := NewStreamProviderWithEncoding("nats://localhost:4222", StreamEncoding{Codec: JsonStreamCodec, Compression: NoStreamCompression})
+	assert.NoError(t, err)
+	defer plain.Close(ctx)
+
+	js := compact.(*NatsStreamProvider).channel
+	newStream := "encoding-test-" + uuid.New().String()
+	legacyStream := "encoding-test-" + uuid.New().String()
+	defer js.DeleteStream(context.Background(), newStream)
+	defer js.DeleteStream(context.Background(), legacyStream)
+
+	// a stream created before streams recorded their encoding
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{Name: legacyStream, Subjects: []string{legacyStream + ".sections"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, compact.SetupStream(ctx, newStream, newStream+".sections"))
+	assert.NoError(t, compact.SetupStream(ctx, legacyStream, legacyStream+".sections"))
+
+	for _, published := range []struct {
+		provider StreamProvider
+		stream   string
+		codec    string
+	}{
+		{plain, newStream, MsgPackStreamCodec},
+		{compact, newStream, MsgPackStreamCodec},
+		{compact, legacyStream, JsonStreamCodec},
+	} {
+		section := FileSection{ID: uuid.New().String(), SectionSequenceNumber: 1}
+		assert.NoError(t, published.provider.PublishToTopic(ctx, published.stream+".sections", &section))
+
+		stream, err := js.Stream(ctx, published.stream)
+		assert.NoError(t, err)
+		message, err := stream.GetLastMsgForSubject(ctx, published.stream+".sections")
+		assert.NoError(t, err)
+		assert.Equal(t, published.codec, message.Header.Get(StreamCodecHeader), published.stream)
+
+		var decoded FileSection
+		assert.NoError(t, DecodeStreamMessage(message.Header, message.Data, &decoded))
+		assert.Equal(t, section, decoded)
+	}
+}
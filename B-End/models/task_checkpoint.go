@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// TaskCheckpointRecorder is how the activities record how far a task has got,
+// so that an interrupted task can carry on from where it stopped after a restart.
+// FileIDs are the IDs of the topics the sections were published to,
+// i.e. the partition IDs for partitioned files.
+type TaskCheckpointRecorder interface {
+	RecordSectionRead(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int, isLastSection bool) error
+	GetLastSectionRead(ctx context.Context, taskID string, fileID string) (lastSectionSequenceNumber int, isFullyRead bool)
+	RecordSectionReconciled(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int) error
+	HasSectionBeenReconciled(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int) bool
+	HaveAllSectionsBeenReconciled(ctx context.Context, taskID string, fileID string) bool
+	RecordSectionReconstructed(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int) error
+}
+
+// TaskCheckpoint is everything needed to pick a task back up after a restart.
+// Checkpoints are deleted once their task finishes, IsComplete is only set on
+// the checkpoints of completed tasks that earlier versions of the service kept.
+type TaskCheckpoint struct {
+	TaskDetails ReconTaskDetails
+	Files       map[string]*FileCheckpoint
+	IsComplete  bool
+	UpdatedAt   time.Time
+}
+
+// FileCheckpoint tracks the progress of one file (or partition) of a task.
+// File is only set for files that were uploaded to the task.
+type FileCheckpoint struct {
+	File                  *FileToBeRead
+	FilePath              string
+	LastSectionRead       int
+	IsFullyRead           bool
+	ReconciledSections    map[int]bool
+	ReconstructedSections map[int]bool
+}
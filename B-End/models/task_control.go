@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrStoppedAfterFailure is the cause the work of a task is stopped with when other work
+// of the task failed. Unlike a cancelled task, the streams it was working on are kept for a retry
+var ErrStoppedAfterFailure = errors.New("stopped after other work of the task failed")
+
+// IsStoppedAfterFailure reports whether the context was stopped because other work of the task failed
+func IsStoppedAfterFailure(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrStoppedAfterFailure)
+}
+
+// TaskControl lets a running task be paused, resumed or cancelled from outside.
+// The activities of a task run with its Context and call WaitWhilePaused
+// before taking on more work.
+// A nil TaskControl never pauses and is never cancelled.
+type TaskControl struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	isPaused bool
+	resumed  chan struct{}
+	mu       sync.Mutex
+}
+
+func NewTaskControl() *TaskControl {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TaskControl{
+		ctx:     ctx,
+		cancel:  cancel,
+		resumed: make(chan struct{}),
+	}
+}
+
+// Context is done once the task has been cancelled.
+func (c *TaskControl) Context() context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *TaskControl) Cancel() {
+	if c == nil {
+		return
+	}
+	c.cancel()
+}
+
+func (c *TaskControl) Pause() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isPaused {
+		c.isPaused = true
+		c.resumed = make(chan struct{})
+	}
+}
+
+func (c *TaskControl) Resume() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isPaused {
+		c.isPaused = false
+		close(c.resumed)
+	}
+}
+
+func (c *TaskControl) IsPaused() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isPaused
+}
+
+// WaitWhilePaused blocks while the task is paused.
+// It returns an error if the task is cancelled or the given context is done.
+func (c *TaskControl) WaitWhilePaused(ctx context.Context) error {
+	if c == nil {
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	resumed := c.resumed
+	isPaused := c.isPaused
+	c.mu.Unlock()
+
+	if isPaused {
+		select {
+		case <-resumed:
+		case <-c.ctx.Done():
+		case <-ctx.Done():
+		}
+	}
+
+	if c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	return ctx.Err()
+}
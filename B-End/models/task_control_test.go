@@ -0,0 +1,53 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskControlPauseAndResume(t *testing.T) {
+	control := NewTaskControl()
+	control.Pause()
+	assert.True(t, control.IsPaused())
+
+	waited := make(chan error)
+	go func() {
+		waited <- control.WaitWhilePaused(context.Background())
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("WaitWhilePaused returned while the task was paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	control.Resume()
+	assert.NoError(t, <-waited)
+	assert.False(t, control.IsPaused())
+}
+
+func TestTaskControlCancelWhilePaused(t *testing.T) {
+	control := NewTaskControl()
+	control.Pause()
+
+	waited := make(chan error)
+	go func() {
+		waited <- control.WaitWhilePaused(context.Background())
+	}()
+
+	control.Cancel()
+	assert.ErrorIs(t, <-waited, context.Canceled)
+	assert.ErrorIs(t, control.Context().Err(), context.Canceled)
+}
+
+func TestNilTaskControlNeverPauses(t *testing.T) {
+	var control *TaskControl
+	control.Pause()
+
+	assert.False(t, control.IsPaused())
+	assert.NoError(t, control.WaitWhilePaused(context.Background()))
+	assert.NoError(t, control.Context().Err())
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/task_status"
+	"time"
+)
+
+// TaskEvent is something that happened to a task,
+// streamed to whoever is watching the task.
+type TaskEvent struct {
+	Sequence              uint64
+	TaskID                string
+	Type                  task_event_type.TaskEventType
+	Status                task_status.TaskStatus `json:",omitempty"`
+	FileID                string                 `json:",omitempty"`
+	SectionSequenceNumber int                    `json:",omitempty"`
+	Error                 string                 `json:",omitempty"`
+	Progress              *TaskProgress          `json:",omitempty"`
+	OccurredAt            time.Time
+}
+
+// TaskEventPublisher is how the activities and repositories publish the events of a task.
+type TaskEventPublisher interface {
+	Publish(event TaskEvent)
+}
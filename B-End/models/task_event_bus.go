@@ -0,0 +1,114 @@
+package models
+
+import (
+	"log"
+	"sync"
+)
+
+// TaskEventBus hands the events of each task to everyone subscribed to that task.
+// Publishing never blocks, a subscriber that falls more than its buffer
+// behind misses events rather than holding up the task.
+type TaskEventBus struct {
+	subscribers      map[string]map[uint64]chan TaskEvent
+	listeners        []func(event TaskEvent)
+	bufferSize       int
+	nextSubscriberID uint64
+	nextSequence     uint64
+	isClosed         bool
+	mu               sync.Mutex
+}
+
+func NewTaskEventBus(bufferSize int) *TaskEventBus {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &TaskEventBus{
+		subscribers: make(map[string]map[uint64]chan TaskEvent),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe returns the channel the events of the task are sent on
+// and the function that stops the subscription and closes the channel.
+// Once the bus is closed the channel is returned already closed.
+func (b *TaskEventBus) Subscribe(taskID string) (<-chan TaskEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan TaskEvent, b.bufferSize)
+	if b.isClosed {
+		close(events)
+		return events, func() {}
+	}
+
+	b.nextSubscriberID++
+	subscriberID := b.nextSubscriberID
+	if b.subscribers[taskID] == nil {
+		b.subscribers[taskID] = make(map[uint64]chan TaskEvent)
+	}
+	b.subscribers[taskID][subscriberID] = events
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.removeSubscriberLocked(taskID, subscriberID)
+	}
+	return events, unsubscribe
+}
+
+// AddListener registers a function that is called with every event of every task.
+// Listeners are called while the event is being published so they must not block,
+// unlike subscribers they never miss an event.
+func (b *TaskEventBus) AddListener(listener func(event TaskEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+// Publish numbers the event and sends it to the subscribers of its task.
+// A nil TaskEventBus drops every event.
+func (b *TaskEventBus) Publish(event TaskEvent) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextSequence++
+	event.Sequence = b.nextSequence
+	for _, listener := range b.listeners {
+		listener(event)
+	}
+	for subscriberID, events := range b.subscribers[event.TaskID] {
+		select {
+		case events <- event:
+		default:
+			log.Printf("dropped task event [%v] of task [%v] for slow subscriber [%v]", event.Type, event.TaskID, subscriberID)
+		}
+	}
+}
+
+// Close ends every subscription, used on shutdown so the event streams let go of their connections.
+func (b *TaskEventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.isClosed = true
+	for taskID, taskSubscribers := range b.subscribers {
+		for subscriberID := range taskSubscribers {
+			b.removeSubscriberLocked(taskID, subscriberID)
+		}
+	}
+}
+
+func (b *TaskEventBus) removeSubscriberLocked(taskID string, subscriberID uint64) {
+	events, exists := b.subscribers[taskID][subscriberID]
+	if !exists {
+		return
+	}
+	close(events)
+	delete(b.subscribers[taskID], subscriberID)
+	if len(b.subscribers[taskID]) == 0 {
+		delete(b.subscribers, taskID)
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"reconciler.io/models/enums/task_event_type"
+)
+
+func TestTaskEventBusOnlySendsEventsOfTheSubscribedTask(t *testing.T) {
+	bus := NewTaskEventBus(4)
+	events, unsubscribe := bus.Subscribe("task_1")
+	defer unsubscribe()
+
+	bus.Publish(TaskEvent{TaskID: "task_2", Type: task_event_type.SectionReconciled})
+	bus.Publish(TaskEvent{TaskID: "task_1", Type: task_event_type.FileReadCompleted})
+
+	event := <-events
+	assert.Equal(t, "task_1", event.TaskID)
+	assert.Equal(t, task_event_type.FileReadCompleted, event.Type)
+	assert.Equal(t, uint64(2), event.Sequence)
+	assert.Empty(t, events)
+}
+
+func TestTaskEventBusDropsEventsForSlowSubscribers(t *testing.T) {
+	bus := NewTaskEventBus(1)
+	events, unsubscribe := bus.Subscribe("task_1")
+
+	bus.Publish(TaskEvent{TaskID: "task_1", Type: task_event_type.SectionReconciled, SectionSequenceNumber: 1})
+	bus.Publish(TaskEvent{TaskID: "task_1", Type: task_event_type.SectionReconciled, SectionSequenceNumber: 2})
+
+	assert.Equal(t, 1, (<-events).SectionSequenceNumber)
+
+	unsubscribe()
+	_, isOpen := <-events
+	assert.False(t, isOpen)
+}
+
+func TestTaskEventBusCloseEndsEverySubscription(t *testing.T) {
+	bus := NewTaskEventBus(1)
+	events, _ := bus.Subscribe("task_1")
+
+	bus.Close()
+	_, isOpen := <-events
+	assert.False(t, isOpen)
+
+	eventsAfterClose, unsubscribe := bus.Subscribe("task_1")
+	unsubscribe()
+	_, isOpen = <-eventsAfterClose
+	assert.False(t, isOpen)
+}
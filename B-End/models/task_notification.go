@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"reconciler.io/models/enums/task_status"
+	"time"
+)
+
+// TaskSummary is where a task stands when a notification about it is sent.
+type TaskSummary struct {
+	ID                 string
+	UserID             string `json:",omitempty"`
+	Status             task_status.TaskStatus
+	Error              string `json:",omitempty"`
+	SectionsReconciled int
+	RowsMatched        int64
+	RowsFailed         int64
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// TaskLinks are where more about a task can be fetched from, sent out in notifications.
+// Results is only set once the results file has been written.
+type TaskLinks struct {
+	Task    string
+	Results string `json:",omitempty"`
+}
+
+// ValidateEmailRecipients checks every recipient is a bare email address.
+func ValidateEmailRecipients(recipients []string) error {
+	for _, recipient := range recipients {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil || address.Address != recipient {
+			return fmt.Errorf("[%v] is not an email address", recipient)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"math"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_status"
+	"sync"
+	"time"
+)
+
+// how much of the overall percentage each phase of a task accounts for
+var readingProgressWeight = 0.2
+var reconcilingProgressWeight = 0.7
+var reconstructingProgressWeight = 0.1
+
+// TaskProgress is a point in time snapshot of how far a task has got.
+type TaskProgress struct {
+	Files                     map[string]FileProgress
+	SectionsReconciled        int
+	RowsMatched               int64
+	RowsFailed                int64
+	RowsReconstructed         int64
+	Percentage                float64
+	EstimatedSecondsRemaining *int64     `json:",omitempty"`
+	EstimatedCompletionAt     *time.Time `json:",omitempty"`
+	StartedAt                 *time.Time `json:",omitempty"`
+	UpdatedAt                 *time.Time `json:",omitempty"`
+}
+
+// FileProgress is how much of one uploaded file has been read.
+type FileProgress struct {
+	FilePurpose       file_purpose.FilePurposeType
+	RowsRead          int64
+	SectionsPublished int
+	BytesRead         int64
+	TotalBytes        int64
+	IsFullyRead       bool
+}
+
+// TaskProgressTracker is updated by the activities of a task as they go.
+// A nil TaskProgressTracker ignores every update.
+type TaskProgressTracker struct {
+	progress  TaskProgress
+	startedAt time.Time
+	updatedAt time.Time
+	mu        sync.Mutex
+}
+
+func NewTaskProgressTracker() *TaskProgressTracker {
+	return &TaskProgressTracker{
+		progress: TaskProgress{Files: make(map[string]FileProgress)},
+	}
+}
+
+// RecordSectionRead records a section read from an uploaded file and published to its stream.
+// bytesRead is how far into the file the reader is.
+func (p *TaskProgressTracker) RecordSectionRead(
+	fileID string,
+	filePurpose file_purpose.FilePurposeType,
+	rowsInSection int,
+	bytesRead int64,
+	totalBytes int64,
+	isLastSection bool,
+) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fileProgress := p.progress.Files[fileID]
+	fileProgress.FilePurpose = filePurpose
+	fileProgress.RowsRead += int64(rowsInSection)
+	fileProgress.SectionsPublished++
+	fileProgress.BytesRead = bytesRead
+	fileProgress.TotalBytes = totalBytes
+	fileProgress.IsFullyRead = fileProgress.IsFullyRead || isLastSection
+	p.progress.Files[fileID] = fileProgress
+	p.touchLocked()
+}
+
+// IsFileFullyRead is true once the last section of the file has been read.
+func (p *TaskProgressTracker) IsFileFullyRead(fileID string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.progress.Files[fileID].IsFullyRead
+}
+
+// RecordSectionReconciled counts the rows of a section that has been given its final recon results.
+func (p *TaskProgressTracker) RecordSectionReconciled(section FileSection) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.progress.SectionsReconciled++
+	for _, row := range section.SectionRows {
+		if row.ReconResult == recon_status.Successfull {
+			p.progress.RowsMatched++
+		} else {
+			p.progress.RowsFailed++
+		}
+	}
+	p.touchLocked()
+}
+
+// RecordRowsReconstructed sets how many rows the reconstruction has received so far.
+func (p *TaskProgressTracker) RecordRowsReconstructed(rowsReconstructed int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.progress.RowsReconstructed = rowsReconstructed
+	p.touchLocked()
+}
+
+// Snapshot returns the progress so far with the percentage done and,
+// once there is something to go on, an estimate of when the task will finish.
+// The number of primary file rows still to be read is estimated from how far
+// into the file the reader is, so the percentage never has to wait for the whole file to be read.
+func (p *TaskProgressTracker) Snapshot(now time.Time) *TaskProgress {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snapshot := p.progress
+	snapshot.Files = make(map[string]FileProgress, len(p.progress.Files))
+	for fileID, fileProgress := range p.progress.Files {
+		snapshot.Files[fileID] = fileProgress
+	}
+
+	if p.startedAt.IsZero() {
+		return &snapshot
+	}
+	startedAt, updatedAt := p.startedAt, p.updatedAt
+	snapshot.StartedAt = &startedAt
+	snapshot.UpdatedAt = &updatedAt
+
+	readFraction, expectedPrimaryRows := p.estimateReadingLocked()
+	reconcileFraction, reconstructFraction := 0.0, 0.0
+	if expectedPrimaryRows > 0 {
+		reconcileFraction = fractionOf(float64(p.progress.RowsMatched+p.progress.RowsFailed), expectedPrimaryRows)
+		reconstructFraction = fractionOf(float64(p.progress.RowsReconstructed), expectedPrimaryRows)
+	}
+
+	done := readingProgressWeight*readFraction +
+		reconcilingProgressWeight*reconcileFraction +
+		reconstructingProgressWeight*reconstructFraction
+	snapshot.Percentage = math.Round(done*10000) / 100
+
+	// assume the rest of the task carries on at the same pace
+	if snapshot.Percentage > 0 && snapshot.Percentage < 100 {
+		elapsed := now.Sub(p.startedAt)
+		remaining := time.Duration(float64(elapsed) * (1 - done) / done)
+		secondsRemaining := int64(remaining.Seconds())
+		completionAt := now.Add(remaining)
+		snapshot.EstimatedSecondsRemaining = &secondsRemaining
+		snapshot.EstimatedCompletionAt = &completionAt
+	}
+	return &snapshot
+}
+
+// estimateReadingLocked returns the fraction of the uploaded files read so far
+// and the number of rows the primary file is expected to have.
+func (p *TaskProgressTracker) estimateReadingLocked() (float64, float64) {
+	var bytesRead, totalBytes int64
+	expectedPrimaryRows := 0.0
+	for _, fileProgress := range p.progress.Files {
+		fileReadFraction := 1.0
+		if !fileProgress.IsFullyRead {
+			fileReadFraction = fractionOf(float64(fileProgress.BytesRead), float64(fileProgress.TotalBytes))
+		}
+
+		if fileProgress.IsFullyRead {
+			bytesRead += fileProgress.TotalBytes
+		} else {
+			bytesRead += fileProgress.BytesRead
+		}
+		totalBytes += fileProgress.TotalBytes
+
+		if fileProgress.FilePurpose == file_purpose.PrimaryFile && fileReadFraction > 0 {
+			expectedPrimaryRows = float64(fileProgress.RowsRead) / fileReadFraction
+		}
+	}
+
+	// both files have to be read
+	readFraction := fractionOf(float64(bytesRead), float64(totalBytes))
+	if len(p.progress.Files) < 2 {
+		readFraction /= 2
+	}
+	return readFraction, expectedPrimaryRows
+}
+
+func (p *TaskProgressTracker) touchLocked() {
+	p.updatedAt = time.Now()
+	if p.startedAt.IsZero() {
+		p.startedAt = p.updatedAt
+	}
+}
+
+func fractionOf(done float64, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	if done >= total {
+		return 1
+	}
+	return done / total
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/recon_status"
+)
+
+func TestTaskProgressSnapshotEstimatesPercentageAndETA(t *testing.T) {
+	progress := NewTaskProgressTracker()
+
+	// half way through the primary file, all of the comparison file
+	progress.RecordSectionRead("primary", file_purpose.PrimaryFile, 10, 50, 100, false)
+	progress.RecordSectionRead("comparison", file_purpose.ComparisonFile, 20, 100, 100, true)
+	progress.RecordSectionReconciled(FileSection{
+		SectionRows: []FileSectionRow{
+			{ReconResult: recon_status.Successfull},
+			{ReconResult: recon_status.Successfull},
+			{ReconResult: recon_status.Successfull},
+			{ReconResult: recon_status.Successfull},
+			{ReconResult: recon_status.Failed},
+		},
+	})
+
+	snapshot := progress.Snapshot(time.Now().Add(10 * time.Second))
+
+	assert.Equal(t, int64(10), snapshot.Files["primary"].RowsRead)
+	assert.Equal(t, 1, snapshot.Files["primary"].SectionsPublished)
+	assert.True(t, snapshot.Files["comparison"].IsFullyRead)
+	assert.Equal(t, 1, snapshot.SectionsReconciled)
+	assert.Equal(t, int64(4), snapshot.RowsMatched)
+	assert.Equal(t, int64(1), snapshot.RowsFailed)
+
+	// reading is 3/4 done, 5 of the expected 20 primary rows are reconciled
+	assert.InDelta(t, 32.5, snapshot.Percentage, 0.001)
+	assert.NotNil(t, snapshot.EstimatedSecondsRemaining)
+	assert.NotNil(t, snapshot.EstimatedCompletionAt)
+	assert.Greater(t, *snapshot.EstimatedSecondsRemaining, int64(0))
+}
+
+func TestTaskProgressSnapshotOfFinishedTask(t *testing.T) {
+	progress := NewTaskProgressTracker()
+	progress.RecordSectionRead("primary", file_purpose.PrimaryFile, 2, 10, 10, true)
+	progress.RecordSectionRead("comparison", file_purpose.ComparisonFile, 2, 10, 10, true)
+	progress.RecordSectionReconciled(FileSection{
+		SectionRows: []FileSectionRow{
+			{ReconResult: recon_status.Successfull},
+			{ReconResult: recon_status.Successfull},
+		},
+	})
+	progress.RecordRowsReconstructed(2)
+
+	snapshot := progress.Snapshot(time.Now())
+
+	assert.InDelta(t, 100, snapshot.Percentage, 0.001)
+	assert.Nil(t, snapshot.EstimatedSecondsRemaining)
+}
+
+func TestTaskProgressBeforeAnythingHappened(t *testing.T) {
+	var noTracker *TaskProgressTracker
+	noTracker.RecordRowsReconstructed(1)
+	assert.Nil(t, noTracker.Snapshot(time.Now()))
+
+	snapshot := NewTaskProgressTracker().Snapshot(time.Now())
+	assert.Equal(t, float64(0), snapshot.Percentage)
+	assert.Nil(t, snapshot.StartedAt)
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reconciler.io/constants"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/webhook_delivery_status"
+	"strings"
+	"time"
+)
+
+// defaultWebhookEvents are the events a webhook is called for when it doesn't pick any
+var defaultWebhookEvents = []task_event_type.TaskEventType{
+	task_event_type.TaskCompleted,
+	task_event_type.TaskFailed,
+	task_event_type.TaskCancelled,
+}
+
+// webhookEvents are the events a webhook can be called for,
+// the progress of each section is left to the event streams
+var webhookEvents = map[task_event_type.TaskEventType]bool{
+	task_event_type.TaskStatusChanged: true,
+	task_event_type.TaskPaused:        true,
+	task_event_type.TaskResumed:       true,
+	task_event_type.TaskCompleted:     true,
+	task_event_type.TaskFailed:        true,
+	task_event_type.TaskCancelled:     true,
+}
+
+// CanBeSentToWebhooks reports whether any webhook can be called for the given event.
+func CanBeSentToWebhooks(eventType task_event_type.TaskEventType) bool {
+	return webhookEvents[eventType]
+}
+
+// Webhook is a URL that is POSTed to when the task it is registered on changes.
+type Webhook struct {
+	ID     string
+	URL    string
+	Events []task_event_type.TaskEventType `json:",omitempty"`
+}
+
+// Validate checks the URL can be called and the events can be sent to a webhook.
+func (w Webhook) Validate() error {
+	parsedURL, err := url.Parse(w.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("webhook URL [%v] is not an http(s) URL", w.URL)
+	}
+	if !constants.WEBHOOK_ALLOW_PRIVATE_HOSTS && isPrivateHost(parsedURL.Hostname()) {
+		return fmt.Errorf("webhook URL [%v] points at a private host", w.URL)
+	}
+	for _, eventType := range w.Events {
+		if !webhookEvents[eventType] {
+			return fmt.Errorf("webhooks can not be called for [%v] events", eventType)
+		}
+	}
+	return nil
+}
+
+// isPrivateHost reports whether the host is localhost or a private address,
+// host names are only checked once they are resolved when the webhook is called
+func isPrivateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && IsPrivateAddress(ip)
+}
+
+// IsPrivateAddress reports whether the address is one webhooks may not be called on:
+// loopback, link local (where cloud metadata services live), private or unspecified.
+func IsPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// IsCalledFor reports whether the webhook wants to hear about the given event.
+func (w Webhook) IsCalledFor(eventType task_event_type.TaskEventType) bool {
+	events := w.Events
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+	for _, wanted := range events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook.
+type WebhookPayload struct {
+	DeliveryID string
+	Event      TaskEvent
+	Task       TaskSummary
+	Links      TaskLinks
+}
+
+// WebhookDelivery is the log of every attempt at calling a webhook for one event.
+type WebhookDelivery struct {
+	ID        string
+	TaskID    string
+	WebhookID string
+	URL       string
+	EventType task_event_type.TaskEventType
+	Status    webhook_delivery_status.WebhookDeliveryStatus
+	Attempts  []WebhookDeliveryAttempt
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookDeliveryAttempt is one POST to a webhook.
+// StatusCode is zero when no response came back at all.
+type WebhookDeliveryAttempt struct {
+	AttemptNumber  int
+	StatusCode     int    `json:",omitempty"`
+	Error          string `json:",omitempty"`
+	At             time.Time
+	DurationMillis int64
+}
@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// backgroundSends runs the sends of a notifier in the background,
+// so publishing a task event never waits on a slow receiver,
+// and lets the notifier wait for the sends that are in flight on shutdown.
+type backgroundSends struct {
+	inFlight  sync.WaitGroup
+	stopped   chan struct{}
+	isStopped bool
+	mu        sync.Mutex
+}
+
+func newBackgroundSends() *backgroundSends {
+	return &backgroundSends{
+		stopped: make(chan struct{}),
+	}
+}
+
+// start runs the send in the background, unless the sends have been closed.
+func (b *backgroundSends) start(send func()) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.isStopped {
+		return false
+	}
+
+	b.inFlight.Add(1)
+	go func() {
+		defer b.inFlight.Done()
+		send()
+	}()
+	return true
+}
+
+// waitBeforeRetry waits out the backoff before a failed send is retried,
+// it returns false when the sends are closed in the meantime and the send should give up.
+func (b *backgroundSends) waitBeforeRetry(backoff time.Duration) bool {
+	select {
+	case <-time.After(backoff):
+		return true
+	case <-b.stopped:
+		return false
+	}
+}
+
+// close stops any more sends from starting or being retried and waits for
+// the sends that are in flight to finish or the context to be done.
+func (b *backgroundSends) close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.isStopped {
+		b.isStopped = true
+		close(b.stopped)
+	}
+	b.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		b.inFlight.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,317 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/results_format"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/repositories"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultEmailSubjectTemplate and DefaultEmailBodyTemplate are used unless the
+// EMAIL_TEMPLATES_DIRECTORY has a subject.tmpl or body.tmpl, both are given an EmailTemplateData.
+var DefaultEmailSubjectTemplate = `Reconciliation task {{.Task.ID}} {{.Task.Status}}`
+var DefaultEmailBodyTemplate = `Reconciliation task {{.Task.ID}} is {{.Task.Status}}.
+{{if .Task.Error}}
+Error: {{.Task.Error}}
+{{end}}{{with .Results}}
+Rows reconciled: {{.PrimaryFileRows}}
+  Matched: {{.MatchedRows}}
+  Mismatched: {{.MismatchedRows}}
+  Missing from the comparison file: {{.MissingFromComparisonFile}}
+  Duplicates: {{.DuplicateRows}}
+{{if .TopMismatchingColumns}}
+Columns that differ most often:
+{{range .TopMismatchingColumns}}  {{.Rows}} x {{.Column}}
+{{end}}{{end}}{{end}}
+Task: {{.Links.Task}}
+{{if .Links.Results}}Results: {{.Links.Results}}
+{{end}}{{if .IsResultsFileAttached}}The results file is attached.
+{{end}}`
+
+// EmailTemplateData is what the email templates are filled in with.
+// Results is nil when the task has no summary of its results.
+type EmailTemplateData struct {
+	Event                 models.TaskEvent
+	Task                  models.TaskSummary
+	Results               *models.ReconSummary
+	Links                 models.TaskLinks
+	IsResultsFileAttached bool
+}
+
+// sendMailFunc has the signature of smtp.SendMail
+type sendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailNotifier emails the recipients of a task when the task completes or fails.
+type EmailNotifier struct {
+	taskDetailsRepo    *repositories.TaskDetailsRepository
+	sendMail           sendMailFunc
+	smtpAddress        string
+	smtpAuth           smtp.Auth
+	from               string
+	publicBaseURL      string
+	subjectTemplate    *template.Template
+	bodyTemplate       *template.Template
+	maxAttachmentBytes int64
+	maxAttempts        int
+	initialBackoff     time.Duration
+	sends              *backgroundSends
+}
+
+// NewEmailNotifier sets up the notifier from the SMTP and email constants.
+func NewEmailNotifier(taskDetailsRepo *repositories.TaskDetailsRepository) (*EmailNotifier, error) {
+	subjectTemplate, err := loadEmailTemplate("subject.tmpl", DefaultEmailSubjectTemplate)
+
+	//error on parse
+	if err != nil {
+		return nil, err
+	}
+
+	bodyTemplate, err := loadEmailTemplate("body.tmpl", DefaultEmailBodyTemplate)
+
+	//error on parse
+	if err != nil {
+		return nil, err
+	}
+
+	var smtpAuth smtp.Auth
+	if len(constants.SMTP_USERNAME) > 0 {
+		smtpAuth = smtp.PlainAuth("", constants.SMTP_USERNAME, constants.SMTP_PASSWORD, constants.SMTP_HOST)
+	}
+
+	return &EmailNotifier{
+		taskDetailsRepo:    taskDetailsRepo,
+		sendMail:           smtp.SendMail,
+		smtpAddress:        net.JoinHostPort(constants.SMTP_HOST, constants.SMTP_PORT),
+		smtpAuth:           smtpAuth,
+		from:               constants.EMAIL_FROM,
+		publicBaseURL:      constants.PUBLIC_BASE_URL,
+		subjectTemplate:    subjectTemplate,
+		bodyTemplate:       bodyTemplate,
+		maxAttachmentBytes: constants.EMAIL_MAX_ATTACHMENT_BYTES,
+		maxAttempts:        constants.EMAIL_MAX_ATTEMPTS,
+		initialBackoff:     constants.EMAIL_INITIAL_BACKOFF,
+		sends:              newBackgroundSends(),
+	}, nil
+}
+
+// HandleTaskEvent emails the recipients of the task once it has completed or failed, in the background.
+// It is meant to be registered with TaskDetailsRepository.OnTaskEvent.
+func (n *EmailNotifier) HandleTaskEvent(event models.TaskEvent) {
+	if event.Type != task_event_type.TaskCompleted && event.Type != task_event_type.TaskFailed {
+		return
+	}
+
+	isStarted := n.sends.start(func() {
+		n.notify(event)
+	})
+	if !isStarted {
+		log.Printf("email notifier is stopped, not emailing about task [%v]", event.TaskID)
+	}
+}
+
+// Close stops retrying failed emails and waits for the emails
+// that are being sent to go out or the context to be done.
+func (n *EmailNotifier) Close(ctx context.Context) error {
+	return n.sends.close(ctx)
+}
+
+func (n *EmailNotifier) notify(event models.TaskEvent) {
+	taskDetails, err := n.taskDetailsRepo.GetReconciliationTaskStatus(context.Background(), event.TaskID)
+
+	//error on retrieve
+	if err != nil {
+		log.Printf("unable to email about task [%v]: %v", event.TaskID, err)
+		return
+	}
+
+	if len(taskDetails.NotificationEmails) == 0 {
+		return
+	}
+
+	message, err := n.buildMessage(taskDetails, event)
+
+	//error on building the email
+	if err != nil {
+		log.Printf("unable to build email about task [%v]: %v", taskDetails.ID, err)
+		return
+	}
+
+	backoff := n.initialBackoff
+	for attemptNumber := 1; ; attemptNumber++ {
+		err = n.sendMail(n.smtpAddress, n.smtpAuth, n.from, taskDetails.NotificationEmails, message)
+		if err == nil {
+			log.Printf("emailed [%v] recipients about task [%v] being [%v]", len(taskDetails.NotificationEmails), taskDetails.ID, taskDetails.Status)
+			return
+		}
+
+		log.Printf("attempt [%v] at emailing about task [%v] failed: %v", attemptNumber, taskDetails.ID, err)
+		if attemptNumber >= n.maxAttempts {
+			return
+		}
+
+		if !n.sends.waitBeforeRetry(backoff) {
+			log.Printf("email about task [%v] stopped before it could be retried", taskDetails.ID)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// readAttachment is the content of the results file of the task, or nil when it is too big to attach
+func (n *EmailNotifier) readAttachment(taskDetails models.ReconTaskDetails) []byte {
+	resultsFile, err := taskDetails.Files.Get(context.Background(), taskDetails.ResultsFilePath)
+
+	//error on retrieve
+	if err != nil {
+		log.Printf("unable to attach results of task [%v]: %v", taskDetails.ID, err)
+		return nil
+	}
+	defer resultsFile.Close()
+
+	if resultsFile.Size > n.maxAttachmentBytes {
+		return nil
+	}
+
+	content, err := io.ReadAll(resultsFile)
+
+	//error on read
+	if err != nil {
+		log.Printf("unable to attach results of task [%v]: %v", taskDetails.ID, err)
+		return nil
+	}
+	return content
+}
+
+// buildMessage renders the templates into a MIME email,
+// with the results file attached when it is small enough.
+func (n *EmailNotifier) buildMessage(taskDetails models.ReconTaskDetails, event models.TaskEvent) ([]byte, error) {
+	data := EmailTemplateData{
+		Event:   event,
+		Task:    summariseTask(taskDetails),
+		Results: taskDetails.Summary,
+		Links:   taskLinks(n.publicBaseURL, taskDetails),
+	}
+
+	// only a csv results file is attached
+	var attachment []byte
+	if len(taskDetails.ResultsFilePath) > 0 && taskDetails.MainResultsFormat() == results_format.Csv && taskDetails.Files != nil {
+		attachment = n.readAttachment(taskDetails)
+		data.IsResultsFileAttached = attachment != nil
+	}
+
+	var subject, body bytes.Buffer
+	err := n.subjectTemplate.Execute(&subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("error on rendering email subject: [%v]", err)
+	}
+	err = n.bodyTemplate.Execute(&body, data)
+	if err != nil {
+		return nil, fmt.Errorf("error on rendering email body: [%v]", err)
+	}
+
+	var message bytes.Buffer
+	parts := multipart.NewWriter(&message)
+
+	// a line break in the subject would start a new header
+	subjectLine := strings.Join(strings.Fields(subject.String()), " ")
+	fmt.Fprintf(&message, "From: %v\r\n", n.from)
+	fmt.Fprintf(&message, "To: %v\r\n", strings.Join(taskDetails.NotificationEmails, ", "))
+	fmt.Fprintf(&message, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", subjectLine))
+	fmt.Fprintf(&message, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%v\r\n\r\n", parts.Boundary())
+
+	bodyPart, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	bodyWriter := quotedprintable.NewWriter(bodyPart)
+	_, err = bodyWriter.Write(body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	err = bodyWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment != nil {
+		fileName := fmt.Sprintf("ReconResults-%v.csv", taskDetails.ID)
+		attachmentPart, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType("text/csv", map[string]string{"name": fileName})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": fileName})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeBase64Lines(attachmentPart, attachment)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = parts.Close()
+	if err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// writeBase64Lines writes the data base64 encoded in lines of 76 characters, as email requires.
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		lineLength := 76
+		if len(encoded) < lineLength {
+			lineLength = len(encoded)
+		}
+		_, err := fmt.Fprintf(w, "%v\r\n", encoded[:lineLength])
+		if err != nil {
+			return err
+		}
+		encoded = encoded[lineLength:]
+	}
+	return nil
+}
+
+// loadEmailTemplate parses the template of the given name from the EMAIL_TEMPLATES_DIRECTORY
+// and falls back to the default when the directory doesn't have one.
+func loadEmailTemplate(name string, defaultTemplate string) (*template.Template, error) {
+	text := defaultTemplate
+	if len(constants.EMAIL_TEMPLATES_DIRECTORY) > 0 {
+		contents, err := os.ReadFile(filepath.Join(constants.EMAIL_TEMPLATES_DIRECTORY, name))
+		if err == nil {
+			text = string(contents)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error on reading email template [%v]: [%v]", name, err)
+		}
+	}
+
+	parsed, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error on parsing email template [%v]: [%v]", name, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,190 @@
+package notifications
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"reconciler.io/models"
+	"reconciler.io/repositories"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testResultsFile = `column_1,column_2,ReconResult,ReconResultReasons
+TXN-1,100,Successfull,"RowMatchFound. 
+PrimaryFile Row: [0] 
+ComparisonFile Row: [1] 
+"
+TXN-2,200,Failed,"RowMismatchFound. 
+PrimaryFileRow: [1] PrimaryFileColumn: [column_2] 
+ComparisonFileRow: [0] ComparisonFileColumn: [column_2] 
+PrimaryFile value: [200] 
+ComparisonFile value: [201]
+"
+TXN-3,300,Failed,"RowMismatchFound. 
+PrimaryFileRow: [2] PrimaryFileColumn: [column_2] 
+ComparisonFileRow: [2] ComparisonFileColumn: [column_2] 
+PrimaryFile value: [300] 
+ComparisonFile value: [999]
+"
+TXN-4,400,Failed,no matching record found in the entire comparison file
+`
+
+// sentEmails stands in for smtp.SendMail, failing the first failSends emails
+type sentEmails struct {
+	failSends  int
+	attempts   int
+	recipients [][]string
+	messages   [][]byte
+	mu         sync.Mutex
+}
+
+func (s *sentEmails) send(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failSends {
+		return errors.New("421 service not available")
+	}
+	s.recipients = append(s.recipients, to)
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *sentEmails) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+// storeTestResultsFile puts the test results file in a file store of its own that the tasks of the repository keep their files in
+func storeTestResultsFile(t *testing.T, taskRepo *repositories.TaskDetailsRepository) string {
+	files := models.NewLocalFileStore(t.TempDir())
+	taskRepo.StoreFilesIn(files)
+
+	key := "users/user_1/ReconResults.csv"
+	assert.NoError(t, files.Put(context.Background(), key, strings.NewReader(testResultsFile)))
+	return key
+}
+
+func newTestEmailNotifier(t *testing.T, recipients []string) (*EmailNotifier, *sentEmails, *repositories.TaskDetailsRepository, string) {
+	taskRepo := repositories.NewTaskDetailsRepository(nil)
+	notifier, err := NewEmailNotifier(taskRepo)
+	assert.NoError(t, err)
+
+	sent := &sentEmails{}
+	notifier.sendMail = sent.send
+	notifier.initialBackoff = 10 * time.Millisecond
+	taskRepo.OnTaskEvent(notifier.HandleTaskEvent)
+	t.Cleanup(func() {
+		_ = notifier.Close(context.Background())
+	})
+
+	taskID, err := taskRepo.SaveTaskDetails(context.Background(), models.ReconTaskDetails{NotificationEmails: recipients})
+	assert.NoError(t, err)
+	return notifier, sent, taskRepo, taskID
+}
+
+// readEmail splits a sent email into its subject, text body and attachments by file name
+func readEmail(t *testing.T, message []byte) (string, string, map[string]string) {
+	parsed, err := mail.ReadMessage(strings.NewReader(string(message)))
+	assert.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	assert.NoError(t, err)
+
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+
+	body := ""
+	attachments := make(map[string]string)
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+
+		if len(part.FileName()) == 0 {
+			decoded, err := io.ReadAll(quotedprintable.NewReader(part))
+			assert.NoError(t, err)
+			body = string(decoded)
+			continue
+		}
+		decoded, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		assert.NoError(t, err)
+		attachments[part.FileName()] = string(decoded)
+	}
+	return subject, body, attachments
+}
+
+func TestCompletedTaskIsEmailedWithItsResultsAttached(t *testing.T) {
+	_, sent, taskRepo, taskID := newTestEmailNotifier(t, []string{"ops@example.com", "finance@example.com"})
+	assert.NoError(t, taskRepo.SaveReconSummary(context.Background(), taskID, models.ReconSummary{
+		PrimaryFileRows:           4,
+		MatchedRows:               1,
+		MismatchedRows:            2,
+		MissingFromComparisonFile: 1,
+		TopMismatchingColumns:     []models.ColumnMismatchCount{{Column: "column_2", Rows: 2}},
+	}))
+	completeTask(t, taskRepo, taskID, storeTestResultsFile(t, taskRepo))
+
+	assert.Eventually(t, func() bool { return sent.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ops@example.com", "finance@example.com"}, sent.recipients[0])
+
+	subject, body, attachments := readEmail(t, sent.messages[0])
+	assert.Equal(t, "Reconciliation task "+taskID+" Completed", subject)
+	assert.Contains(t, body, "Rows reconciled: 4")
+	assert.Contains(t, body, "Mismatched: 2")
+	assert.Contains(t, body, "Missing from the comparison file: 1")
+	assert.Contains(t, body, "2 x column_2")
+	assert.Contains(t, body, "/tasks/"+taskID+"/results/download")
+	assert.Contains(t, body, "The results file is attached.")
+	assert.Equal(t, testResultsFile, attachments["ReconResults-"+taskID+".csv"])
+}
+
+func TestLargeResultsAreLinkedRatherThanAttached(t *testing.T) {
+	notifier, sent, taskRepo, taskID := newTestEmailNotifier(t, []string{"ops@example.com"})
+	notifier.maxAttachmentBytes = 10
+	completeTask(t, taskRepo, taskID, storeTestResultsFile(t, taskRepo))
+
+	assert.Eventually(t, func() bool { return sent.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	_, body, attachments := readEmail(t, sent.messages[0])
+	assert.Contains(t, body, "/tasks/"+taskID+"/results/download")
+	assert.NotContains(t, body, "Rows reconciled")
+	assert.NotContains(t, body, "The results file is attached.")
+	assert.Empty(t, attachments)
+}
+
+func TestFailedEmailIsRetried(t *testing.T) {
+	_, sent, taskRepo, taskID := newTestEmailNotifier(t, []string{"ops@example.com"})
+	sent.failSends = 2
+	assert.NoError(t, taskRepo.FailReconciliationTask(context.Background(), taskID, errors.New("comparison file is empty")))
+
+	assert.Eventually(t, func() bool { return sent.count() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3, sent.attempts)
+
+	subject, body, _ := readEmail(t, sent.messages[0])
+	assert.Equal(t, "Reconciliation task "+taskID+" Failed", subject)
+	assert.Contains(t, body, "Error: comparison file is empty")
+}
+
+func TestTaskWithoutRecipientsIsNotEmailed(t *testing.T) {
+	notifier, sent, taskRepo, taskID := newTestEmailNotifier(t, nil)
+	completeTask(t, taskRepo, taskID, storeTestResultsFile(t, taskRepo))
+
+	// once the notifier is closed the send it started has finished
+	assert.NoError(t, notifier.Close(context.Background()))
+	assert.Equal(t, 0, sent.attempts)
+}
@@ -0,0 +1,38 @@
+package notifications
+
+import (
+	"fmt"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/results_format"
+)
+
+func taskLinks(publicBaseURL string, taskDetails models.ReconTaskDetails) models.TaskLinks {
+	links := models.TaskLinks{
+		Task: fmt.Sprintf("%v/tasks/%v", publicBaseURL, taskDetails.ID),
+	}
+	if len(taskDetails.ResultsFilePath) > 0 {
+		links.Results = fmt.Sprintf("%v/tasks/%v/results/download", publicBaseURL, taskDetails.ID)
+	}
+	// the download is a csv unless the task was asked for something else in its place
+	if len(links.Results) > 0 && taskDetails.MainResultsFormat() != results_format.Csv {
+		links.Results += fmt.Sprintf("?format=%v", taskDetails.MainResultsFormat())
+	}
+	return links
+}
+
+func summariseTask(taskDetails models.ReconTaskDetails) models.TaskSummary {
+	summary := models.TaskSummary{
+		ID:        taskDetails.ID,
+		UserID:    taskDetails.UserID,
+		Status:    taskDetails.Status,
+		Error:     taskDetails.Error,
+		CreatedAt: taskDetails.CreatedAt,
+		UpdatedAt: taskDetails.UpdatedAt,
+	}
+	if taskDetails.Progress != nil {
+		summary.SectionsReconciled = taskDetails.Progress.SectionsReconciled
+		summary.RowsMatched = taskDetails.Progress.RowsMatched
+		summary.RowsFailed = taskDetails.Progress.RowsFailed
+	}
+	return summary
+}
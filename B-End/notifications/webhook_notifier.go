@@ -0,0 +1,252 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/webhook_delivery_status"
+	"reconciler.io/repositories"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 of the payload, see SignWebhookPayload
+var WebhookSignatureHeader = "X-Reconciler-Signature"
+var WebhookEventHeader = "X-Reconciler-Event"
+var WebhookDeliveryHeader = "X-Reconciler-Delivery"
+
+// WebhookNotifier calls the webhooks registered on a task when the task changes.
+// Every call is logged in the WebhookDeliveryRepository and failed calls are retried with exponential backoff.
+type WebhookNotifier struct {
+	taskDetailsRepo *repositories.TaskDetailsRepository
+	deliveryRepo    *repositories.WebhookDeliveryRepository
+	client          *http.Client
+	signingSecret   []byte
+	publicBaseURL   string
+	maxAttempts     int
+	initialBackoff  time.Duration
+	sends           *backgroundSends
+}
+
+func NewWebhookNotifier(
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	deliveryRepo *repositories.WebhookDeliveryRepository,
+) *WebhookNotifier {
+	if len(constants.WEBHOOK_SIGNING_SECRET) == 0 {
+		log.Printf("no webhook signing secret is set, webhooks are not called until RECONCILER_WEBHOOK_SIGNING_SECRET is set")
+	}
+	return &WebhookNotifier{
+		taskDetailsRepo: taskDetailsRepo,
+		deliveryRepo:    deliveryRepo,
+		client:          newWebhookClient(constants.WEBHOOK_ALLOW_PRIVATE_HOSTS),
+		signingSecret:   []byte(constants.WEBHOOK_SIGNING_SECRET),
+		publicBaseURL:   constants.PUBLIC_BASE_URL,
+		maxAttempts:     constants.WEBHOOK_MAX_ATTEMPTS,
+		initialBackoff:  constants.WEBHOOK_INITIAL_BACKOFF,
+		sends:           newBackgroundSends(),
+	}
+}
+
+// newWebhookClient is the client webhooks are called with, it refuses to connect to private addresses
+// unless they are allowed so that a host name can't be pointed at the internal network after it was registered.
+// It doesn't go through a proxy, which would make the connections for it
+func newWebhookClient(allowPrivateHosts bool) *http.Client {
+	dialer := &net.Dialer{Timeout: constants.WEBHOOK_REQUEST_TIMEOUT}
+	if !allowPrivateHosts {
+		dialer.Control = refusePrivateAddresses
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: constants.WEBHOOK_REQUEST_TIMEOUT, Transport: transport}
+}
+
+// refusePrivateAddresses stops the dialer before it connects to a private address
+func refusePrivateAddresses(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || models.IsPrivateAddress(ip) {
+		return fmt.Errorf("webhooks can not be called on the private address [%v]", host)
+	}
+	return nil
+}
+
+// HandleTaskEvent calls the webhooks of the task that want the event, in the background.
+// It is meant to be registered with TaskDetailsRepository.OnTaskEvent.
+func (n *WebhookNotifier) HandleTaskEvent(event models.TaskEvent) {
+	if !models.CanBeSentToWebhooks(event.Type) {
+		return
+	}
+
+	isStarted := n.sends.start(func() {
+		n.notify(event)
+	})
+	if !isStarted {
+		log.Printf("webhook notifier is stopped, not calling webhooks of task [%v] for [%v]", event.TaskID, event.Type)
+	}
+}
+
+// Close stops retrying failed calls and waits for the calls that are
+// in flight to finish or the context to be done.
+// Deliveries that were waiting to be retried are left Pending in the log.
+func (n *WebhookNotifier) Close(ctx context.Context) error {
+	return n.sends.close(ctx)
+}
+
+func (n *WebhookNotifier) notify(event models.TaskEvent) {
+	taskDetails, err := n.taskDetailsRepo.GetReconciliationTaskStatus(context.Background(), event.TaskID)
+
+	//error on retrieve
+	if err != nil {
+		log.Printf("unable to call webhooks of task [%v]: %v", event.TaskID, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, webhook := range taskDetails.Webhooks {
+		if !webhook.IsCalledFor(event.Type) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(webhook models.Webhook) {
+			defer wg.Done()
+			n.deliver(taskDetails, webhook, event)
+		}(webhook)
+	}
+	wg.Wait()
+}
+
+// deliver POSTs the event to the webhook until it is accepted,
+// the attempts run out or the notifier is closed.
+func (n *WebhookNotifier) deliver(taskDetails models.ReconTaskDetails, webhook models.Webhook, event models.TaskEvent) {
+	ctx := context.Background()
+	delivery := models.WebhookDelivery{
+		ID:        uuid.New().String(),
+		TaskID:    taskDetails.ID,
+		WebhookID: webhook.ID,
+		URL:       webhook.URL,
+		EventType: event.Type,
+	}
+
+	err := n.deliveryRepo.SaveWebhookDelivery(ctx, delivery)
+
+	//error on save
+	if err != nil {
+		log.Printf("unable to log webhook delivery for task [%v]: %v", taskDetails.ID, err)
+		return
+	}
+
+	//a payload signed with an empty key could have been signed by anyone
+	if len(n.signingSecret) == 0 {
+		attempt := models.WebhookDeliveryAttempt{
+			AttemptNumber: 1,
+			Error:         "no webhook signing secret is set, the payload can not be signed",
+			At:            time.Now(),
+		}
+		err = n.deliveryRepo.RecordWebhookDeliveryAttempt(ctx, delivery.ID, attempt, webhook_delivery_status.Failed)
+		if err != nil {
+			log.Printf("unable to log webhook delivery attempt [%v]: %v", delivery.ID, err)
+		}
+		log.Printf("webhook delivery [%v] to [%v] for task [%v] is [%v]: %v", delivery.ID, webhook.URL, taskDetails.ID, webhook_delivery_status.Failed, attempt.Error)
+		return
+	}
+
+	body, err := json.Marshal(models.WebhookPayload{
+		DeliveryID: delivery.ID,
+		Event:      event,
+		Task:       summariseTask(taskDetails),
+		Links:      taskLinks(n.publicBaseURL, taskDetails),
+	})
+
+	//error on encoding
+	if err != nil {
+		log.Printf("unable to encode webhook payload for task [%v]: %v", taskDetails.ID, err)
+		return
+	}
+
+	backoff := n.initialBackoff
+	for attemptNumber := 1; ; attemptNumber++ {
+		attempt := n.post(webhook.URL, delivery, body)
+		attempt.AttemptNumber = attemptNumber
+
+		status := webhook_delivery_status.Pending
+		if len(attempt.Error) == 0 {
+			status = webhook_delivery_status.Delivered
+		} else if attemptNumber >= n.maxAttempts {
+			status = webhook_delivery_status.Failed
+		}
+
+		err = n.deliveryRepo.RecordWebhookDeliveryAttempt(ctx, delivery.ID, attempt, status)
+		if err != nil {
+			log.Printf("unable to log webhook delivery attempt [%v]: %v", delivery.ID, err)
+		}
+
+		if status != webhook_delivery_status.Pending {
+			log.Printf("webhook delivery [%v] to [%v] for task [%v] is [%v]", delivery.ID, webhook.URL, taskDetails.ID, status)
+			return
+		}
+
+		if !n.sends.waitBeforeRetry(backoff) {
+			log.Printf("webhook delivery [%v] for task [%v] stopped before it could be retried", delivery.ID, taskDetails.ID)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single call to the webhook, any response other than a 2xx is a failure.
+func (n *WebhookNotifier) post(url string, delivery models.WebhookDelivery, body []byte) models.WebhookDeliveryAttempt {
+	startedAt := time.Now()
+	attempt := models.WebhookDeliveryAttempt{At: startedAt}
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(n.signingSecret, body))
+
+	response, err := n.client.Do(request)
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.DurationMillis = time.Since(startedAt).Milliseconds()
+		return attempt
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("webhook responded with status [%v]", response.StatusCode)
+	}
+	attempt.DurationMillis = time.Since(startedAt).Milliseconds()
+	return attempt
+}
+
+// SignWebhookPayload is the value of the signature header sent with a payload,
+// receivers compute the same value over the raw request body to check where it came from.
+func SignWebhookPayload(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,203 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/task_status"
+	"reconciler.io/models/enums/webhook_delivery_status"
+	"reconciler.io/repositories"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookStandIn records the calls made to it and fails the first failCalls of them
+type webhookStandIn struct {
+	failCalls int
+	calls     []*http.Request
+	bodies    [][]byte
+	mu        sync.Mutex
+}
+
+func (s *webhookStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	s.calls = append(s.calls, r)
+	s.bodies = append(s.bodies, body)
+	if len(s.calls) <= s.failCalls {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *webhookStandIn) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+func newTestNotifier(t *testing.T, webhooks []models.Webhook) (*WebhookNotifier, *repositories.TaskDetailsRepository, *repositories.WebhookDeliveryRepository, string) {
+	taskRepo := repositories.NewTaskDetailsRepository(nil)
+	deliveryRepo := repositories.NewWebhookDeliveryRepository()
+
+	notifier := NewWebhookNotifier(taskRepo, deliveryRepo)
+	// the stand ins listen on loopback
+	notifier.client = newWebhookClient(true)
+	notifier.signingSecret = []byte("test-secret")
+	notifier.initialBackoff = 10 * time.Millisecond
+	notifier.maxAttempts = 3
+	taskRepo.OnTaskEvent(notifier.HandleTaskEvent)
+	t.Cleanup(func() {
+		_ = notifier.Close(context.Background())
+	})
+
+	taskID, err := taskRepo.SaveTaskDetails(context.Background(), models.ReconTaskDetails{Webhooks: webhooks})
+	assert.NoError(t, err)
+	return notifier, taskRepo, deliveryRepo, taskID
+}
+
+func completeTask(t *testing.T, taskRepo *repositories.TaskDetailsRepository, taskID string, resultsFilePath string) {
+	ctx := context.Background()
+	assert.NoError(t, taskRepo.AttachPrimaryFile(ctx, taskID, "primary"))
+	assert.NoError(t, taskRepo.AttachComparisonFile(ctx, taskID, "comparison"))
+	assert.NoError(t, taskRepo.TransitionReconciliationTask(ctx, taskID, task_status.Reconciling))
+	assert.NoError(t, taskRepo.TransitionReconciliationTask(ctx, taskID, task_status.Reconstructing))
+	assert.NoError(t, taskRepo.CompleteReconciliationTask(ctx, taskID, resultsFilePath))
+}
+
+// taskDeliveries returns the deliveries logged for the task
+func taskDeliveries(t *testing.T, deliveryRepo *repositories.WebhookDeliveryRepository, taskID string) []models.WebhookDelivery {
+	deliveries, err := deliveryRepo.GetWebhookDeliveriesForTask(context.Background(), taskID)
+	assert.NoError(t, err)
+	return deliveries
+}
+
+func TestWebhookIsRetriedUntilItIsDelivered(t *testing.T) {
+	standIn := &webhookStandIn{failCalls: 1}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	_, taskRepo, deliveryRepo, taskID := newTestNotifier(t, []models.Webhook{{URL: server.URL}})
+	completeTask(t, taskRepo, taskID, "/tmp/ReconResults.csv")
+
+	assert.Eventually(t, func() bool {
+		deliveries := taskDeliveries(t, deliveryRepo, taskID)
+		return len(deliveries) == 1 && deliveries[0].Status == webhook_delivery_status.Delivered
+	}, 2*time.Second, 10*time.Millisecond)
+
+	delivery := taskDeliveries(t, deliveryRepo, taskID)[0]
+	assert.Equal(t, task_event_type.TaskCompleted, delivery.EventType)
+	assert.Equal(t, "webhook_1", delivery.WebhookID)
+	assert.Len(t, delivery.Attempts, 2)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode)
+	assert.NotEmpty(t, delivery.Attempts[0].Error)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[1].StatusCode)
+
+	// only the completion is sent to a webhook that didn't pick its events
+	assert.Equal(t, 2, standIn.callCount())
+	request, body := standIn.calls[1], standIn.bodies[1]
+	assert.Equal(t, SignWebhookPayload([]byte("test-secret"), body), request.Header.Get(WebhookSignatureHeader))
+	assert.Equal(t, delivery.ID, request.Header.Get(WebhookDeliveryHeader))
+	assert.Equal(t, "TaskCompleted", request.Header.Get(WebhookEventHeader))
+
+	var payload models.WebhookPayload
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, delivery.ID, payload.DeliveryID)
+	assert.Equal(t, task_status.Completed, payload.Task.Status)
+	assert.Contains(t, payload.Links.Results, "/tasks/"+taskID+"/results/download")
+}
+
+func TestWebhookDeliveryFailsOnceItsAttemptsRunOut(t *testing.T) {
+	standIn := &webhookStandIn{failCalls: 100}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	_, taskRepo, deliveryRepo, taskID := newTestNotifier(t, []models.Webhook{
+		{URL: server.URL, Events: []task_event_type.TaskEventType{task_event_type.TaskFailed}},
+	})
+	assert.NoError(t, taskRepo.FailReconciliationTask(context.Background(), taskID, errors.New("boom")))
+
+	assert.Eventually(t, func() bool {
+		deliveries := taskDeliveries(t, deliveryRepo, taskID)
+		return len(deliveries) == 1 && deliveries[0].Status == webhook_delivery_status.Failed
+	}, 2*time.Second, 10*time.Millisecond)
+
+	delivery := taskDeliveries(t, deliveryRepo, taskID)[0]
+	assert.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, 3, standIn.callCount())
+
+	// the backoff doubles after every attempt
+	firstWait := delivery.Attempts[1].At.Sub(delivery.Attempts[0].At)
+	secondWait := delivery.Attempts[2].At.Sub(delivery.Attempts[1].At)
+	assert.GreaterOrEqual(t, firstWait, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, secondWait, 20*time.Millisecond)
+}
+
+func TestWebhookIsOnlyCalledForTheEventsItPicked(t *testing.T) {
+	standIn := &webhookStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	notifier, taskRepo, deliveryRepo, taskID := newTestNotifier(t, []models.Webhook{
+		{URL: server.URL, Events: []task_event_type.TaskEventType{task_event_type.TaskPaused}},
+	})
+	assert.NoError(t, taskRepo.PauseReconciliationTask(context.Background(), taskID))
+	_, err := taskRepo.CancelReconciliationTask(context.Background(), taskID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, notifier.Close(context.Background()))
+	deliveries := taskDeliveries(t, deliveryRepo, taskID)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, task_event_type.TaskPaused, deliveries[0].EventType)
+	assert.Equal(t, 1, standIn.callCount())
+}
+
+func TestWebhookIsNotCalledOnAPrivateAddress(t *testing.T) {
+	standIn := &webhookStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	notifier, taskRepo, deliveryRepo, taskID := newTestNotifier(t, []models.Webhook{{URL: server.URL}})
+	notifier.client = newWebhookClient(false)
+	assert.NoError(t, taskRepo.FailReconciliationTask(context.Background(), taskID, errors.New("boom")))
+
+	assert.Eventually(t, func() bool {
+		deliveries := taskDeliveries(t, deliveryRepo, taskID)
+		return len(deliveries) == 1 && deliveries[0].Status == webhook_delivery_status.Failed
+	}, 2*time.Second, 10*time.Millisecond)
+
+	delivery := taskDeliveries(t, deliveryRepo, taskID)[0]
+	assert.Contains(t, delivery.Attempts[0].Error, "private address")
+	assert.Equal(t, 0, standIn.callCount())
+}
+
+func TestWebhookIsNotCalledWithoutASigningSecret(t *testing.T) {
+	standIn := &webhookStandIn{}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	notifier, taskRepo, deliveryRepo, taskID := newTestNotifier(t, []models.Webhook{{URL: server.URL}})
+	notifier.signingSecret = nil
+	assert.NoError(t, taskRepo.FailReconciliationTask(context.Background(), taskID, errors.New("boom")))
+
+	assert.Eventually(t, func() bool {
+		deliveries := taskDeliveries(t, deliveryRepo, taskID)
+		return len(deliveries) == 1 && deliveries[0].Status == webhook_delivery_status.Failed
+	}, 2*time.Second, 10*time.Millisecond)
+
+	delivery := taskDeliveries(t, deliveryRepo, taskID)[0]
+	assert.Len(t, delivery.Attempts, 1)
+	assert.Contains(t, delivery.Attempts[0].Error, "signing secret")
+	assert.Equal(t, 0, standIn.callCount())
+}
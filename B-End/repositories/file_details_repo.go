@@ -3,20 +3,33 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"reconciler.io/models"
 	"reconciler.io/models/enums/file_purpose"
 	"sync"
 )
 
 type FileDetailsRepository struct {
-	fileDetailsMap   map[string]*models.FileToBeRead
+	store            FileDetailsStore
+	fileStreams      map[string]models.StreamProvider
 	fileDetailsMutex sync.Mutex
+	checkpoints      *TaskCheckpointRepository
 }
 
-func NewFileDetailsRepository() *FileDetailsRepository {
+// NewFileDetailsRepository creates the repository with its files kept in memory. When checkpoints is not nil
+// every saved file is also written to its task's checkpoint.
+func NewFileDetailsRepository(checkpoints *TaskCheckpointRepository) *FileDetailsRepository {
+	return NewFileDetailsRepositoryWithStore(NewInMemoryFileDetailsStore(), checkpoints)
+}
+
+// NewFileDetailsRepositoryWithStore creates the repository with its files kept in the given store.
+func NewFileDetailsRepositoryWithStore(store FileDetailsStore, checkpoints *TaskCheckpointRepository) *FileDetailsRepository {
 	return &FileDetailsRepository{
-		fileDetailsMap: make(map[string]*models.FileToBeRead),
+		store:       store,
+		fileStreams: make(map[string]models.StreamProvider),
+		checkpoints: checkpoints,
 	}
 }
 
@@ -32,62 +45,116 @@ func (m *FileDetailsRepository) SaveFileToBeRead(ctx context.Context, fileToBeRe
 	defer m.fileDetailsMutex.Unlock()
 
 	if len(fileToBeRead.ID) <= 0 {
-		fileId := "file_" + string(rune(len(m.fileDetailsMap)+1))
-		fileToBeRead.ID = fileId
+		fileToBeRead.ID = "file_" + uuid.New().String()
 	}
 
-	m.fileDetailsMap[fileToBeRead.ID] = &fileToBeRead
+	err := m.saveLocked(ctx, fileToBeRead)
+	if err != nil {
+		return "", err
+	}
 
 	return fileToBeRead.ID, nil
 }
 
+// saveLocked keeps the stream of the file for as long as this service runs
+// and writes the rest of the file to the store and the checkpoint
+func (m *FileDetailsRepository) saveLocked(ctx context.Context, fileToBeRead models.FileToBeRead) error {
+	if fileToBeRead.ReadFileResultsStream != nil {
+		m.fileStreams[fileToBeRead.ID] = fileToBeRead.ReadFileResultsStream
+	}
+
+	fileToBeRead.ReadFileResultsStream = nil
+	err := m.store.SaveFileDetails(ctx, fileToBeRead)
+	if err != nil {
+		return err
+	}
+
+	return m.saveCheckpoint(ctx, fileToBeRead)
+}
+
+func (m *FileDetailsRepository) saveCheckpoint(ctx context.Context, fileToBeRead models.FileToBeRead) error {
+	if m.checkpoints == nil {
+		return nil
+	}
+
+	err := m.checkpoints.SaveFileDetails(ctx, fileToBeRead)
+	if err != nil {
+		return fmt.Errorf("error on saving file checkpoint: [%v]", err)
+	}
+	return nil
+}
+
 func (m *FileDetailsRepository) UpdateFileDetails(ctx context.Context, fileToBeRead models.FileToBeRead) error {
 	m.fileDetailsMutex.Lock()
 	defer m.fileDetailsMutex.Unlock()
 
-	if _, exists := m.fileDetailsMap[fileToBeRead.ID]; !exists {
-		return errors.New("task not found")
+	_, err := m.store.GetFileDetails(ctx, fileToBeRead.ID)
+	if err != nil {
+		return err
 	}
 
-	m.fileDetailsMap[fileToBeRead.ID] = &fileToBeRead
-
-	return nil
+	return m.saveLocked(ctx, fileToBeRead)
 }
 
 func (m *FileDetailsRepository) GetFileDetails(ctx context.Context, Id string) (*models.FileToBeRead, error) {
 	m.fileDetailsMutex.Lock()
 	defer m.fileDetailsMutex.Unlock()
 
-	file, exists := m.fileDetailsMap[Id]
-	if !exists {
-		return nil, errors.New("task not found")
+	file, err := m.store.GetFileDetails(ctx, Id)
+	if err != nil {
+		return nil, err
 	}
 
-	return file, nil
+	file = m.withStreamLocked(file)
+	return &file, nil
 }
 
 func (m *FileDetailsRepository) GetPrimaryFileDetailsForTask(ctx context.Context, TaskId string) (models.FileToBeRead, error) {
 	m.fileDetailsMutex.Lock()
 	defer m.fileDetailsMutex.Unlock()
 
-	for _, file := range m.fileDetailsMap {
-		if file.ReconciliationTaskID == TaskId &&
-			file.FilePurpose == file_purpose.PrimaryFile {
-			return *file, nil
-		}
+	file, err := m.store.GetFileDetailsForTask(ctx, TaskId, file_purpose.PrimaryFile)
+	if errors.Is(err, ErrFileNotFound) {
+		return models.FileToBeRead{}, errors.New("primaryFile for Task not found")
 	}
-	return models.FileToBeRead{}, errors.New("primaryFile for Task not found")
+	if err != nil {
+		return models.FileToBeRead{}, err
+	}
+	return m.withStreamLocked(file), nil
 }
 
 func (m *FileDetailsRepository) GetComparisonFileDetailsForTask(ctx context.Context, TaskId string) (models.FileToBeRead, error) {
 	m.fileDetailsMutex.Lock()
 	defer m.fileDetailsMutex.Unlock()
 
-	for _, file := range m.fileDetailsMap {
-		if file.ReconciliationTaskID == TaskId &&
-			file.FilePurpose == file_purpose.ComparisonFile {
-			return *file, nil
-		}
+	file, err := m.store.GetFileDetailsForTask(ctx, TaskId, file_purpose.ComparisonFile)
+	if errors.Is(err, ErrFileNotFound) {
+		return models.FileToBeRead{}, errors.New("comparisonFile for Task not found")
+	}
+	if err != nil {
+		return models.FileToBeRead{}, err
+	}
+	return m.withStreamLocked(file), nil
+}
+
+// GetAllFileDetails returns every file in the store, only the files
+// this service has read or is reading come with their stream.
+func (m *FileDetailsRepository) GetAllFileDetails(ctx context.Context) ([]models.FileToBeRead, error) {
+	m.fileDetailsMutex.Lock()
+	defer m.fileDetailsMutex.Unlock()
+
+	files, err := m.store.GetAllFileDetails(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return models.FileToBeRead{}, errors.New("comparisonFile for Task not found")
+
+	for i := range files {
+		files[i] = m.withStreamLocked(files[i])
+	}
+	return files, nil
+}
+
+func (m *FileDetailsRepository) withStreamLocked(file models.FileToBeRead) models.FileToBeRead {
+	file.ReadFileResultsStream = m.fileStreams[file.ID]
+	return file
 }
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"sort"
+	"sync"
+)
+
+var ErrFileNotFound = errors.New("file not found")
+
+// FileDetailsStore is where the FileDetailsRepository keeps the files of tasks.
+// The stream a file is read into belongs to the service that is reading it and is not kept.
+type FileDetailsStore interface {
+	SaveFileDetails(ctx context.Context, fileToBeRead models.FileToBeRead) error
+	// GetFileDetails and GetFileDetailsForTask return ErrFileNotFound when there is no such file.
+	GetFileDetails(ctx context.Context, fileID string) (models.FileToBeRead, error)
+	GetFileDetailsForTask(ctx context.Context, taskID string, filePurpose file_purpose.FilePurposeType) (models.FileToBeRead, error)
+	GetAllFileDetails(ctx context.Context) ([]models.FileToBeRead, error)
+}
+
+// InMemoryFileDetailsStore keeps the files in a map, they are gone once the service stops.
+type InMemoryFileDetailsStore struct {
+	filesMap   map[string]models.FileToBeRead
+	filesMutex sync.Mutex
+}
+
+func NewInMemoryFileDetailsStore() *InMemoryFileDetailsStore {
+	return &InMemoryFileDetailsStore{
+		filesMap: make(map[string]models.FileToBeRead),
+	}
+}
+
+func (s *InMemoryFileDetailsStore) SaveFileDetails(ctx context.Context, fileToBeRead models.FileToBeRead) error {
+	s.filesMutex.Lock()
+	defer s.filesMutex.Unlock()
+
+	s.filesMap[fileToBeRead.ID] = fileToBeRead
+	return nil
+}
+
+func (s *InMemoryFileDetailsStore) GetFileDetails(ctx context.Context, fileID string) (models.FileToBeRead, error) {
+	s.filesMutex.Lock()
+	defer s.filesMutex.Unlock()
+
+	file, exists := s.filesMap[fileID]
+	if !exists {
+		return models.FileToBeRead{}, ErrFileNotFound
+	}
+	return file, nil
+}
+
+func (s *InMemoryFileDetailsStore) GetFileDetailsForTask(ctx context.Context, taskID string, filePurpose file_purpose.FilePurposeType) (models.FileToBeRead, error) {
+	s.filesMutex.Lock()
+	defer s.filesMutex.Unlock()
+
+	for _, file := range s.filesMap {
+		if file.ReconciliationTaskID == taskID && file.FilePurpose == filePurpose {
+			return file, nil
+		}
+	}
+	return models.FileToBeRead{}, ErrFileNotFound
+}
+
+func (s *InMemoryFileDetailsStore) GetAllFileDetails(ctx context.Context) ([]models.FileToBeRead, error) {
+	s.filesMutex.Lock()
+	defer s.filesMutex.Unlock()
+
+	files := make([]models.FileToBeRead, 0, len(s.filesMap))
+	for _, file := range s.filesMap {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ID < files[j].ID
+	})
+	return files, nil
+}
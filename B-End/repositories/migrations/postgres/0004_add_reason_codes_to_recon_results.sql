@@ -0,0 +1 @@
+ALTER TABLE recon_results ADD COLUMN reason_codes TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,22 @@
+CREATE TABLE recon_task_ids (
+    id INTEGER PRIMARY KEY AUTOINCREMENT
+);
+
+CREATE TABLE recon_tasks (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    details    TEXT NOT NULL
+);
+
+CREATE TABLE files_to_be_read (
+    id           TEXT PRIMARY KEY,
+    task_id      TEXT NOT NULL,
+    file_purpose TEXT NOT NULL,
+    file_path    TEXT NOT NULL,
+    details      TEXT NOT NULL
+);
+
+CREATE INDEX files_to_be_read_task_id ON files_to_be_read (task_id, file_purpose);
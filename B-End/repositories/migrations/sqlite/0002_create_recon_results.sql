@@ -0,0 +1,11 @@
+CREATE TABLE recon_results (
+    task_id            TEXT NOT NULL,
+    primary_row_number INTEGER NOT NULL,
+    status             TEXT NOT NULL,
+    diff_columns       TEXT NOT NULL,
+    searchable_values  TEXT NOT NULL,
+    details            TEXT NOT NULL,
+    PRIMARY KEY (task_id, primary_row_number)
+);
+
+CREATE INDEX recon_results_status ON recon_results (task_id, status, primary_row_number);
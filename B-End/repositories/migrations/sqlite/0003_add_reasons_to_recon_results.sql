@@ -0,0 +1,3 @@
+ALTER TABLE recon_results ADD COLUMN reasons TEXT NOT NULL DEFAULT '[]';
+
+UPDATE recon_results SET reasons = lower(COALESCE(json_extract(details, '$.Reasons'), '[]'));
@@ -0,0 +1,8 @@
+CREATE TABLE task_checkpoints (
+    task_id    TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    checkpoint TEXT NOT NULL
+);
+
+CREATE INDEX task_checkpoints_owner ON task_checkpoints (owner);
@@ -0,0 +1,8 @@
+CREATE TABLE webhook_deliveries (
+    id         TEXT PRIMARY KEY,
+    task_id    TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    details    TEXT NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_task_id ON webhook_deliveries (task_id, created_at);
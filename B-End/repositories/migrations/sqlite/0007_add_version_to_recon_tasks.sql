@@ -0,0 +1 @@
+ALTER TABLE recon_tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"sort"
+	"sync"
+)
+
+// ReconResultsRepository keeps the result of every reconciled row,
+// the reconstruction writes to it and the results can be queried without the results file.
+type ReconResultsRepository interface {
+	models.ReconResultRecorder
+	QueryReconResults(ctx context.Context, query models.ReconResultsQuery) (models.ReconResultsPage, error)
+}
+
+func ReconResultsRepositoryMiddleware(repo ReconResultsRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("ReconResultsRepository", repo)
+		c.Next()
+	}
+}
+
+// InMemoryReconResultsRepository keeps the results in a map, they are gone once the service stops.
+type InMemoryReconResultsRepository struct {
+	resultsMap   map[string]map[uint64]models.ReconResult
+	resultsMutex sync.Mutex
+}
+
+func NewInMemoryReconResultsRepository() *InMemoryReconResultsRepository {
+	return &InMemoryReconResultsRepository{
+		resultsMap: make(map[string]map[uint64]models.ReconResult),
+	}
+}
+
+func (r *InMemoryReconResultsRepository) DeleteReconResults(ctx context.Context, taskID string) error {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
+
+	delete(r.resultsMap, taskID)
+	return nil
+}
+
+func (r *InMemoryReconResultsRepository) SaveReconResults(ctx context.Context, taskID string, results []models.ReconResult) error {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
+
+	taskResults, exists := r.resultsMap[taskID]
+	if !exists {
+		taskResults = make(map[uint64]models.ReconResult)
+		r.resultsMap[taskID] = taskResults
+	}
+
+	for _, result := range results {
+		result.TaskID = taskID
+		taskResults[result.RowNumber] = result
+	}
+	return nil
+}
+
+func (r *InMemoryReconResultsRepository) QueryReconResults(ctx context.Context, query models.ReconResultsQuery) (models.ReconResultsPage, error) {
+	r.resultsMutex.Lock()
+	defer r.resultsMutex.Unlock()
+
+	query = withPaging(query)
+	matches := make([]models.ReconResult, 0)
+	for _, result := range r.resultsMap[query.TaskID] {
+		if isReconResultMatch(result, query) {
+			matches = append(matches, result)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].RowNumber < matches[j].RowNumber
+	})
+
+	page := models.ReconResultsPage{
+		Results:    []models.ReconResult{},
+		TotalCount: len(matches),
+		Offset:     query.Offset,
+		Limit:      query.Limit,
+	}
+	if query.Offset < len(matches) {
+		end := query.Offset + query.Limit
+		if end > len(matches) {
+			end = len(matches)
+		}
+		page.Results = matches[query.Offset:end]
+	}
+	return page, nil
+}
+
+func isReconResultMatch(result models.ReconResult, query models.ReconResultsQuery) bool {
+	if len(query.Status) > 0 && result.Status != query.Status {
+		return false
+	}
+	if len(query.Column) > 0 && !result.HasDiffInColumn(query.Column) {
+		return false
+	}
+	if len(query.Value) > 0 && !result.HasValue(query.Value) {
+		return false
+	}
+	if len(query.Reason) > 0 && !result.HasReasonContaining(query.Reason) {
+		return false
+	}
+	if len(query.ReasonCode) > 0 && !result.HasReasonCode(query.ReasonCode) {
+		return false
+	}
+	return true
+}
+
+// withPaging fills in the page size when the query has none and keeps the offset from being negative
+func withPaging(query models.ReconResultsQuery) models.ReconResultsQuery {
+	if query.Limit <= 0 {
+		query.Limit = constants.RECON_RESULTS_PAGE_SIZE
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	return query
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testReconResultsRepositories are the in-memory repository and a SQL one for every test database
+func testReconResultsRepositories(t *testing.T) map[string]ReconResultsRepository {
+	repos := map[string]ReconResultsRepository{"memory": NewInMemoryReconResultsRepository()}
+	for dialect, database := range testDatabases(t) {
+		repos[dialect] = NewSQLReconResultsRepository(database)
+	}
+	return repos
+}
+
+func testReconResults() []models.ReconResult {
+	matchedRowNumber := uint64(12)
+	return []models.ReconResult{
+		{
+			RowNumber:        3,
+			Status:           recon_status.Successfull,
+			Reasons:          []models.ReconReason{{Code: recon_reason_code.RowMatchFound, CounterpartRowNumber: &matchedRowNumber}},
+			Values:           []string{"INV-3", "300"},
+			MatchedRowNumber: &matchedRowNumber,
+		},
+		{
+			RowNumber: 1,
+			Status:    recon_status.Failed,
+			Reasons: []models.ReconReason{{
+				Code:                 recon_reason_code.RowMismatchFound,
+				PrimaryColumn:        "Amount",
+				ComparisonColumn:     "Value",
+				PrimaryValue:         "100",
+				ComparisonValue:      "150",
+				CounterpartRowNumber: &matchedRowNumber,
+			}},
+			Values:           []string{"INV-1", "100"},
+			MatchedRowNumber: &matchedRowNumber,
+		},
+		{
+			RowNumber: 2,
+			Status:    recon_status.Failed,
+			Reasons:   []models.ReconReason{{Code: recon_reason_code.NoMatchFound}},
+			Values:    []string{"INV-2", "1000"},
+		},
+	}
+}
+
+func resultRowNumbers(page models.ReconResultsPage) []uint64 {
+	rowNumbers := make([]uint64, 0, len(page.Results))
+	for _, result := range page.Results {
+		rowNumbers = append(rowNumbers, result.RowNumber)
+	}
+	return rowNumbers
+}
+
+func TestReconResultsAreFilteredAndPaged(t *testing.T) {
+	for name, repo := range testReconResultsRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, repo.SaveReconResults(ctx, "task_1", testReconResults()))
+			assert.NoError(t, repo.SaveReconResults(ctx, "task_2", testReconResults()[:1]))
+
+			page, err := repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1"})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{1, 2, 3}, resultRowNumbers(page))
+			assert.Equal(t, 3, page.TotalCount)
+			assert.Equal(t, 100, page.Limit)
+			assert.Equal(t, "task_1", page.Results[0].TaskID)
+			assert.Equal(t, uint64(12), *page.Results[0].MatchedRowNumber)
+			assert.Equal(t, "150", page.Results[0].Reasons[0].ComparisonValue)
+			assert.Nil(t, page.Results[1].MatchedRowNumber)
+
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Status: recon_status.Failed, Offset: 1, Limit: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{2}, resultRowNumbers(page))
+			assert.Equal(t, 2, page.TotalCount)
+
+			// the column can be named as it is in either file
+			for _, column := range []string{"Amount", "Value"} {
+				page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Column: column})
+				assert.NoError(t, err)
+				assert.Equal(t, []uint64{1}, resultRowNumbers(page))
+			}
+
+			// values only match whole values, including the values of the matched row that differ
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Value: "100"})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{1}, resultRowNumbers(page))
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Value: "150"})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{1}, resultRowNumbers(page))
+
+			// reasons are found by any part of them, whatever the case
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Reason: "primaryfilecolumn: [AMOUNT]"})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{1}, resultRowNumbers(page))
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Status: recon_status.Failed, Reason: "no matching"})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{2}, resultRowNumbers(page))
+
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", ReasonCode: recon_reason_code.NoMatchFound})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{2}, resultRowNumbers(page))
+
+			page, err = repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1", Offset: 5})
+			assert.NoError(t, err)
+			assert.Empty(t, page.Results)
+			assert.Equal(t, 3, page.TotalCount)
+		})
+	}
+}
+
+func TestReconResultsAreReplacedWhenATaskIsReconstructedAgain(t *testing.T) {
+	for name, repo := range testReconResultsRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, repo.SaveReconResults(ctx, "task_1", testReconResults()))
+
+			assert.NoError(t, repo.DeleteReconResults(ctx, "task_1"))
+			results := testReconResults()[:1]
+			results[0].Status = recon_status.Failed
+			assert.NoError(t, repo.SaveReconResults(ctx, "task_1", results))
+			assert.NoError(t, repo.SaveReconResults(ctx, "task_1", results))
+
+			page, err := repo.QueryReconResults(ctx, models.ReconResultsQuery{TaskID: "task_1"})
+			assert.NoError(t, err)
+			assert.Equal(t, []uint64{3}, resultRowNumbers(page))
+			assert.Equal(t, recon_status.Failed, page.Results[0].Status)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// sqlDialect is what differs between the databases the SQL stores run on.
+// The queries of the stores are written with ? placeholders
+// and renumbered for databases that want $1, $2...
+type sqlDialect struct {
+	name                 string
+	driverName           string
+	hasNumberedArguments bool
+	// lockMigrations is run before migrating so that services
+	// starting at the same time don't both apply a migration
+	lockMigrations string
+	// findText is the function that finds where some text starts in a column,
+	// it is case-sensitive unlike LIKE on sqlite
+	findText string
+}
+
+var sqliteDialect = sqlDialect{
+	name:       "sqlite",
+	driverName: "sqlite3",
+	findText:   "instr",
+}
+
+var postgresDialect = sqlDialect{
+	name:                 "postgres",
+	driverName:           "postgres",
+	hasNumberedArguments: true,
+	lockMigrations:       "SELECT pg_advisory_xact_lock(7253401)",
+	findText:             "strpos",
+}
+
+// SQLDatabase is a migrated database the SQL stores keep their records in.
+type SQLDatabase struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// OpenSQLDatabase connects to the database of the given driver (sqlite or postgres)
+// and applies any migrations it does not have yet.
+// For sqlite the url is the path to the database file.
+func OpenSQLDatabase(ctx context.Context, driver string, url string) (*SQLDatabase, error) {
+	var dialect sqlDialect
+	switch driver {
+	case sqliteDialect.name:
+		dialect = sqliteDialect
+
+		// wait on other connections writing rather than failing straight away
+		if !strings.Contains(url, "?") {
+			url += "?_busy_timeout=5000&_journal_mode=WAL"
+		}
+	case postgresDialect.name:
+		dialect = postgresDialect
+	default:
+		return nil, fmt.Errorf("unsupported database driver: [%v]", driver)
+	}
+
+	db, err := sql.Open(dialect.driverName, url)
+	if err != nil {
+		return nil, fmt.Errorf("error on opening database: [%v]", err)
+	}
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error on connecting to database: [%v]", err)
+	}
+
+	database := &SQLDatabase{db: db, dialect: dialect}
+	err = database.migrate(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return database, nil
+}
+
+func (d *SQLDatabase) Close() error {
+	return d.db.Close()
+}
+
+// migrate applies the migrations of the dialect that have not been applied yet, in the order of their names
+func (d *SQLDatabase) migrate(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("error on creating schema_migrations: [%v]", err)
+	}
+
+	migrationsDirectory := path.Join("migrations", d.dialect.name)
+	migrations, err := fs.ReadDir(migrationFiles, migrationsDirectory)
+	if err != nil {
+		return fmt.Errorf("error on reading migrations: [%v]", err)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error on starting migrations: [%v]", err)
+	}
+	defer tx.Rollback()
+
+	if len(d.dialect.lockMigrations) > 0 {
+		_, err = tx.ExecContext(ctx, d.dialect.lockMigrations)
+		if err != nil {
+			return fmt.Errorf("error on locking migrations: [%v]", err)
+		}
+	}
+
+	for _, migration := range migrations {
+		version := strings.TrimSuffix(migration.Name(), ".sql")
+
+		var isApplied bool
+		err = tx.QueryRowContext(ctx, d.rebind("SELECT COUNT(*) > 0 FROM schema_migrations WHERE version = ?"), version).Scan(&isApplied)
+		if err != nil {
+			return fmt.Errorf("error on checking migration: [%v], Error: %v", version, err)
+		}
+		if isApplied {
+			continue
+		}
+
+		statements, err := fs.ReadFile(migrationFiles, path.Join(migrationsDirectory, migration.Name()))
+		if err != nil {
+			return fmt.Errorf("error on reading migration: [%v], Error: %v", version, err)
+		}
+
+		_, err = tx.ExecContext(ctx, string(statements))
+		if err != nil {
+			return fmt.Errorf("error on applying migration: [%v], Error: %v", version, err)
+		}
+
+		_, err = tx.ExecContext(ctx, d.rebind("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"), version, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("error on recording migration: [%v], Error: %v", version, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error on committing migrations: [%v]", err)
+	}
+	return nil
+}
+
+// rebind renumbers the ? placeholders of the query for dialects that use numbered arguments
+func (d *SQLDatabase) rebind(query string) string {
+	if !d.dialect.hasNumberedArguments {
+		return query
+	}
+
+	var rebound strings.Builder
+	argumentNumber := 0
+	for _, character := range query {
+		if character != '?' {
+			rebound.WriteRune(character)
+			continue
+		}
+		argumentNumber++
+		rebound.WriteString("$" + strconv.Itoa(argumentNumber))
+	}
+	return rebound.String()
+}
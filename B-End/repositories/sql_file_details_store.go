@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+)
+
+// SQLFileDetailsStore keeps the files of tasks in the files_to_be_read table of a SQL database.
+// The path of the file is not part of its json, so it has a column of its own.
+type SQLFileDetailsStore struct {
+	database *SQLDatabase
+}
+
+func NewSQLFileDetailsStore(database *SQLDatabase) *SQLFileDetailsStore {
+	return &SQLFileDetailsStore{database: database}
+}
+
+func (s *SQLFileDetailsStore) SaveFileDetails(ctx context.Context, fileToBeRead models.FileToBeRead) error {
+	details, err := json.Marshal(fileToBeRead)
+	if err != nil {
+		return fmt.Errorf("error on encoding file: [%v], Error: %v", fileToBeRead.ID, err)
+	}
+
+	_, err = s.database.db.ExecContext(ctx, s.database.rebind(`
+		INSERT INTO files_to_be_read (id, task_id, file_purpose, file_path, details)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			task_id = excluded.task_id,
+			file_purpose = excluded.file_purpose,
+			file_path = excluded.file_path,
+			details = excluded.details`),
+		fileToBeRead.ID,
+		fileToBeRead.ReconciliationTaskID,
+		string(fileToBeRead.FilePurpose),
+		fileToBeRead.FilePath,
+		string(details),
+	)
+	if err != nil {
+		return fmt.Errorf("error on saving file: [%v], Error: %v", fileToBeRead.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLFileDetailsStore) GetFileDetails(ctx context.Context, fileID string) (models.FileToBeRead, error) {
+	row := s.database.db.QueryRowContext(ctx, s.database.rebind("SELECT id, file_path, details FROM files_to_be_read WHERE id = ?"), fileID)
+	return scanFileDetails(row)
+}
+
+func (s *SQLFileDetailsStore) GetFileDetailsForTask(ctx context.Context, taskID string, filePurpose file_purpose.FilePurposeType) (models.FileToBeRead, error) {
+	row := s.database.db.QueryRowContext(ctx, s.database.rebind(`
+		SELECT id, file_path, details FROM files_to_be_read
+		WHERE task_id = ? AND file_purpose = ?
+		ORDER BY id LIMIT 1`),
+		taskID,
+		string(filePurpose),
+	)
+	return scanFileDetails(row)
+}
+
+func (s *SQLFileDetailsStore) GetAllFileDetails(ctx context.Context) ([]models.FileToBeRead, error) {
+	rows, err := s.database.db.QueryContext(ctx, "SELECT id, file_path, details FROM files_to_be_read ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error on loading files: [%v]", err)
+	}
+	defer rows.Close()
+
+	files := make([]models.FileToBeRead, 0)
+	for rows.Next() {
+		file, err := scanFileDetails(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error on loading files: [%v]", err)
+	}
+	return files, nil
+}
+
+// scanFileDetails reads a file from a row of id, file_path and details
+func scanFileDetails(row interface{ Scan(dest ...any) error }) (models.FileToBeRead, error) {
+	var fileID, filePath string
+	var details []byte
+	err := row.Scan(&fileID, &filePath, &details)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.FileToBeRead{}, ErrFileNotFound
+	}
+	if err != nil {
+		return models.FileToBeRead{}, fmt.Errorf("error on loading file: [%v]", err)
+	}
+
+	var file models.FileToBeRead
+	err = json.Unmarshal(details, &file)
+	if err != nil {
+		return models.FileToBeRead{}, fmt.Errorf("error on decoding file: [%v], Error: %v", fileID, err)
+	}
+	file.FilePath = filePath
+	return file, nil
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reconciler.io/models"
+	"strings"
+)
+
+// SQLReconResultsRepository keeps the results in the recon_results table of a SQL database.
+// The columns that differ and the values of a result are kept as json arrays that are searched
+// for the json encoding of the column or value, which only ever matches a whole element.
+// The text of the reasons is kept lower-cased as a json array too, so that a reason can be found by any part of it,
+// and the codes of the reasons are kept like the columns.
+type SQLReconResultsRepository struct {
+	database *SQLDatabase
+}
+
+func NewSQLReconResultsRepository(database *SQLDatabase) *SQLReconResultsRepository {
+	return &SQLReconResultsRepository{database: database}
+}
+
+func (r *SQLReconResultsRepository) DeleteReconResults(ctx context.Context, taskID string) error {
+	_, err := r.database.db.ExecContext(ctx, r.database.rebind("DELETE FROM recon_results WHERE task_id = ?"), taskID)
+	if err != nil {
+		return fmt.Errorf("error on deleting results of task: [%v], Error: %v", taskID, err)
+	}
+	return nil
+}
+
+func (r *SQLReconResultsRepository) SaveReconResults(ctx context.Context, taskID string, results []models.ReconResult) error {
+	tx, err := r.database.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error on saving results of task: [%v], Error: %v", taskID, err)
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.PrepareContext(ctx, r.database.rebind(`
+		INSERT INTO recon_results (task_id, primary_row_number, status, diff_columns, searchable_values, reasons, reason_codes, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (task_id, primary_row_number) DO UPDATE SET
+			status = excluded.status,
+			diff_columns = excluded.diff_columns,
+			searchable_values = excluded.searchable_values,
+			reasons = excluded.reasons,
+			reason_codes = excluded.reason_codes,
+			details = excluded.details`))
+	if err != nil {
+		return fmt.Errorf("error on saving results of task: [%v], Error: %v", taskID, err)
+	}
+	defer insert.Close()
+
+	for _, result := range results {
+		result.TaskID = taskID
+		diffColumns, err := json.Marshal(result.DiffColumns())
+		if err != nil {
+			return err
+		}
+		searchableValues, err := json.Marshal(result.SearchableValues())
+		if err != nil {
+			return err
+		}
+		reasons, err := json.Marshal(lowerCased(models.ReconReasonTexts(result.RowNumber, result.Reasons)))
+		if err != nil {
+			return err
+		}
+		reasonCodes, err := json.Marshal(result.ReasonCodes())
+		if err != nil {
+			return err
+		}
+		details, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		_, err = insert.ExecContext(ctx,
+			taskID,
+			int64(result.RowNumber),
+			string(result.Status),
+			string(diffColumns),
+			string(searchableValues),
+			string(reasons),
+			string(reasonCodes),
+			string(details),
+		)
+		if err != nil {
+			return fmt.Errorf("error on saving result of task: [%v], row: [%v], Error: %v", taskID, result.RowNumber, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error on saving results of task: [%v], Error: %v", taskID, err)
+	}
+	return nil
+}
+
+func (r *SQLReconResultsRepository) QueryReconResults(ctx context.Context, query models.ReconResultsQuery) (models.ReconResultsPage, error) {
+	query = withPaging(query)
+	where, arguments, err := r.whereClause(query)
+	if err != nil {
+		return models.ReconResultsPage{}, err
+	}
+
+	page := models.ReconResultsPage{
+		Results: []models.ReconResult{},
+		Offset:  query.Offset,
+		Limit:   query.Limit,
+	}
+	err = r.database.db.QueryRowContext(ctx, r.database.rebind("SELECT COUNT(*) FROM recon_results WHERE "+where), arguments...).Scan(&page.TotalCount)
+	if err != nil {
+		return models.ReconResultsPage{}, fmt.Errorf("error on counting results of task: [%v], Error: %v", query.TaskID, err)
+	}
+
+	rows, err := r.database.db.QueryContext(
+		ctx,
+		r.database.rebind("SELECT details FROM recon_results WHERE "+where+" ORDER BY primary_row_number LIMIT ? OFFSET ?"),
+		append(arguments, query.Limit, query.Offset)...,
+	)
+	if err != nil {
+		return models.ReconResultsPage{}, fmt.Errorf("error on loading results of task: [%v], Error: %v", query.TaskID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var details []byte
+		err = rows.Scan(&details)
+		if err != nil {
+			return models.ReconResultsPage{}, fmt.Errorf("error on loading results of task: [%v], Error: %v", query.TaskID, err)
+		}
+
+		var result models.ReconResult
+		err = json.Unmarshal(details, &result)
+		if err != nil {
+			return models.ReconResultsPage{}, fmt.Errorf("error on decoding result of task: [%v], Error: %v", query.TaskID, err)
+		}
+		page.Results = append(page.Results, result)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return models.ReconResultsPage{}, fmt.Errorf("error on loading results of task: [%v], Error: %v", query.TaskID, err)
+	}
+	return page, nil
+}
+
+func (r *SQLReconResultsRepository) whereClause(query models.ReconResultsQuery) (string, []any, error) {
+	conditions := []string{"task_id = ?"}
+	arguments := []any{query.TaskID}
+
+	if len(query.Status) > 0 {
+		conditions = append(conditions, "status = ?")
+		arguments = append(arguments, string(query.Status))
+	}
+
+	if len(query.Column) > 0 {
+		encoded, err := json.Marshal(query.Column)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, r.database.dialect.findText+"(diff_columns, ?) > 0")
+		arguments = append(arguments, string(encoded))
+	}
+
+	if len(query.Value) > 0 {
+		encoded, err := json.Marshal(query.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, r.database.dialect.findText+"(searchable_values, ?) > 0")
+		arguments = append(arguments, string(encoded))
+	}
+
+	if len(query.ReasonCode) > 0 {
+		encoded, err := json.Marshal(query.ReasonCode)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, r.database.dialect.findText+"(reason_codes, ?) > 0")
+		arguments = append(arguments, string(encoded))
+	}
+
+	if len(query.Reason) > 0 {
+		// leave off the quotes so that the text is found anywhere inside a reason
+		encoded, err := json.Marshal(strings.ToLower(query.Reason))
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, r.database.dialect.findText+"(reasons, ?) > 0")
+		arguments = append(arguments, strings.TrimSuffix(strings.TrimPrefix(string(encoded), `"`), `"`))
+	}
+
+	return strings.Join(conditions, " AND "), arguments, nil
+}
+
+func lowerCased(texts []string) []string {
+	lowered := make([]string, 0, len(texts))
+	for _, text := range texts {
+		lowered = append(lowered, strings.ToLower(text))
+	}
+	return lowered
+}
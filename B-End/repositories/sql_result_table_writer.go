@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reconciler.io/models"
+)
+
+// SQLResultTableWriter writes the results of tasks into tables of a SQL database that other services read from.
+// A table has a row for every row of the primary file of each task sent to it, with the reasons kept
+// as a json array of their text and the values as a json object keyed by the column headers of the primary file.
+// The tables are not part of the migrations since their names are picked by the tasks.
+type SQLResultTableWriter struct {
+	database *SQLDatabase
+}
+
+func NewSQLResultTableWriter(database *SQLDatabase) *SQLResultTableWriter {
+	return &SQLResultTableWriter{database: database}
+}
+
+// PrepareResultTable expects the name of the table to have been checked with models.ValidateResultSinks,
+// it is put into the queries as it is
+func (w *SQLResultTableWriter) PrepareResultTable(ctx context.Context, table string, taskID string) error {
+	_, err := w.database.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (
+		task_id            TEXT NOT NULL,
+		row_number         BIGINT NOT NULL,
+		status             TEXT NOT NULL,
+		matched_row_number BIGINT,
+		reasons            TEXT NOT NULL,
+		row_values         TEXT NOT NULL,
+		PRIMARY KEY (task_id, row_number)
+	)`, table))
+	if err != nil {
+		return fmt.Errorf("error on creating result table: [%v], Error: %v", table, err)
+	}
+
+	_, err = w.database.db.ExecContext(ctx, w.database.rebind(fmt.Sprintf("DELETE FROM %v WHERE task_id = ?", table)), taskID)
+	if err != nil {
+		return fmt.Errorf("error on deleting results of task: [%v] from table: [%v], Error: %v", taskID, table, err)
+	}
+	return nil
+}
+
+func (w *SQLResultTableWriter) InsertResults(ctx context.Context, table string, columnHeaders []string, results []models.ReconResult) error {
+	tx, err := w.database.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error on inserting results into table: [%v], Error: %v", table, err)
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.PrepareContext(ctx, w.database.rebind(fmt.Sprintf(`
+		INSERT INTO %v (task_id, row_number, status, matched_row_number, reasons, row_values)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (task_id, row_number) DO UPDATE SET
+			status = excluded.status,
+			matched_row_number = excluded.matched_row_number,
+			reasons = excluded.reasons,
+			row_values = excluded.row_values`, table)))
+	if err != nil {
+		return fmt.Errorf("error on inserting results into table: [%v], Error: %v", table, err)
+	}
+	defer insert.Close()
+
+	for _, result := range results {
+		reasons, err := json.Marshal(models.ReconReasonTexts(result.RowNumber, result.Reasons))
+		if err != nil {
+			return err
+		}
+		values, err := json.Marshal(valuesByColumn(columnHeaders, result.Values))
+		if err != nil {
+			return err
+		}
+
+		var matchedRowNumber *int64
+		if result.MatchedRowNumber != nil {
+			rowNumber := int64(*result.MatchedRowNumber)
+			matchedRowNumber = &rowNumber
+		}
+
+		_, err = insert.ExecContext(ctx,
+			result.TaskID,
+			int64(result.RowNumber),
+			string(result.Status),
+			matchedRowNumber,
+			string(reasons),
+			string(values),
+		)
+		if err != nil {
+			return fmt.Errorf("error on inserting result of task: [%v], row: [%v] into table: [%v], Error: %v", result.TaskID, result.RowNumber, table, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error on inserting results into table: [%v], Error: %v", table, err)
+	}
+	return nil
+}
+
+// valuesByColumn keys the values by their column header,
+// values past the last header are keyed by their position
+func valuesByColumn(columnHeaders []string, values []string) map[string]string {
+	byColumn := make(map[string]string, len(values))
+	for i, value := range values {
+		column := fmt.Sprintf("Column%v", i+1)
+		if i < len(columnHeaders) {
+			column = columnHeaders[i]
+		}
+		byColumn[column] = value
+	}
+	return byColumn
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/recon_reason_code"
+	"reconciler.io/models/enums/recon_status"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLResultTableWriterReplacesTheResultsOfEarlierAttempts(t *testing.T) {
+	for dialect, database := range testDatabases(t) {
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+			writer := NewSQLResultTableWriter(database)
+			table := "settlement_results_" + dialect
+			matchedRowNumber := uint64(4)
+			results := []models.ReconResult{
+				{TaskID: "task_1", RowNumber: 0, Status: recon_status.Successfull, MatchedRowNumber: &matchedRowNumber, Values: []string{"1", "10"}},
+				{
+					TaskID:    "task_1",
+					RowNumber: 1,
+					Status:    recon_status.Failed,
+					Reasons:   []models.ReconReason{{Code: recon_reason_code.NoMatchFound}},
+					Values:    []string{"2", "20", "extra"},
+				},
+			}
+
+			assert.NoError(t, writer.PrepareResultTable(ctx, table, "task_1"))
+			assert.NoError(t, writer.InsertResults(ctx, table, []string{"Id", "Amount"}, results))
+			assert.NoError(t, writer.PrepareResultTable(ctx, table, "task_2"))
+			assert.NoError(t, writer.InsertResults(ctx, table, []string{"Id", "Amount"}, []models.ReconResult{{TaskID: "task_2", RowNumber: 0, Values: []string{"3", "30"}}}))
+
+			var count int
+			assert.NoError(t, database.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count))
+			assert.Equal(t, 3, count)
+
+			var status, reasons, values string
+			var matched *int64
+			row := database.db.QueryRowContext(ctx, database.rebind("SELECT status, matched_row_number, reasons, row_values FROM "+table+" WHERE task_id = ? AND row_number = ?"), "task_1", 1)
+			assert.NoError(t, row.Scan(&status, &matched, &reasons, &values))
+			assert.Equal(t, "Failed", status)
+			assert.Nil(t, matched)
+			assert.Equal(t, `["no matching record found in the entire comparison file"]`, reasons)
+			assert.JSONEq(t, `{"Id":"2","Amount":"20","Column3":"extra"}`, values)
+
+			// reconstructing the task again starts its results over
+			assert.NoError(t, writer.PrepareResultTable(ctx, table, "task_1"))
+			assert.NoError(t, database.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count))
+			assert.Equal(t, 1, count)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/task_status"
+	"reconciler.io/models/enums/webhook_delivery_status"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDatabases opens a sqlite database for the test, and a postgres one
+// when RECONCILER_TEST_POSTGRES_URL says where to find it
+func testDatabases(t *testing.T) map[string]*SQLDatabase {
+	ctx := context.Background()
+	databases := make(map[string]*SQLDatabase)
+
+	sqliteDatabase, err := OpenSQLDatabase(ctx, "sqlite", filepath.Join(t.TempDir(), "reconciler.db"))
+	assert.NoError(t, err)
+	databases["sqlite"] = sqliteDatabase
+
+	postgresURL := os.Getenv("RECONCILER_TEST_POSTGRES_URL")
+	if len(postgresURL) > 0 {
+		postgresDatabase, err := OpenSQLDatabase(ctx, "postgres", postgresURL)
+		assert.NoError(t, err)
+		databases["postgres"] = postgresDatabase
+	}
+
+	t.Cleanup(func() {
+		for _, database := range databases {
+			database.Close()
+		}
+	})
+	return databases
+}
+
+func TestSQLStoresKeepTasksAndFiles(t *testing.T) {
+	for dialect, database := range testDatabases(t) {
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+			taskStore := NewSQLTaskDetailsStore(database)
+			fileStore := NewSQLFileDetailsStore(database)
+
+			firstID, err := taskStore.NextTaskID(ctx)
+			assert.NoError(t, err)
+			secondID, err := taskStore.NextTaskID(ctx)
+			assert.NoError(t, err)
+			assert.NotEqual(t, firstID, secondID)
+
+			taskID := "task_" + uuid.New().String()
+			_, err = taskStore.GetTaskDetails(ctx, taskID)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+
+			task := models.ReconTaskDetails{
+				ID:                 taskID,
+				UserID:             "user_1",
+				Status:             task_status.Reading,
+				NotificationEmails: []string{"ops@example.com"},
+			}
+			assert.NoError(t, taskStore.SaveTaskDetails(ctx, task))
+			task.Status = task_status.Reconciling
+
+			// only the latest version of the task can be saved over
+			assert.ErrorIs(t, taskStore.SaveTaskDetails(ctx, task), ErrTaskVersionConflict)
+			task.Version = 1
+			assert.NoError(t, taskStore.SaveTaskDetails(ctx, task))
+
+			savedTask, err := taskStore.GetTaskDetails(ctx, taskID)
+			assert.NoError(t, err)
+			assert.Equal(t, task_status.Reconciling, savedTask.Status)
+			assert.Equal(t, 2, savedTask.Version)
+			assert.Equal(t, []string{"ops@example.com"}, savedTask.NotificationEmails)
+
+			allTasks, err := taskStore.GetAllTaskDetails(ctx)
+			assert.NoError(t, err)
+			assert.Contains(t, allTasks, savedTask)
+
+			file := models.FileToBeRead{
+				ID:                   "PrimaryFile-" + uuid.New().String(),
+				ReconciliationTaskID: taskID,
+				FilePurpose:          file_purpose.PrimaryFile,
+				ColumnHeaders:        []string{"ID", "Amount"},
+				FilePath:             "./uploads/primary.csv",
+			}
+			assert.NoError(t, fileStore.SaveFileDetails(ctx, file))
+
+			savedFile, err := fileStore.GetFileDetailsForTask(ctx, taskID, file_purpose.PrimaryFile)
+			assert.NoError(t, err)
+			assert.Equal(t, file, savedFile)
+
+			_, err = fileStore.GetFileDetailsForTask(ctx, taskID, file_purpose.ComparisonFile)
+			assert.ErrorIs(t, err, ErrFileNotFound)
+			_, err = fileStore.GetFileDetails(ctx, "missing")
+			assert.ErrorIs(t, err, ErrFileNotFound)
+		})
+	}
+}
+
+func TestTasksOutliveTheService(t *testing.T) {
+	ctx := context.Background()
+	databasePath := filepath.Join(t.TempDir(), "reconciler.db")
+
+	database, err := OpenSQLDatabase(ctx, "sqlite", databasePath)
+	assert.NoError(t, err)
+
+	repo := NewTaskDetailsRepositoryWithStore(NewSQLTaskDetailsStore(database), nil)
+	fileRepo := NewFileDetailsRepositoryWithStore(NewSQLFileDetailsStore(database), nil)
+	taskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{UserID: "user_1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "task_1", taskID)
+
+	_, err = fileRepo.SaveFileToBeRead(ctx, models.FileToBeRead{
+		ID:                   "ComparisonFile-1",
+		ReconciliationTaskID: taskID,
+		FilePurpose:          file_purpose.ComparisonFile,
+		FilePath:             "./uploads/comparison.csv",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.AttachPrimaryFile(ctx, taskID, "PrimaryFile-1"))
+	assert.NoError(t, repo.AttachComparisonFile(ctx, taskID, "ComparisonFile-1"))
+	_, err = repo.AddWebhook(ctx, taskID, models.Webhook{URL: "https://erp.local/hooks"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.PauseReconciliationTask(ctx, taskID))
+	assert.NoError(t, database.Close())
+
+	// a restarted service migrates the same database again and carries on numbering tasks
+	database, err = OpenSQLDatabase(ctx, "sqlite", databasePath)
+	assert.NoError(t, err)
+	defer database.Close()
+
+	repo = NewTaskDetailsRepositoryWithStore(NewSQLTaskDetailsStore(database), nil)
+	fileRepo = NewFileDetailsRepositoryWithStore(NewSQLFileDetailsStore(database), nil)
+
+	task, err := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, "user_1", task.UserID)
+	assert.Equal(t, task_status.Reading, task.Status)
+	assert.True(t, task.IsPaused)
+	assert.NotNil(t, task.Phases[task_status.AwaitingFiles].EndedAt)
+	assert.Equal(t, "webhook_1", task.Webhooks[0].ID)
+
+	// the streams and control of the task stayed with the service that stopped
+	assert.Nil(t, task.FileToBeReconstructedChannel)
+	assert.Nil(t, task.Control)
+
+	file, err := fileRepo.GetComparisonFileDetailsForTask(ctx, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, "./uploads/comparison.csv", file.FilePath)
+
+	assert.NoError(t, repo.ResumeReconciliationTask(ctx, taskID))
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reconciling))
+
+	nextTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+	assert.Equal(t, "task_2", nextTaskID)
+
+	tasks, err := repo.GetAllReconciliationTasks(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, task_status.Reconciling, tasks[0].Status)
+}
+
+func TestEachServiceOnlyLoadsItsOwnCheckpoints(t *testing.T) {
+	for dialect, database := range testDatabases(t) {
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+			firstService := "service-" + uuid.New().String()
+			secondService := "service-" + uuid.New().String()
+			taskID := "task_" + uuid.New().String()
+
+			checkpoints, err := NewTaskCheckpointRepositoryWithStore(NewSQLTaskCheckpointStore(database, firstService))
+			assert.NoError(t, err)
+			assert.NoError(t, checkpoints.SaveTaskDetails(ctx, models.ReconTaskDetails{ID: taskID, Status: task_status.Reading}))
+			assert.NoError(t, checkpoints.RecordSectionRead(ctx, taskID, "PrimaryFile-1", 1, true))
+			assert.NoError(t, checkpoints.Flush())
+
+			// the first service restarting picks its task back up
+			restarted, err := NewTaskCheckpointRepositoryWithStore(NewSQLTaskCheckpointStore(database, firstService))
+			assert.NoError(t, err)
+			loaded, err := restarted.GetAllTaskCheckpoints(ctx)
+			assert.NoError(t, err)
+			assert.Len(t, loaded, 1)
+			assert.Equal(t, task_status.Reading, loaded[0].TaskDetails.Status)
+			lastSectionRead, isFullyRead := restarted.GetLastSectionRead(ctx, taskID, "PrimaryFile-1")
+			assert.Equal(t, 1, lastSectionRead)
+			assert.True(t, isFullyRead)
+
+			// another service sharing the database leaves it alone
+			other, err := NewTaskCheckpointRepositoryWithStore(NewSQLTaskCheckpointStore(database, secondService))
+			assert.NoError(t, err)
+			loaded, err = other.GetAllTaskCheckpoints(ctx)
+			assert.NoError(t, err)
+			assert.Empty(t, loaded)
+
+			assert.NoError(t, restarted.DeleteTaskCheckpoint(ctx, taskID))
+			restarted, err = NewTaskCheckpointRepositoryWithStore(NewSQLTaskCheckpointStore(database, firstService))
+			assert.NoError(t, err)
+			loaded, err = restarted.GetAllTaskCheckpoints(ctx)
+			assert.NoError(t, err)
+			assert.Empty(t, loaded)
+		})
+	}
+}
+
+func TestRestoringACheckpointKeepsTheTaskAsItWasUpdatedSince(t *testing.T) {
+	ctx := context.Background()
+	database := testDatabases(t)["sqlite"]
+	checkpoints, err := NewTaskCheckpointRepositoryWithStore(NewSQLTaskCheckpointStore(database, "service-1"))
+	assert.NoError(t, err)
+	repo := NewTaskDetailsRepositoryWithStore(NewSQLTaskDetailsStore(database), checkpoints)
+
+	taskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{UserID: "user_1"})
+	assert.NoError(t, err)
+	snapshots, err := checkpoints.GetAllTaskCheckpoints(ctx)
+	assert.NoError(t, err)
+	staleTask := snapshots[0].TaskDetails
+
+	// another service failed the task after the checkpoint was taken
+	assert.NoError(t, repo.FailReconciliationTask(ctx, taskID, errors.New("comparison file is empty")))
+
+	restored, err := repo.RestoreTaskDetails(ctx, staleTask)
+	assert.NoError(t, err)
+	assert.Equal(t, task_status.Failed, restored.Status)
+
+	task, err := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, task_status.Failed, task.Status)
+	assert.Equal(t, "comparison file is empty", task.Error)
+}
+
+// racingTaskStore has another service change the task right before each save, while it is armed
+type racingTaskStore struct {
+	TaskDetailsStore
+	otherService func()
+	saves        int
+}
+
+func (s *racingTaskStore) SaveTaskDetails(ctx context.Context, taskDetails models.ReconTaskDetails) error {
+	s.saves++
+	if s.otherService != nil {
+		s.otherService()
+	}
+	return s.TaskDetailsStore.SaveTaskDetails(ctx, taskDetails)
+}
+
+func TestUpdatesOfATaskChangedByAnotherServiceAreAppliedToItsLatestVersion(t *testing.T) {
+	for dialect, database := range testDatabases(t) {
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+			store := &racingTaskStore{TaskDetailsStore: NewSQLTaskDetailsStore(database)}
+			repo := NewTaskDetailsRepositoryWithStore(store, nil)
+			otherRepo := NewTaskDetailsRepositoryWithStore(NewSQLTaskDetailsStore(database), nil)
+
+			taskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{ID: "task_" + uuid.New().String()})
+			assert.NoError(t, err)
+
+			// the other service changes the task only once, the update is tried again on top of it
+			store.otherService = func() {
+				store.otherService = nil
+				assert.NoError(t, otherRepo.SetEmailRecipients(ctx, taskID, []string{"ops@example.com"}))
+			}
+			store.saves = 0
+			assert.NoError(t, repo.UpdateReconciliationTask(ctx, models.ReconTaskDetails{ID: taskID, MaxConcurrentSections: 4}))
+			assert.Equal(t, 2, store.saves)
+
+			task, err := repo.GetReconciliationTaskStatus(ctx, taskID)
+			assert.NoError(t, err)
+			assert.Equal(t, 4, task.MaxConcurrentSections)
+			assert.Equal(t, []string{"ops@example.com"}, task.NotificationEmails)
+
+			// an update that keeps losing to the other service gives up
+			store.otherService = func() {
+				assert.NoError(t, otherRepo.SaveReconSummary(ctx, taskID, models.ReconSummary{}))
+			}
+			store.saves = 0
+			err = repo.UpdateReconciliationTask(ctx, models.ReconTaskDetails{ID: taskID, MaxConcurrentSections: 8})
+			assert.ErrorIs(t, err, ErrTaskVersionConflict)
+			assert.Equal(t, constants.TASK_UPDATE_MAX_ATTEMPTS, store.saves)
+
+			store.otherService = nil
+			assert.NoError(t, repo.CloseTaskStreams(ctx))
+		})
+	}
+}
+
+func TestWebhookDeliveriesOutliveTheService(t *testing.T) {
+	for dialect, database := range testDatabases(t) {
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+			taskID := "task_" + uuid.New().String()
+			firstID := "delivery_" + uuid.New().String()
+			secondID := "delivery_" + uuid.New().String()
+
+			repo := NewWebhookDeliveryRepositoryWithStore(NewSQLWebhookDeliveryStore(database))
+			assert.NoError(t, repo.SaveWebhookDelivery(ctx, models.WebhookDelivery{ID: firstID, TaskID: taskID, EventType: task_event_type.TaskStatusChanged}))
+			assert.NoError(t, repo.SaveWebhookDelivery(ctx, models.WebhookDelivery{ID: secondID, TaskID: taskID, EventType: task_event_type.TaskCompleted}))
+			assert.Error(t, repo.SaveWebhookDelivery(ctx, models.WebhookDelivery{ID: firstID, TaskID: taskID}))
+			assert.NoError(t, repo.RecordWebhookDeliveryAttempt(ctx, firstID, models.WebhookDeliveryAttempt{AttemptNumber: 1, StatusCode: 200}, webhook_delivery_status.Delivered))
+			assert.ErrorIs(t, repo.RecordWebhookDeliveryAttempt(ctx, "delivery_missing", models.WebhookDeliveryAttempt{AttemptNumber: 1}, webhook_delivery_status.Failed), ErrWebhookDeliveryNotFound)
+
+			// a service started later on the same database sees the deliveries, oldest first
+			restarted := NewWebhookDeliveryRepositoryWithStore(NewSQLWebhookDeliveryStore(database))
+			deliveries, err := restarted.GetWebhookDeliveriesForTask(ctx, taskID)
+			assert.NoError(t, err)
+			assert.Len(t, deliveries, 2)
+			assert.Equal(t, firstID, deliveries[0].ID)
+			assert.Equal(t, webhook_delivery_status.Delivered, deliveries[0].Status)
+			assert.Len(t, deliveries[0].Attempts, 1)
+			assert.Equal(t, 200, deliveries[0].Attempts[0].StatusCode)
+			assert.Equal(t, secondID, deliveries[1].ID)
+			assert.Equal(t, webhook_delivery_status.Pending, deliveries[1].Status)
+			assert.Empty(t, deliveries[1].Attempts)
+
+			deliveries, err = restarted.GetWebhookDeliveriesForTask(ctx, "task_"+uuid.New().String())
+			assert.NoError(t, err)
+			assert.Empty(t, deliveries)
+		})
+	}
+}
+
+func TestRebindNumbersArgumentsForPostgres(t *testing.T) {
+	postgres := &SQLDatabase{dialect: postgresDialect}
+	sqlite := &SQLDatabase{dialect: sqliteDialect}
+
+	query := "SELECT details FROM files_to_be_read WHERE task_id = ? AND file_purpose = ?"
+	assert.Equal(t, "SELECT details FROM files_to_be_read WHERE task_id = $1 AND file_purpose = $2", postgres.rebind(query))
+	assert.Equal(t, query, sqlite.rebind(query))
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reconciler.io/models"
+)
+
+// SQLTaskCheckpointStore keeps the checkpoints in the task_checkpoints table of a SQL database,
+// next to the tasks they are checkpoints of. Every checkpoint belongs to the service that wrote it
+// and only that service loads it, so services sharing the database never resume each other's tasks.
+type SQLTaskCheckpointStore struct {
+	database *SQLDatabase
+	owner    string
+}
+
+// NewSQLTaskCheckpointStore creates the store of the service known as owner,
+// the owner has to stay the same across restarts of the service.
+func NewSQLTaskCheckpointStore(database *SQLDatabase, owner string) *SQLTaskCheckpointStore {
+	return &SQLTaskCheckpointStore{database: database, owner: owner}
+}
+
+func (s *SQLTaskCheckpointStore) LoadTaskCheckpoints(ctx context.Context) ([]*models.TaskCheckpoint, error) {
+	rows, err := s.database.db.QueryContext(ctx, s.database.rebind("SELECT task_id, checkpoint FROM task_checkpoints WHERE owner = ?"), s.owner)
+	if err != nil {
+		return nil, fmt.Errorf("error on loading checkpoints: [%v]", err)
+	}
+	defer rows.Close()
+
+	checkpoints := make([]*models.TaskCheckpoint, 0)
+	for rows.Next() {
+		var taskID string
+		var encoded []byte
+		err = rows.Scan(&taskID, &encoded)
+		if err != nil {
+			return nil, fmt.Errorf("error on loading checkpoints: [%v]", err)
+		}
+
+		var checkpoint models.TaskCheckpoint
+		err = json.Unmarshal(encoded, &checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("error on decoding checkpoint for task: [%v], Error: %v", taskID, err)
+		}
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error on loading checkpoints: [%v]", err)
+	}
+	return checkpoints, nil
+}
+
+func (s *SQLTaskCheckpointStore) SaveTaskCheckpoint(ctx context.Context, checkpoint models.TaskCheckpoint) error {
+	taskID := checkpoint.TaskDetails.ID
+	encoded, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("error on encoding checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+
+	_, err = s.database.db.ExecContext(ctx, s.database.rebind(`
+		INSERT INTO task_checkpoints (task_id, owner, updated_at, checkpoint)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (task_id) DO UPDATE SET
+			owner = excluded.owner,
+			updated_at = excluded.updated_at,
+			checkpoint = excluded.checkpoint`),
+		taskID,
+		s.owner,
+		checkpoint.UpdatedAt.UTC(),
+		string(encoded),
+	)
+	if err != nil {
+		return fmt.Errorf("error on writing checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+	return nil
+}
+
+func (s *SQLTaskCheckpointStore) DeleteTaskCheckpoint(ctx context.Context, taskID string) error {
+	_, err := s.database.db.ExecContext(ctx, s.database.rebind("DELETE FROM task_checkpoints WHERE task_id = ?"), taskID)
+	if err != nil {
+		return fmt.Errorf("error on deleting checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+	return nil
+}
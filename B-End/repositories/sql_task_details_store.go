@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reconciler.io/models"
+	"strconv"
+)
+
+// SQLTaskDetailsStore keeps the tasks in the recon_tasks table of a SQL database,
+// so that they outlive the service and services sharing the database see the same tasks.
+// The task is kept as a json document next to the columns it is looked up by,
+// its version has a column of its own so a task is only replaced by an update of its latest version.
+type SQLTaskDetailsStore struct {
+	database *SQLDatabase
+}
+
+func NewSQLTaskDetailsStore(database *SQLDatabase) *SQLTaskDetailsStore {
+	return &SQLTaskDetailsStore{database: database}
+}
+
+// NextTaskID numbers tasks from a table every service shares, so two services never hand out the same ID
+func (s *SQLTaskDetailsStore) NextTaskID(ctx context.Context) (string, error) {
+	var nextID int64
+	err := s.database.db.QueryRowContext(ctx, "INSERT INTO recon_task_ids DEFAULT VALUES RETURNING id").Scan(&nextID)
+	if err != nil {
+		return "", fmt.Errorf("error on numbering task: [%v]", err)
+	}
+	return "task_" + strconv.FormatInt(nextID, 10), nil
+}
+
+func (s *SQLTaskDetailsStore) SaveTaskDetails(ctx context.Context, taskDetails models.ReconTaskDetails) error {
+	loadedVersion := taskDetails.Version
+	taskDetails.Version++
+	details, err := json.Marshal(taskDetails)
+	if err != nil {
+		return fmt.Errorf("error on encoding task: [%v], Error: %v", taskDetails.ID, err)
+	}
+
+	result, err := s.database.db.ExecContext(ctx, s.database.rebind(`
+		INSERT INTO recon_tasks (id, user_id, status, created_at, updated_at, version, details)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = excluded.user_id,
+			status = excluded.status,
+			updated_at = excluded.updated_at,
+			version = excluded.version,
+			details = excluded.details
+		WHERE recon_tasks.id = ? AND recon_tasks.version = ?`),
+		taskDetails.ID,
+		taskDetails.UserID,
+		string(taskDetails.Status),
+		taskDetails.CreatedAt.UTC(),
+		taskDetails.UpdatedAt.UTC(),
+		taskDetails.Version,
+		string(details),
+		taskDetails.ID,
+		loadedVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("error on saving task: [%v], Error: %v", taskDetails.ID, err)
+	}
+
+	savedRows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error on saving task: [%v], Error: %v", taskDetails.ID, err)
+	}
+
+	//another service saved the task after it was loaded
+	if savedRows == 0 {
+		return ErrTaskVersionConflict
+	}
+	return nil
+}
+
+func (s *SQLTaskDetailsStore) GetTaskDetails(ctx context.Context, taskID string) (models.ReconTaskDetails, error) {
+	var version int
+	var details []byte
+	err := s.database.db.QueryRowContext(ctx, s.database.rebind("SELECT version, details FROM recon_tasks WHERE id = ?"), taskID).Scan(&version, &details)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ReconTaskDetails{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return models.ReconTaskDetails{}, fmt.Errorf("error on loading task: [%v], Error: %v", taskID, err)
+	}
+
+	return decodeTaskDetails(taskID, version, details)
+}
+
+func (s *SQLTaskDetailsStore) GetAllTaskDetails(ctx context.Context) ([]models.ReconTaskDetails, error) {
+	rows, err := s.database.db.QueryContext(ctx, "SELECT id, version, details FROM recon_tasks ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("error on loading tasks: [%v]", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]models.ReconTaskDetails, 0)
+	for rows.Next() {
+		var taskID string
+		var version int
+		var details []byte
+		err = rows.Scan(&taskID, &version, &details)
+		if err != nil {
+			return nil, fmt.Errorf("error on loading tasks: [%v]", err)
+		}
+
+		task, err := decodeTaskDetails(taskID, version, details)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error on loading tasks: [%v]", err)
+	}
+	return tasks, nil
+}
+
+// decodeTaskDetails reads the task from its json, at the version of its row
+func decodeTaskDetails(taskID string, version int, details []byte) (models.ReconTaskDetails, error) {
+	var task models.ReconTaskDetails
+	err := json.Unmarshal(details, &task)
+	if err != nil {
+		return models.ReconTaskDetails{}, fmt.Errorf("error on decoding task: [%v], Error: %v", taskID, err)
+	}
+	task.Version = version
+	return task, nil
+}
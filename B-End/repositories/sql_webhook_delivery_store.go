@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reconciler.io/models"
+)
+
+// SQLWebhookDeliveryStore keeps the log of the webhooks called for each task in the webhook_deliveries table of a SQL database.
+// The task and creation time of a delivery have columns of their own so the deliveries of a task can be listed in order.
+type SQLWebhookDeliveryStore struct {
+	database *SQLDatabase
+}
+
+func NewSQLWebhookDeliveryStore(database *SQLDatabase) *SQLWebhookDeliveryStore {
+	return &SQLWebhookDeliveryStore{database: database}
+}
+
+func (s *SQLWebhookDeliveryStore) SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	details, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("error on encoding webhook delivery: [%v], Error: %v", delivery.ID, err)
+	}
+
+	_, err = s.database.db.ExecContext(ctx, s.database.rebind(`
+		INSERT INTO webhook_deliveries (id, task_id, created_at, details)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			task_id = excluded.task_id,
+			created_at = excluded.created_at,
+			details = excluded.details`),
+		delivery.ID,
+		delivery.TaskID,
+		delivery.CreatedAt.UTC(),
+		string(details),
+	)
+	if err != nil {
+		return fmt.Errorf("error on saving webhook delivery: [%v], Error: %v", delivery.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLWebhookDeliveryStore) GetWebhookDelivery(ctx context.Context, deliveryID string) (models.WebhookDelivery, error) {
+	row := s.database.db.QueryRowContext(ctx, s.database.rebind("SELECT id, details FROM webhook_deliveries WHERE id = ?"), deliveryID)
+	return scanWebhookDelivery(row)
+}
+
+func (s *SQLWebhookDeliveryStore) GetWebhookDeliveriesForTask(ctx context.Context, taskID string) ([]models.WebhookDelivery, error) {
+	rows, err := s.database.db.QueryContext(ctx, s.database.rebind(`
+		SELECT id, details FROM webhook_deliveries
+		WHERE task_id = ?
+		ORDER BY created_at, id`),
+		taskID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error on loading webhook deliveries for task: [%v], Error: %v", taskID, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error on loading webhook deliveries for task: [%v], Error: %v", taskID, err)
+	}
+	return deliveries, nil
+}
+
+// scanWebhookDelivery reads a delivery from a row of id and details
+func scanWebhookDelivery(row interface{ Scan(dest ...any) error }) (models.WebhookDelivery, error) {
+	var deliveryID string
+	var details []byte
+	err := row.Scan(&deliveryID, &details)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebhookDelivery{}, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("error on loading webhook delivery: [%v]", err)
+	}
+
+	var delivery models.WebhookDelivery
+	err = json.Unmarshal(details, &delivery)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("error on decoding webhook delivery: [%v], Error: %v", deliveryID, err)
+	}
+	return delivery, nil
+}
@@ -0,0 +1,310 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"sync"
+	"time"
+)
+
+// TaskCheckpointRepository keeps a checkpoint of every unfinished task in its TaskCheckpointStore.
+// Section level progress is written at most once every TASK_CHECKPOINT_FLUSH_INTERVAL,
+// anything lost in between is simply redone after a restart.
+// The checkpoint of a task is deleted once the task has finished, progress recorded after that is dropped.
+type TaskCheckpointRepository struct {
+	store            TaskCheckpointStore
+	checkpointsMap   map[string]*models.TaskCheckpoint
+	lastPersistedAt  map[string]time.Time
+	pendingPersists  map[string]*time.Timer
+	finishedTasks    map[string]bool
+	checkpointsMutex sync.Mutex
+}
+
+// NewTaskCheckpointRepository creates the repository with its checkpoints kept in json files under the directory.
+func NewTaskCheckpointRepository(checkpointsDirectory string) (*TaskCheckpointRepository, error) {
+	store, err := NewFileTaskCheckpointStore(checkpointsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	return NewTaskCheckpointRepositoryWithStore(store)
+}
+
+// NewTaskCheckpointRepositoryWithStore creates the repository with the checkpoints the store has.
+func NewTaskCheckpointRepositoryWithStore(store TaskCheckpointStore) (*TaskCheckpointRepository, error) {
+	repo := &TaskCheckpointRepository{
+		store:           store,
+		checkpointsMap:  make(map[string]*models.TaskCheckpoint),
+		lastPersistedAt: make(map[string]time.Time),
+		pendingPersists: make(map[string]*time.Timer),
+		finishedTasks:   make(map[string]bool),
+	}
+
+	checkpoints, err := store.LoadTaskCheckpoints(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Files == nil {
+			checkpoint.Files = make(map[string]*models.FileCheckpoint)
+		}
+		repo.checkpointsMap[checkpoint.TaskDetails.ID] = checkpoint
+	}
+
+	return repo, nil
+}
+
+// SaveTaskDetails snapshots the task details into the task's checkpoint.
+func (r *TaskCheckpointRepository) SaveTaskDetails(ctx context.Context, taskDetails models.ReconTaskDetails) error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	if r.finishedTasks[taskDetails.ID] {
+		return nil
+	}
+
+	checkpoint := r.getOrCreateCheckpointLocked(taskDetails.ID)
+	checkpoint.TaskDetails = taskDetails
+	return r.writeCheckpointLocked(taskDetails.ID)
+}
+
+// SaveFileDetails snapshots an uploaded file into its task's checkpoint.
+func (r *TaskCheckpointRepository) SaveFileDetails(ctx context.Context, fileToBeRead models.FileToBeRead) error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	if r.finishedTasks[fileToBeRead.ReconciliationTaskID] {
+		return nil
+	}
+
+	checkpoint := r.getOrCreateCheckpointLocked(fileToBeRead.ReconciliationTaskID)
+	fileCheckpoint := getOrCreateFileCheckpoint(checkpoint, fileToBeRead.ID)
+	fileCheckpoint.File = &fileToBeRead
+	fileCheckpoint.FilePath = fileToBeRead.FilePath
+	return r.writeCheckpointLocked(fileToBeRead.ReconciliationTaskID)
+}
+
+// GetAllTaskCheckpoints returns a copy of every checkpoint, finished or not.
+func (r *TaskCheckpointRepository) GetAllTaskCheckpoints(ctx context.Context) ([]models.TaskCheckpoint, error) {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	checkpoints := make([]models.TaskCheckpoint, 0, len(r.checkpointsMap))
+	for _, checkpoint := range r.checkpointsMap {
+		// round trip through json so callers never share maps with the activities
+		encoded, err := json.Marshal(checkpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		var copied models.TaskCheckpoint
+		err = json.Unmarshal(encoded, &copied)
+		if err != nil {
+			return nil, err
+		}
+
+		checkpoints = append(checkpoints, copied)
+	}
+	return checkpoints, nil
+}
+
+func (r *TaskCheckpointRepository) RecordSectionRead(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int, isLastSection bool) error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	if r.finishedTasks[taskID] {
+		return nil
+	}
+
+	fileCheckpoint := getOrCreateFileCheckpoint(r.getOrCreateCheckpointLocked(taskID), fileID)
+	if sectionSequenceNumber > fileCheckpoint.LastSectionRead {
+		fileCheckpoint.LastSectionRead = sectionSequenceNumber
+	}
+	if isLastSection {
+		fileCheckpoint.IsFullyRead = true
+	}
+	return r.persistLocked(taskID)
+}
+
+func (r *TaskCheckpointRepository) GetLastSectionRead(ctx context.Context, taskID string, fileID string) (int, bool) {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	fileCheckpoint := r.findFileCheckpointLocked(taskID, fileID)
+	if fileCheckpoint == nil {
+		return 0, false
+	}
+	return fileCheckpoint.LastSectionRead, fileCheckpoint.IsFullyRead
+}
+
+func (r *TaskCheckpointRepository) RecordSectionReconciled(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int) error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	if r.finishedTasks[taskID] {
+		return nil
+	}
+
+	fileCheckpoint := getOrCreateFileCheckpoint(r.getOrCreateCheckpointLocked(taskID), fileID)
+	fileCheckpoint.ReconciledSections[sectionSequenceNumber] = true
+	return r.persistLocked(taskID)
+}
+
+func (r *TaskCheckpointRepository) HasSectionBeenReconciled(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int) bool {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	fileCheckpoint := r.findFileCheckpointLocked(taskID, fileID)
+	if fileCheckpoint == nil {
+		return false
+	}
+	return fileCheckpoint.ReconciledSections[sectionSequenceNumber]
+}
+
+// HaveAllSectionsBeenReconciled is only true once the whole file
+// has been read and every one of its sections has been reconciled.
+func (r *TaskCheckpointRepository) HaveAllSectionsBeenReconciled(ctx context.Context, taskID string, fileID string) bool {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	fileCheckpoint := r.findFileCheckpointLocked(taskID, fileID)
+	if fileCheckpoint == nil || !fileCheckpoint.IsFullyRead {
+		return false
+	}
+
+	for sectionSequenceNumber := 1; sectionSequenceNumber <= fileCheckpoint.LastSectionRead; sectionSequenceNumber++ {
+		if !fileCheckpoint.ReconciledSections[sectionSequenceNumber] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *TaskCheckpointRepository) RecordSectionReconstructed(ctx context.Context, taskID string, fileID string, sectionSequenceNumber int) error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	if r.finishedTasks[taskID] {
+		return nil
+	}
+
+	fileCheckpoint := getOrCreateFileCheckpoint(r.getOrCreateCheckpointLocked(taskID), fileID)
+	fileCheckpoint.ReconstructedSections[sectionSequenceNumber] = true
+	return r.persistLocked(taskID)
+}
+
+// DeleteTaskCheckpoint deletes the checkpoint of a task that has finished,
+// a finished task is never resumed so there is nothing to keep.
+func (r *TaskCheckpointRepository) DeleteTaskCheckpoint(ctx context.Context, taskID string) error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	if timer, isPending := r.pendingPersists[taskID]; isPending {
+		timer.Stop()
+		delete(r.pendingPersists, taskID)
+	}
+	delete(r.checkpointsMap, taskID)
+	delete(r.lastPersistedAt, taskID)
+	r.finishedTasks[taskID] = true
+
+	return r.store.DeleteTaskCheckpoint(ctx, taskID)
+}
+
+// Flush writes every checkpoint that is waiting for its flush interval.
+func (r *TaskCheckpointRepository) Flush() error {
+	r.checkpointsMutex.Lock()
+	defer r.checkpointsMutex.Unlock()
+
+	flushErrors := make([]error, 0)
+	for taskID := range r.pendingPersists {
+		err := r.writeCheckpointLocked(taskID)
+		if err != nil {
+			flushErrors = append(flushErrors, err)
+		}
+	}
+	return errors.Join(flushErrors...)
+}
+
+func (r *TaskCheckpointRepository) getOrCreateCheckpointLocked(taskID string) *models.TaskCheckpoint {
+	checkpoint, exists := r.checkpointsMap[taskID]
+	if !exists {
+		checkpoint = &models.TaskCheckpoint{
+			TaskDetails: models.ReconTaskDetails{ID: taskID},
+			Files:       make(map[string]*models.FileCheckpoint),
+		}
+		r.checkpointsMap[taskID] = checkpoint
+	}
+	return checkpoint
+}
+
+func (r *TaskCheckpointRepository) findFileCheckpointLocked(taskID string, fileID string) *models.FileCheckpoint {
+	checkpoint, exists := r.checkpointsMap[taskID]
+	if !exists {
+		return nil
+	}
+	return checkpoint.Files[fileID]
+}
+
+func getOrCreateFileCheckpoint(checkpoint *models.TaskCheckpoint, fileID string) *models.FileCheckpoint {
+	fileCheckpoint, exists := checkpoint.Files[fileID]
+	if !exists {
+		fileCheckpoint = &models.FileCheckpoint{}
+		checkpoint.Files[fileID] = fileCheckpoint
+	}
+	if fileCheckpoint.ReconciledSections == nil {
+		fileCheckpoint.ReconciledSections = make(map[int]bool)
+	}
+	if fileCheckpoint.ReconstructedSections == nil {
+		fileCheckpoint.ReconstructedSections = make(map[int]bool)
+	}
+	return fileCheckpoint
+}
+
+// persistLocked writes the checkpoint straight away unless it was written
+// less than a flush interval ago, in which case a write is scheduled
+func (r *TaskCheckpointRepository) persistLocked(taskID string) error {
+	if _, isPending := r.pendingPersists[taskID]; isPending {
+		return nil
+	}
+
+	sinceLastPersist := time.Since(r.lastPersistedAt[taskID])
+	if sinceLastPersist >= constants.TASK_CHECKPOINT_FLUSH_INTERVAL {
+		return r.writeCheckpointLocked(taskID)
+	}
+
+	r.pendingPersists[taskID] = time.AfterFunc(constants.TASK_CHECKPOINT_FLUSH_INTERVAL-sinceLastPersist, func() {
+		r.checkpointsMutex.Lock()
+		defer r.checkpointsMutex.Unlock()
+
+		if _, isPending := r.pendingPersists[taskID]; !isPending {
+			return
+		}
+
+		err := r.writeCheckpointLocked(taskID)
+		if err != nil {
+			log.Printf("Error on writing checkpoint for task: [%v], Error: %v", taskID, err)
+		}
+	})
+	return nil
+}
+
+func (r *TaskCheckpointRepository) writeCheckpointLocked(taskID string) error {
+	if timer, isPending := r.pendingPersists[taskID]; isPending {
+		timer.Stop()
+		delete(r.pendingPersists, taskID)
+	}
+
+	checkpoint := r.checkpointsMap[taskID]
+	checkpoint.UpdatedAt = time.Now()
+
+	err := r.store.SaveTaskCheckpoint(context.Background(), *checkpoint)
+	if err != nil {
+		return err
+	}
+
+	r.lastPersistedAt[taskID] = time.Now()
+	return nil
+}
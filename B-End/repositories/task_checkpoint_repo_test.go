@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskCheckpointsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	checkpointsDirectory := t.TempDir()
+
+	repo, err := NewTaskCheckpointRepository(checkpointsDirectory)
+	assert.NoError(t, err)
+
+	err = repo.SaveTaskDetails(ctx, models.ReconTaskDetails{ID: "task_1", HasBegun: true})
+	assert.NoError(t, err)
+
+	err = repo.SaveFileDetails(ctx, models.FileToBeRead{
+		ID:                   "PrimaryFile-1234",
+		ReconciliationTaskID: "task_1",
+		FilePurpose:          file_purpose.PrimaryFile,
+		FilePath:             "./PrimaryFile-1234.Csv",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.RecordSectionRead(ctx, "task_1", "PrimaryFile-1234", 1, false))
+	assert.NoError(t, repo.RecordSectionRead(ctx, "task_1", "PrimaryFile-1234", 2, true))
+	assert.NoError(t, repo.RecordSectionReconciled(ctx, "task_1", "PrimaryFile-1234", 2))
+	assert.NoError(t, repo.Flush())
+
+	// load the checkpoints again as if the service had restarted
+	restarted, err := NewTaskCheckpointRepository(checkpointsDirectory)
+	assert.NoError(t, err)
+
+	checkpoints, err := restarted.GetAllTaskCheckpoints(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, checkpoints, 1)
+	assert.True(t, checkpoints[0].TaskDetails.HasBegun)
+	assert.False(t, checkpoints[0].IsComplete)
+	assert.Equal(t, "./PrimaryFile-1234.Csv", checkpoints[0].Files["PrimaryFile-1234"].FilePath)
+
+	lastSectionRead, isFullyRead := restarted.GetLastSectionRead(ctx, "task_1", "PrimaryFile-1234")
+	assert.Equal(t, 2, lastSectionRead)
+	assert.True(t, isFullyRead)
+
+	assert.True(t, restarted.HasSectionBeenReconciled(ctx, "task_1", "PrimaryFile-1234", 2))
+	assert.False(t, restarted.HasSectionBeenReconciled(ctx, "task_1", "PrimaryFile-1234", 1))
+	assert.False(t, restarted.HaveAllSectionsBeenReconciled(ctx, "task_1", "PrimaryFile-1234"))
+
+	assert.NoError(t, restarted.RecordSectionReconciled(ctx, "task_1", "PrimaryFile-1234", 1))
+	assert.True(t, restarted.HaveAllSectionsBeenReconciled(ctx, "task_1", "PrimaryFile-1234"))
+}
+
+func TestDeleteTaskCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	checkpointsDirectory := t.TempDir()
+	repo, err := NewTaskCheckpointRepository(checkpointsDirectory)
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.DeleteTaskCheckpoint(ctx, "non_existent_task"))
+
+	assert.NoError(t, repo.SaveTaskDetails(ctx, models.ReconTaskDetails{ID: "task_1"}))
+	assert.NoError(t, repo.SaveTaskDetails(ctx, models.ReconTaskDetails{ID: "task_2"}))
+	assert.NoError(t, repo.DeleteTaskCheckpoint(ctx, "task_1"))
+
+	// progress of the finished task that was still on its way is dropped
+	assert.NoError(t, repo.RecordSectionReconstructed(ctx, "task_1", "PrimaryFile-1234", 1))
+	assert.NoError(t, repo.Flush())
+
+	restarted, err := NewTaskCheckpointRepository(checkpointsDirectory)
+	assert.NoError(t, err)
+	checkpoints, err := restarted.GetAllTaskCheckpoints(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, checkpoints, 1)
+	assert.Equal(t, "task_2", checkpoints[0].TaskDetails.ID)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reconciler.io/models"
+	"strings"
+)
+
+// TaskCheckpointStore is where the TaskCheckpointRepository writes its checkpoints down.
+type TaskCheckpointStore interface {
+	// LoadTaskCheckpoints returns the checkpoints this service wrote, the tasks it is to resume after a restart.
+	LoadTaskCheckpoints(ctx context.Context) ([]*models.TaskCheckpoint, error)
+	SaveTaskCheckpoint(ctx context.Context, checkpoint models.TaskCheckpoint) error
+	// DeleteTaskCheckpoint deletes the checkpoint of the task, a checkpoint that is not there is already deleted.
+	DeleteTaskCheckpoint(ctx context.Context, taskID string) error
+}
+
+// FileTaskCheckpointStore keeps every checkpoint in a json file of its own under a directory.
+type FileTaskCheckpointStore struct {
+	directory string
+}
+
+func NewFileTaskCheckpointStore(directory string) (*FileTaskCheckpointStore, error) {
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("error on creating checkpoints directory: [%v]", err)
+	}
+	return &FileTaskCheckpointStore{directory: directory}, nil
+}
+
+func (s *FileTaskCheckpointStore) LoadTaskCheckpoints(ctx context.Context) ([]*models.TaskCheckpoint, error) {
+	entries, err := os.ReadDir(s.directory)
+	if err != nil {
+		return nil, fmt.Errorf("error on reading checkpoints directory: [%v]", err)
+	}
+
+	checkpoints := make([]*models.TaskCheckpoint, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		encoded, err := os.ReadFile(filepath.Join(s.directory, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error on reading checkpoint: [%v], Error: %v", entry.Name(), err)
+		}
+
+		var checkpoint models.TaskCheckpoint
+		err = json.Unmarshal(encoded, &checkpoint)
+		if err != nil {
+			log.Printf("Skipping unreadable checkpoint: [%v], Error: %v", entry.Name(), err)
+			continue
+		}
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+	return checkpoints, nil
+}
+
+func (s *FileTaskCheckpointStore) SaveTaskCheckpoint(ctx context.Context, checkpoint models.TaskCheckpoint) error {
+	taskID := checkpoint.TaskDetails.ID
+	encoded, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("error on encoding checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+
+	// write to a temporary file first so a crash never
+	// leaves a half written checkpoint behind
+	checkpointPath := s.checkpointPath(taskID)
+	temporaryPath := checkpointPath + ".tmp"
+	err = os.WriteFile(temporaryPath, encoded, 0600)
+	if err != nil {
+		return fmt.Errorf("error on writing checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+
+	err = os.Rename(temporaryPath, checkpointPath)
+	if err != nil {
+		return fmt.Errorf("error on writing checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+	return nil
+}
+
+func (s *FileTaskCheckpointStore) DeleteTaskCheckpoint(ctx context.Context, taskID string) error {
+	err := os.Remove(s.checkpointPath(taskID))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error on deleting checkpoint for task: [%v], Error: %v", taskID, err)
+	}
+	return nil
+}
+
+func (s *FileTaskCheckpointStore) checkpointPath(taskID string) string {
+	return filepath.Join(s.directory, fmt.Sprintf("%v.json", taskID))
+}
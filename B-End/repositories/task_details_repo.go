@@ -9,17 +9,49 @@ import (
 
 	"reconciler.io/constants"
 	"reconciler.io/models"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/task_status"
 	"strconv"
+	"time"
 )
 
+// TaskDetailsRepository keeps the tasks in its TaskDetailsStore and moves them through their statuses.
+// The streams, control and progress of a task are only known to the service that created
+// or restored the task, copies of the task handed out by other services come without them.
+// Every task of the service sends its sections to be reconstructed over the same NATS connection.
 type TaskDetailsRepository struct {
-	reconTasksMap   map[string]*models.ReconTaskDetails
-	reconTasksMutex sync.Mutex
+	store                 TaskDetailsStore
+	runtimes              map[string]*taskRuntime
+	reconstructionStreams models.StreamProvider
+	reconTasksMutex       sync.Mutex
+	checkpoints           *TaskCheckpointRepository
+	events                *models.TaskEventBus
+	results               models.ReconResultRecorder
+	resultTables          models.ResultTableWriter
+	resultStreams         models.StreamProvider
+	files                 models.FileStore
 }
 
-func NewTaskDetailsRepository() *TaskDetailsRepository {
+// taskRuntime is what a running task needs that can't be kept in a store
+type taskRuntime struct {
+	control         *models.TaskControl
+	progressTracker *models.TaskProgressTracker
+}
+
+// NewTaskDetailsRepository creates the repository with its tasks kept in memory. When checkpoints is not nil
+// every change to a task is also written to the task's checkpoint.
+func NewTaskDetailsRepository(checkpoints *TaskCheckpointRepository) *TaskDetailsRepository {
+	return NewTaskDetailsRepositoryWithStore(NewInMemoryTaskDetailsStore(), checkpoints)
+}
+
+// NewTaskDetailsRepositoryWithStore creates the repository with its tasks kept in the given store.
+func NewTaskDetailsRepositoryWithStore(store TaskDetailsStore, checkpoints *TaskCheckpointRepository) *TaskDetailsRepository {
 	return &TaskDetailsRepository{
-		reconTasksMap: make(map[string]*models.ReconTaskDetails),
+		store:       store,
+		runtimes:    make(map[string]*taskRuntime),
+		checkpoints: checkpoints,
+		events:      models.NewTaskEventBus(constants.TASK_EVENTS_BUFFER_SIZE),
+		files:       models.NewLocalFileStore(constants.FILE_STORE_DIRECTORY),
 	}
 }
 
@@ -35,20 +67,101 @@ func (r *TaskDetailsRepository) SaveTaskDetails(ctx context.Context, taskDetails
 	defer r.reconTasksMutex.Unlock()
 
 	if len(taskDetails.ID) <= 0 {
-		nextID := len(r.reconTasksMap) + 1
-		taskID := "task_" + strconv.Itoa(nextID)
+		taskID, err := r.nextTaskIDLocked(ctx)
+		if err != nil {
+			return "", err
+		}
 		taskDetails.ID = taskID
 	}
 
-	toBeReconstructedFileSectionsStream, err := models.NewStreamProvider(constants.NATS_URL)
+	// every task starts out as Created,
+	// whatever the caller sent along
+	now := time.Now()
+	taskDetails.Status = task_status.Created
+	taskDetails.Phases = map[task_status.TaskStatus]*models.TaskPhase{task_status.Created: {StartedAt: now}}
+	taskDetails.SetStatusFlags()
+	taskDetails.Error = ""
+	taskDetails.IsPaused = false
+	taskDetails.CreatedAt = now
+	taskDetails.UpdatedAt = now
+	taskDetails.ResultsFilePath = ""
+	taskDetails.Summary = nil
+	taskDetails.Webhooks = withWebhookIDs(taskDetails.Webhooks)
+
+	err := r.attachTaskStreamsAndSaveLocked(ctx, &taskDetails)
 
 	if err != nil {
-		err = fmt.Errorf("error on creating toBeReconstructedFileSectionsStream: [%v]", err)
 		return "", err
 	}
 
+	return taskDetails.ID, nil
+}
+
+func (r *TaskDetailsRepository) nextTaskIDLocked(ctx context.Context) (string, error) {
+	for {
+		taskID, err := r.store.NextTaskID(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		// tasks restored after a restart may already use this ID
+		_, err = r.store.GetTaskDetails(ctx, taskID)
+		if errors.Is(err, ErrTaskNotFound) {
+			return taskID, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// RestoreTaskDetails puts a task recovered from its checkpoint
+// back into the repository under its original ID.
+// When the store has the task as it was after the checkpoint was taken, that task is kept instead,
+// a task that has finished since is handed back as it is.
+func (r *TaskDetailsRepository) RestoreTaskDetails(ctx context.Context, taskDetails models.ReconTaskDetails) (models.ReconTaskDetails, error) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	stored, err := r.store.GetTaskDetails(ctx, taskDetails.ID)
+	if err != nil && !errors.Is(err, ErrTaskNotFound) {
+		return models.ReconTaskDetails{}, err
+	}
+	if err == nil && stored.UpdatedAt.After(taskDetails.UpdatedAt) {
+		taskDetails = stored
+		if taskDetails.Status.IsTerminal() {
+			return taskDetails, r.saveCheckpoint(ctx, taskDetails)
+		}
+	}
+
+	// the checkpoint replaces the stored task whichever version it was taken at
+	if err == nil {
+		taskDetails.Version = stored.Version
+	}
+
+	err = r.attachTaskStreamsAndSaveLocked(ctx, &taskDetails)
+
+	if err != nil {
+		return models.ReconTaskDetails{}, err
+	}
+
+	return taskDetails, nil
+}
+
+func (r *TaskDetailsRepository) attachTaskStreamsAndSaveLocked(ctx context.Context, taskDetails *models.ReconTaskDetails) error {
+	//connect on the first task, the tasks after it share the connection
+	if r.reconstructionStreams == nil {
+		toBeReconstructedFileSectionsStream, err := models.NewStreamProvider(constants.NATS_URL)
+
+		if err != nil {
+			err = fmt.Errorf("error on creating toBeReconstructedFileSectionsStream: [%v]", err)
+			return err
+		}
+		r.reconstructionStreams = toBeReconstructedFileSectionsStream
+	}
+
 	topicName := fmt.Sprintf("Reconstruct-%v", taskDetails.ID)
-	err = toBeReconstructedFileSectionsStream.SetupStream(
+	err := r.reconstructionStreams.SetupStream(
 		ctx,
 		constants.FILE_RECONSTRUCTION_STREAM_NAME,
 		topicName,
@@ -56,63 +169,535 @@ func (r *TaskDetailsRepository) SaveTaskDetails(ctx context.Context, taskDetails
 
 	if err != nil {
 		err = fmt.Errorf("error on setting up toBeReconstructedFileSectionsStream: [%v]", err)
-		return "", err
+		return err
+	}
+
+	runtime := &taskRuntime{
+		control:         models.NewTaskControl(),
+		progressTracker: models.NewTaskProgressTracker(),
+	}
+	if taskDetails.Status == task_status.Cancelled {
+		runtime.control.Cancel()
 	}
+	if taskDetails.IsPaused {
+		runtime.control.Pause()
+	}
+	r.runtimes[taskDetails.ID] = runtime
+	*taskDetails = r.withRuntimeLocked(*taskDetails)
 
-	taskDetails.FileToBeReconstructedChannel = toBeReconstructedFileSectionsStream
-	r.reconTasksMap[taskDetails.ID] = &taskDetails
+	return r.saveLocked(ctx, *taskDetails)
+}
 
-	return taskDetails.ID, nil
+// getLocked loads the task from the store along with its runtime
+func (r *TaskDetailsRepository) getLocked(ctx context.Context, taskID string) (models.ReconTaskDetails, error) {
+	task, err := r.store.GetTaskDetails(ctx, taskID)
+	if err != nil {
+		return models.ReconTaskDetails{}, err
+	}
+	return r.withRuntimeLocked(task), nil
+}
+
+func (r *TaskDetailsRepository) withRuntimeLocked(task models.ReconTaskDetails) models.ReconTaskDetails {
+	if runtime, exists := r.runtimes[task.ID]; exists {
+		task.FileToBeReconstructedChannel = r.reconstructionStreams
+		task.Control = runtime.control
+		task.ProgressTracker = runtime.progressTracker
+	}
+	task.Events = r.events
+	if r.checkpoints != nil {
+		task.Checkpoints = r.checkpoints
+	}
+	if r.results != nil {
+		task.Results = r.results
+	}
+	task.ResultTables = r.resultTables
+	task.ResultStreams = r.resultStreams
+	task.Files = r.files
+	return task
 }
 
+// saveLocked writes the task to the store and its checkpoint
+func (r *TaskDetailsRepository) saveLocked(ctx context.Context, task models.ReconTaskDetails) error {
+	task = withoutRuntime(task)
+	err := r.store.SaveTaskDetails(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	return r.saveCheckpoint(ctx, task)
+}
+
+// saveCheckpoint snapshots the task into its checkpoint,
+// a task that has finished has its checkpoint deleted instead
+func (r *TaskDetailsRepository) saveCheckpoint(ctx context.Context, task models.ReconTaskDetails) error {
+	if r.checkpoints == nil {
+		return nil
+	}
+
+	if task.Status.IsTerminal() {
+		err := r.checkpoints.DeleteTaskCheckpoint(ctx, task.ID)
+		if err != nil {
+			return fmt.Errorf("error on deleting task checkpoint: [%v]", err)
+		}
+		return nil
+	}
+
+	err := r.checkpoints.SaveTaskDetails(ctx, task)
+
+	if err != nil {
+		return fmt.Errorf("error on saving task checkpoint: [%v]", err)
+	}
+	return nil
+}
+
+// UpdateReconciliationTask saves the task over its latest version, when another service
+// changed the task in the meantime the update is applied again to the task as it is now.
 func (r *TaskDetailsRepository) UpdateReconciliationTask(ctx context.Context, taskDetails models.ReconTaskDetails) error {
 	r.reconTasksMutex.Lock()
 	defer r.reconTasksMutex.Unlock()
 
-	if _, exists := r.reconTasksMap[taskDetails.ID]; !exists {
-		return errors.New("task not found")
+	var err error
+	for attempt := 1; attempt <= constants.TASK_UPDATE_MAX_ATTEMPTS; attempt++ {
+		err = r.updateLocked(ctx, taskDetails)
+		if !errors.Is(err, ErrTaskVersionConflict) {
+			return err
+		}
 	}
 
-	r.reconTasksMap[taskDetails.ID] = &taskDetails
+	return fmt.Errorf("error on updating task: [%v], Error: %w", taskDetails.ID, err)
+}
 
-	return nil
+// updateLocked saves the task over the stored one, keeping what only changes through the other methods
+func (r *TaskDetailsRepository) updateLocked(ctx context.Context, taskDetails models.ReconTaskDetails) error {
+	existing, err := r.getLocked(ctx, taskDetails.ID)
+	if err != nil {
+		return err
+	}
+
+	// the status and pausing only ever change through their own methods,
+	// the caller may be holding a copy from before the last transition
+	taskDetails.IsPaused = existing.IsPaused
+	taskDetails.Status = existing.Status
+	taskDetails.Phases = existing.Phases
+	taskDetails.SetStatusFlags()
+	taskDetails.Error = existing.Error
+	taskDetails.CreatedAt = existing.CreatedAt
+	taskDetails.UpdatedAt = time.Now()
+	taskDetails.ResultsFilePath = existing.ResultsFilePath
+	taskDetails.Summary = existing.Summary
+	taskDetails.Webhooks = existing.Webhooks
+	taskDetails.NotificationEmails = existing.NotificationEmails
+	taskDetails.Version = existing.Version
+
+	return r.saveLocked(ctx, taskDetails)
 }
 
 func (r *TaskDetailsRepository) AttachPrimaryFile(ctx context.Context, taskID, primaryFileID string) error {
 	r.reconTasksMutex.Lock()
 	defer r.reconTasksMutex.Unlock()
 
-	if _, exists := r.reconTasksMap[taskID]; !exists {
-		return errors.New("task not found")
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
 	}
 
-	r.reconTasksMap[taskID].PrimaryFileID = primaryFileID
+	task.PrimaryFileID = primaryFileID
 
-	return nil
+	// files can't be swapped once the task has moved on
+	err = transitionToFileStatus(&task)
+	if err != nil {
+		return err
+	}
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.TaskStatusChanged)
 }
 
 func (r *TaskDetailsRepository) AttachComparisonFile(ctx context.Context, taskID, comparisonFileID string) error {
 	r.reconTasksMutex.Lock()
 	defer r.reconTasksMutex.Unlock()
 
-	if _, exists := r.reconTasksMap[taskID]; !exists {
-		return errors.New("task not found")
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
 	}
 
-	r.reconTasksMap[taskID].ComparisonFileID = comparisonFileID
+	task.ComparisonFileID = comparisonFileID
 
-	return nil
+	// files can't be swapped once the task has moved on
+	err = transitionToFileStatus(&task)
+	if err != nil {
+		return err
+	}
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.TaskStatusChanged)
 }
 
 func (r *TaskDetailsRepository) GetReconciliationTaskStatus(ctx context.Context, taskID string) (models.ReconTaskDetails, error) {
 	r.reconTasksMutex.Lock()
 	defer r.reconTasksMutex.Unlock()
 
-	task, exists := r.reconTasksMap[taskID]
-	if !exists {
+	task, err := r.getLocked(ctx, taskID)
+	if errors.Is(err, ErrTaskNotFound) {
 		errorDetail := fmt.Sprintf("task with ID [%v] not found", taskID)
 		return models.ReconTaskDetails{}, errors.New(errorDetail)
 	}
+	if err != nil {
+		return models.ReconTaskDetails{}, err
+	}
+
+	return withProgress(task), nil
+}
+
+// GetAllReconciliationTasks returns every task in the store, only the tasks
+// this service created or restored come with their streams and control.
+func (r *TaskDetailsRepository) GetAllReconciliationTasks(ctx context.Context) ([]models.ReconTaskDetails, error) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	tasks, err := r.store.GetAllTaskDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		tasks[i] = r.withRuntimeLocked(tasks[i])
+	}
+	return tasks, nil
+}
+
+// CompleteReconciliationTask records where the results of the task were written
+// and moves the task to Completed.
+func (r *TaskDetailsRepository) CompleteReconciliationTask(ctx context.Context, taskID string, resultsFilePath string) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
 
-	return *task, nil
+	if task.Status == task_status.Completed {
+		return nil
+	}
+
+	err = task.TransitionTo(task_status.Completed, time.Now())
+	if err != nil {
+		return err
+	}
+	task.ResultsFilePath = resultsFilePath
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.TaskCompleted)
+}
+
+// AddWebhook registers a webhook on the task and returns it with its ID.
+func (r *TaskDetailsRepository) AddWebhook(ctx context.Context, taskID string, webhook models.Webhook) (models.Webhook, error) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	err = webhook.Validate()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	// copy so that callers holding the task don't see the new webhook appear
+	webhooks := append(append([]models.Webhook{}, task.Webhooks...), webhook)
+	task.Webhooks = withWebhookIDs(webhooks)
+
+	return task.Webhooks[len(task.Webhooks)-1], r.saveLocked(ctx, task)
+}
+
+// SetEmailRecipients replaces the addresses that are emailed when the task finishes.
+func (r *TaskDetailsRepository) SetEmailRecipients(ctx context.Context, taskID string, recipients []string) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	err = models.ValidateEmailRecipients(recipients)
+	if err != nil {
+		return err
+	}
+
+	task.NotificationEmails = append([]string{}, recipients...)
+
+	return r.saveLocked(ctx, task)
+}
+
+// SaveReconSummary keeps the summary of the results on the task.
+func (r *TaskDetailsRepository) SaveReconSummary(ctx context.Context, taskID string, summary models.ReconSummary) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	task.Summary = &summary
+
+	return r.saveLocked(ctx, task)
+}
+
+// CancelReconciliationTask stops everything the task is doing for good.
+func (r *TaskDetailsRepository) CancelReconciliationTask(ctx context.Context, taskID string) (models.ReconTaskDetails, error) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return models.ReconTaskDetails{}, err
+	}
+
+	err = task.TransitionTo(task_status.Cancelled, time.Now())
+	if err != nil {
+		return models.ReconTaskDetails{}, err
+	}
+
+	task.Control.Cancel()
+
+	return task, r.saveAndPublishLocked(ctx, task, task_event_type.TaskCancelled)
+}
+
+// PauseReconciliationTask stops the task from taking on any more sections,
+// sections that are already being worked on are finished.
+func (r *TaskDetailsRepository) PauseReconciliationTask(ctx context.Context, taskID string) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	if task.Status.IsTerminal() {
+		return fmt.Errorf("task with ID [%v] is no longer running", taskID)
+	}
+
+	task.IsPaused = true
+	task.Control.Pause()
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.TaskPaused)
+}
+
+// ResumeReconciliationTask lets a paused task carry on from where it was paused.
+func (r *TaskDetailsRepository) ResumeReconciliationTask(ctx context.Context, taskID string) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	if !task.IsPaused {
+		return fmt.Errorf("task with ID [%v] is not paused", taskID)
+	}
+
+	task.IsPaused = false
+	task.Control.Resume()
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.TaskResumed)
+}
+
+// TransitionReconciliationTask moves the task to the given status.
+// Moving a task to the status it already has does nothing,
+// so steps that are retried or resumed can call it again.
+func (r *TaskDetailsRepository) TransitionReconciliationTask(ctx context.Context, taskID string, status task_status.TaskStatus) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	if task.Status == status {
+		return nil
+	}
+
+	err = task.TransitionTo(status, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.ForStatus(status))
+}
+
+// FailReconciliationTask moves the task to Failed and records the error.
+// A task that has already finished or was cancelled is left as it is.
+func (r *TaskDetailsRepository) FailReconciliationTask(ctx context.Context, taskID string, taskErr error) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	task, err := r.getLocked(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	if task.Status.IsTerminal() {
+		return nil
+	}
+
+	err = task.Fail(taskErr, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return r.saveAndPublishLocked(ctx, task, task_event_type.TaskFailed)
+}
+
+// saveAndPublishLocked saves the task and lets whoever is watching the task know about the change.
+// A change that could not be stored is not published, one that only missed its checkpoint is.
+func (r *TaskDetailsRepository) saveAndPublishLocked(ctx context.Context, task models.ReconTaskDetails, eventType task_event_type.TaskEventType) error {
+	err := r.store.SaveTaskDetails(ctx, withoutRuntime(task))
+	if err != nil {
+		return err
+	}
+
+	task = withProgress(task)
+	r.events.Publish(models.TaskEvent{
+		TaskID:     task.ID,
+		Type:       eventType,
+		Status:     task.Status,
+		Error:      task.Error,
+		Progress:   task.Progress,
+		OccurredAt: time.Now(),
+	})
+
+	return r.saveCheckpoint(ctx, withoutRuntime(task))
+}
+
+// SubscribeToTaskEvents returns the channel the events of the task are sent on
+// and the function that ends the subscription.
+func (r *TaskDetailsRepository) SubscribeToTaskEvents(taskID string) (<-chan models.TaskEvent, func()) {
+	return r.events.Subscribe(taskID)
+}
+
+// RecordResultsIn has the reconstruction of every task keep the result of each row in the given recorder.
+func (r *TaskDetailsRepository) RecordResultsIn(results models.ReconResultRecorder) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	r.results = results
+}
+
+// SendResultSinksTo gives the result sinks of every task the tables and the streams they send the results to,
+// either can be nil when the service has none.
+func (r *TaskDetailsRepository) SendResultSinksTo(tables models.ResultTableWriter, streams models.StreamProvider) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	r.resultTables = tables
+	r.resultStreams = streams
+}
+
+// StoreFilesIn has every task keep its uploaded files and its results files in the given store.
+func (r *TaskDetailsRepository) StoreFilesIn(files models.FileStore) {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	r.files = files
+}
+
+// OnTaskEvent registers a listener that is called with every event of every task,
+// see models.TaskEventBus.AddListener.
+func (r *TaskDetailsRepository) OnTaskEvent(listener func(event models.TaskEvent)) {
+	r.events.AddListener(listener)
+}
+
+// CloseTaskStreams closes the NATS connection the tasks send their sections to be reconstructed over.
+// A task created or restored after it is closed connects again.
+func (r *TaskDetailsRepository) CloseTaskStreams(ctx context.Context) error {
+	r.reconTasksMutex.Lock()
+	defer r.reconTasksMutex.Unlock()
+
+	if r.reconstructionStreams == nil {
+		return nil
+	}
+
+	err := r.reconstructionStreams.Close(ctx)
+	r.reconstructionStreams = nil
+
+	//error on closing the connection
+	if err != nil {
+		return fmt.Errorf("error on closing toBeReconstructedFileSectionsStream: [%v]", err)
+	}
+	return nil
+}
+
+// CloseTaskEventStreams ends every subscription to task events.
+func (r *TaskDetailsRepository) CloseTaskEventStreams() {
+	r.events.Close()
+}
+
+// transitionToFileStatus moves a task that is still waiting on its
+// files to Reading once both files are attached, or to AwaitingFiles before that.
+func transitionToFileStatus(task *models.ReconTaskDetails) error {
+	status := task_status.AwaitingFiles
+	if len(task.PrimaryFileID) > 0 && len(task.ComparisonFileID) > 0 {
+		status = task_status.Reading
+	}
+
+	if task.Status == status {
+		return nil
+	}
+
+	return task.TransitionTo(status, time.Now())
+}
+
+// withProgress fills in the progress of the task as it is right now.
+// A completed task is always all the way done.
+func withProgress(task models.ReconTaskDetails) models.ReconTaskDetails {
+	task.Progress = task.ProgressTracker.Snapshot(time.Now())
+	if task.Progress != nil && task.Status == task_status.Completed {
+		task.Progress.Percentage = 100
+		task.Progress.EstimatedSecondsRemaining = nil
+		task.Progress.EstimatedCompletionAt = nil
+	}
+	return task
+}
+
+// withoutRuntime leaves out what only makes sense inside this service, along with
+// the progress which is worked out from the progress tracker whenever the task is read.
+func withoutRuntime(task models.ReconTaskDetails) models.ReconTaskDetails {
+	task.FileToBeReconstructedChannel = nil
+	task.Checkpoints = nil
+	task.Control = nil
+	task.ProgressTracker = nil
+	task.Events = nil
+	task.Results = nil
+	task.ResultTables = nil
+	task.ResultStreams = nil
+	task.Files = nil
+	task.Progress = nil
+	return task
+}
+
+// withWebhookIDs gives every webhook that doesn't have one an ID unique to its task.
+func withWebhookIDs(webhooks []models.Webhook) []models.Webhook {
+	usedIDs := make(map[string]bool, len(webhooks))
+	for _, webhook := range webhooks {
+		usedIDs[webhook.ID] = true
+	}
+
+	nextID := 1
+	for i := range webhooks {
+		if len(webhooks[i].ID) > 0 {
+			continue
+		}
+		for usedIDs["webhook_"+strconv.Itoa(nextID)] {
+			nextID++
+		}
+		webhooks[i].ID = "webhook_" + strconv.Itoa(nextID)
+		usedIDs[webhooks[i].ID] = true
+	}
+	return webhooks
 }
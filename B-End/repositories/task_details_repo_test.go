@@ -2,7 +2,11 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"reconciler.io/constants"
 	"reconciler.io/models"
+	"reconciler.io/models/enums/task_event_type"
+	"reconciler.io/models/enums/task_status"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,7 +21,8 @@ func TestCreateReconciliationTask(t *testing.T) {
 		ReconConfig:     models.ReconciliationConfigs{},
 	}
 
-	taskID, err := SaveTaskDetails(ctx, taskDetails)
+	repo := NewTaskDetailsRepository(nil)
+	taskID, err := repo.SaveTaskDetails(ctx, taskDetails)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, taskID)
 }
@@ -32,16 +37,49 @@ func TestUpdateReconciliationTask(t *testing.T) {
 	}
 
 	// Create a task first.
-	taskID, _ := SaveTaskDetails(ctx, taskDetails)
+	repo := NewTaskDetailsRepository(nil)
+	taskID, _ := repo.SaveTaskDetails(ctx, taskDetails)
 
 	// Update the task.
-	taskDetails.IsDone = true
-	err := UpdateReconciliationTask(ctx, taskID, taskDetails)
+	taskDetails.ID = taskID
+	taskDetails.MaxConcurrentSections = 4
+	err := repo.UpdateReconciliationTask(ctx, taskDetails)
 	assert.NoError(t, err)
 
 	// Retrieve the task and check the updated value.
-	updatedTask, _ := GetReconciliationTaskStatus(ctx, taskID)
-	assert.True(t, updatedTask.IsDone)
+	updatedTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, 4, updatedTask.MaxConcurrentSections)
+}
+
+func TestStatusFlagsFollowTheStatusOfTheTask(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{IsDone: true, HasBegun: true})
+	assert.NoError(t, err)
+
+	createdTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.False(t, createdTask.IsDone)
+	assert.False(t, createdTask.HasBegun)
+
+	// a caller can't mark a task done that hasn't finished
+	assert.NoError(t, repo.UpdateReconciliationTask(ctx, models.ReconTaskDetails{ID: taskID, IsDone: true, HasBegun: true}))
+	updatedTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.False(t, updatedTask.IsDone)
+	assert.False(t, updatedTask.HasBegun)
+
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reading))
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reconciling))
+
+	// nor take back that a task has begun
+	assert.NoError(t, repo.UpdateReconciliationTask(ctx, models.ReconTaskDetails{ID: taskID}))
+	updatedTask, _ = repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.False(t, updatedTask.IsDone)
+	assert.True(t, updatedTask.HasBegun)
+
+	assert.NoError(t, repo.FailReconciliationTask(ctx, taskID, errors.New("comparison file is empty")))
+	failedTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.False(t, failedTask.IsDone)
+	assert.True(t, failedTask.HasBegun)
 }
 
 func TestGetReconciliationTaskStatus(t *testing.T) {
@@ -54,10 +92,11 @@ func TestGetReconciliationTaskStatus(t *testing.T) {
 	}
 
 	// Create a task first.
-	taskID, _ := SaveTaskDetails(ctx, taskDetails)
+	repo := NewTaskDetailsRepository(nil)
+	taskID, _ := repo.SaveTaskDetails(ctx, taskDetails)
 
 	// Retrieve the task.
-	retrievedTask, err := GetReconciliationTaskStatus(ctx, taskID)
+	retrievedTask, err := repo.GetReconciliationTaskStatus(ctx, taskID)
 	assert.NoError(t, err)
 	assert.Equal(t, taskID, retrievedTask.ID)
 }
@@ -66,6 +105,243 @@ func TestGetReconciliationTaskStatus_NotFound(t *testing.T) {
 	ctx := context.Background()
 
 	// Try to retrieve a non-existent task.
-	_, err := GetReconciliationTaskStatus(ctx, "non_existent_task")
+	repo := NewTaskDetailsRepository(nil)
+	_, err := repo.GetReconciliationTaskStatus(ctx, "non_existent_task")
+	assert.Error(t, err)
+}
+
+func TestPauseResumeAndCancelReconciliationTask(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+
+	assert.Error(t, repo.ResumeReconciliationTask(ctx, taskID))
+	assert.NoError(t, repo.PauseReconciliationTask(ctx, taskID))
+
+	// an update from a stale copy must not unpause the task
+	staleTask := models.ReconTaskDetails{ID: taskID, HasBegun: true}
+	assert.NoError(t, repo.UpdateReconciliationTask(ctx, staleTask))
+
+	pausedTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.True(t, pausedTask.IsPaused)
+	assert.False(t, pausedTask.HasBegun)
+	assert.True(t, pausedTask.Control.IsPaused())
+
+	assert.NoError(t, repo.ResumeReconciliationTask(ctx, taskID))
+	resumedTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.False(t, resumedTask.IsPaused)
+	assert.False(t, resumedTask.Control.IsPaused())
+
+	cancelledTask, err := repo.CancelReconciliationTask(ctx, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, task_status.Cancelled, cancelledTask.Status)
+	assert.Error(t, cancelledTask.Control.Context().Err())
+	assert.Error(t, repo.PauseReconciliationTask(ctx, taskID))
+}
+
+func TestReconciliationTaskStatusFollowsItsFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{Status: task_status.Completed})
+	assert.NoError(t, err)
+
+	createdTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, task_status.Created, createdTask.Status)
+
+	assert.NoError(t, repo.AttachPrimaryFile(ctx, taskID, "PrimaryFile-1"))
+	awaitingTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, task_status.AwaitingFiles, awaitingTask.Status)
+
+	assert.NoError(t, repo.AttachComparisonFile(ctx, taskID, "ComparisonFile-1"))
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reconciling))
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reconciling))
+
+	// the files can no longer be swapped
+	assert.Error(t, repo.AttachPrimaryFile(ctx, taskID, "PrimaryFile-2"))
+
+	assert.NoError(t, repo.FailReconciliationTask(ctx, taskID, errors.New("comparison stream unavailable")))
+	failedTask, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, task_status.Failed, failedTask.Status)
+	assert.Equal(t, "comparison stream unavailable", failedTask.Error)
+	assert.Equal(t, "PrimaryFile-1", failedTask.PrimaryFileID)
+	assert.NotNil(t, failedTask.Phases[task_status.Reconciling].EndedAt)
+
+	// a failed task stays failed
+	assert.NoError(t, repo.FailReconciliationTask(ctx, taskID, errors.New("another failure")))
+	failedTask, _ = repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, "comparison stream unavailable", failedTask.Error)
+}
+
+func TestTaskLifecycleIsPublishedAsEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, _ := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+
+	events, unsubscribe := repo.SubscribeToTaskEvents(taskID)
+	defer unsubscribe()
+
+	assert.NoError(t, repo.AttachPrimaryFile(ctx, taskID, "primary"))
+	assert.NoError(t, repo.AttachComparisonFile(ctx, taskID, "comparison"))
+	assert.NoError(t, repo.PauseReconciliationTask(ctx, taskID))
+	assert.NoError(t, repo.FailReconciliationTask(ctx, taskID, errors.New("boom")))
+
+	expected := []struct {
+		eventType task_event_type.TaskEventType
+		status    task_status.TaskStatus
+	}{
+		{task_event_type.TaskStatusChanged, task_status.AwaitingFiles},
+		{task_event_type.TaskStatusChanged, task_status.Reading},
+		{task_event_type.TaskPaused, task_status.Reading},
+		{task_event_type.TaskFailed, task_status.Failed},
+	}
+	for _, want := range expected {
+		event := <-events
+		assert.Equal(t, taskID, event.TaskID)
+		assert.Equal(t, want.eventType, event.Type)
+		assert.Equal(t, want.status, event.Status)
+	}
+
+	repo.CloseTaskEventStreams()
+	_, isOpen := <-events
+	assert.False(t, isOpen)
+}
+
+func TestWebhooksAndResultsOfTask(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, _ := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{
+		Webhooks: []models.Webhook{{URL: "http://erp.local/hooks"}},
+	})
+
+	webhook, err := repo.AddWebhook(ctx, taskID, models.Webhook{URL: "https://tickets.local/hooks"})
+	assert.NoError(t, err)
+	assert.Equal(t, "webhook_2", webhook.ID)
+
+	_, err = repo.AddWebhook(ctx, taskID, models.Webhook{URL: "ftp://tickets.local"})
+	assert.Error(t, err)
+	for _, privateURL := range []string{
+		"http://localhost:8080/hooks",
+		"http://127.0.0.1/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hooks",
+		"https://[::1]/hooks",
+	} {
+		_, err = repo.AddWebhook(ctx, taskID, models.Webhook{URL: privateURL})
+		assert.Error(t, err, privateURL)
+	}
+
+	allowPrivateHosts := constants.WEBHOOK_ALLOW_PRIVATE_HOSTS
+	constants.WEBHOOK_ALLOW_PRIVATE_HOSTS = true
+	_, err = repo.AddWebhook(ctx, taskID, models.Webhook{URL: "http://localhost:8080/hooks"})
+	constants.WEBHOOK_ALLOW_PRIVATE_HOSTS = allowPrivateHosts
+	assert.NoError(t, err)
+	_, err = repo.AddWebhook(ctx, taskID, models.Webhook{
+		URL:    "https://tickets.local/hooks",
+		Events: []task_event_type.TaskEventType{task_event_type.SectionReconciled},
+	})
 	assert.Error(t, err)
+
+	// completing needs the task to have reached Reconstructing
+	assert.Error(t, repo.CompleteReconciliationTask(ctx, taskID, "results.csv"))
+
+	assert.NoError(t, repo.AttachPrimaryFile(ctx, taskID, "primary"))
+	assert.NoError(t, repo.AttachComparisonFile(ctx, taskID, "comparison"))
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reconciling))
+	assert.NoError(t, repo.TransitionReconciliationTask(ctx, taskID, task_status.Reconstructing))
+	assert.NoError(t, repo.CompleteReconciliationTask(ctx, taskID, "results.csv"))
+
+	task, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, task_status.Completed, task.Status)
+	assert.Equal(t, "results.csv", task.ResultsFilePath)
+	assert.Len(t, task.Webhooks, 3)
+	assert.Equal(t, "webhook_1", task.Webhooks[0].ID)
+}
+
+func TestSetEmailRecipientsOfTask(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, _ := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+
+	assert.Error(t, repo.SetEmailRecipients(ctx, taskID, []string{"not an address"}))
+	assert.Error(t, repo.SetEmailRecipients(ctx, taskID, []string{"Finance <finance@example.com>"}))
+	assert.Error(t, repo.SetEmailRecipients(ctx, "non_existent_task", []string{"ops@example.com"}))
+
+	recipients := []string{"ops@example.com", "finance@example.com"}
+	assert.NoError(t, repo.SetEmailRecipients(ctx, taskID, recipients))
+	recipients[0] = "changed@example.com"
+
+	task, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, []string{"ops@example.com", "finance@example.com"}, task.NotificationEmails)
+
+	// an update from a stale copy keeps the recipients
+	assert.NoError(t, repo.UpdateReconciliationTask(ctx, models.ReconTaskDetails{ID: taskID}))
+	task, _ = repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Len(t, task.NotificationEmails, 2)
+}
+
+func TestSaveReconSummaryOfTask(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+	taskID, _ := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{Summary: &models.ReconSummary{MatchedRows: 9}})
+
+	task, _ := repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Nil(t, task.Summary)
+
+	assert.Error(t, repo.SaveReconSummary(ctx, "non_existent_task", models.ReconSummary{}))
+	assert.NoError(t, repo.SaveReconSummary(ctx, taskID, models.ReconSummary{PrimaryFileRows: 3, MatchedRows: 2}))
+
+	// an update from a stale copy keeps the summary
+	assert.NoError(t, repo.UpdateReconciliationTask(ctx, task))
+	task, _ = repo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.Equal(t, int64(3), task.Summary.PrimaryFileRows)
+	assert.Equal(t, int64(2), task.Summary.MatchedRows)
+}
+
+func TestFinishedTasksLoseTheirCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	checkpoints, err := NewTaskCheckpointRepository(t.TempDir())
+	assert.NoError(t, err)
+	repo := NewTaskDetailsRepository(checkpoints)
+
+	runningTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+	failedTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+	cancelledTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.FailReconciliationTask(ctx, failedTaskID, errors.New("comparison file is empty")))
+	_, err = repo.CancelReconciliationTask(ctx, cancelledTaskID)
+	assert.NoError(t, err)
+
+	remaining, err := checkpoints.GetAllTaskCheckpoints(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, runningTaskID, remaining[0].TaskDetails.ID)
+}
+
+func TestTasksShareOneReconstructionStreamConnection(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTaskDetailsRepository(nil)
+
+	firstTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+	secondTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+
+	firstTask, _ := repo.GetReconciliationTaskStatus(ctx, firstTaskID)
+	secondTask, _ := repo.GetReconciliationTaskStatus(ctx, secondTaskID)
+	assert.NotNil(t, firstTask.FileToBeReconstructedChannel)
+	assert.Same(t, firstTask.FileToBeReconstructedChannel, secondTask.FileToBeReconstructedChannel)
+
+	// a task created once the connection is closed connects again
+	assert.NoError(t, repo.CloseTaskStreams(ctx))
+	assert.NoError(t, repo.CloseTaskStreams(ctx))
+	thirdTaskID, err := repo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+	thirdTask, _ := repo.GetReconciliationTaskStatus(ctx, thirdTaskID)
+	assert.NotNil(t, thirdTask.FileToBeReconstructedChannel)
+	assert.NotSame(t, firstTask.FileToBeReconstructedChannel, thirdTask.FileToBeReconstructedChannel)
+	assert.NoError(t, repo.CloseTaskStreams(ctx))
 }
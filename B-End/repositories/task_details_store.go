@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reconciler.io/models"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrTaskVersionConflict is returned when a task is saved over a version of it that is no longer the latest.
+var ErrTaskVersionConflict = errors.New("task was changed since it was loaded")
+
+// TaskDetailsStore is where the TaskDetailsRepository keeps its tasks.
+// Only what can be written down is kept, the streams and controls of
+// a task belong to the service that is running it.
+type TaskDetailsStore interface {
+	// NextTaskID hands out a task ID that has not been handed out before.
+	NextTaskID(ctx context.Context) (string, error)
+	// SaveTaskDetails only replaces the stored task when it is still at the Version of the given task,
+	// and returns ErrTaskVersionConflict when it is not. The saved task is at the next version.
+	SaveTaskDetails(ctx context.Context, taskDetails models.ReconTaskDetails) error
+	// GetTaskDetails returns ErrTaskNotFound for tasks that were never saved.
+	GetTaskDetails(ctx context.Context, taskID string) (models.ReconTaskDetails, error)
+	GetAllTaskDetails(ctx context.Context) ([]models.ReconTaskDetails, error)
+}
+
+// InMemoryTaskDetailsStore keeps the tasks in a map, they are gone once the service stops.
+type InMemoryTaskDetailsStore struct {
+	tasksMap   map[string]models.ReconTaskDetails
+	lastTaskID int
+	tasksMutex sync.Mutex
+}
+
+func NewInMemoryTaskDetailsStore() *InMemoryTaskDetailsStore {
+	return &InMemoryTaskDetailsStore{
+		tasksMap: make(map[string]models.ReconTaskDetails),
+	}
+}
+
+func (s *InMemoryTaskDetailsStore) NextTaskID(ctx context.Context) (string, error) {
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+
+	s.lastTaskID++
+	return "task_" + strconv.Itoa(s.lastTaskID), nil
+}
+
+func (s *InMemoryTaskDetailsStore) SaveTaskDetails(ctx context.Context, taskDetails models.ReconTaskDetails) error {
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+
+	stored, exists := s.tasksMap[taskDetails.ID]
+	if exists && stored.Version != taskDetails.Version {
+		return ErrTaskVersionConflict
+	}
+
+	taskDetails.Version++
+	s.tasksMap[taskDetails.ID] = taskDetails
+	return nil
+}
+
+func (s *InMemoryTaskDetailsStore) GetTaskDetails(ctx context.Context, taskID string) (models.ReconTaskDetails, error) {
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+
+	task, exists := s.tasksMap[taskID]
+	if !exists {
+		return models.ReconTaskDetails{}, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (s *InMemoryTaskDetailsStore) GetAllTaskDetails(ctx context.Context) ([]models.ReconTaskDetails, error) {
+	s.tasksMutex.Lock()
+	defer s.tasksMutex.Unlock()
+
+	tasks := make([]models.ReconTaskDetails, 0, len(s.tasksMap))
+	for _, task := range s.tasksMap {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return tasks, nil
+}
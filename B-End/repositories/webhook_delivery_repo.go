@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/webhook_delivery_status"
+	"sync"
+	"time"
+)
+
+// WebhookDeliveryRepository is the log of the webhooks called for each task.
+type WebhookDeliveryRepository struct {
+	store           WebhookDeliveryStore
+	deliveriesMutex sync.Mutex
+}
+
+// NewWebhookDeliveryRepository creates the repository with its deliveries kept in memory.
+func NewWebhookDeliveryRepository() *WebhookDeliveryRepository {
+	return NewWebhookDeliveryRepositoryWithStore(NewInMemoryWebhookDeliveryStore())
+}
+
+// NewWebhookDeliveryRepositoryWithStore creates the repository with its deliveries kept in the given store.
+func NewWebhookDeliveryRepositoryWithStore(store WebhookDeliveryStore) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{store: store}
+}
+
+func WebhookDeliveryRepositoryMiddleware(repo *WebhookDeliveryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("WebhookDeliveryRepository", repo)
+		c.Next()
+	}
+}
+
+// SaveWebhookDelivery starts the log of a new delivery, it is Pending until its attempts are recorded.
+func (r *WebhookDeliveryRepository) SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	r.deliveriesMutex.Lock()
+	defer r.deliveriesMutex.Unlock()
+
+	if len(delivery.ID) <= 0 {
+		return errors.New("webhook delivery has no ID")
+	}
+	_, err := r.store.GetWebhookDelivery(ctx, delivery.ID)
+	if err == nil {
+		return errors.New("webhook delivery already exists")
+	}
+	if !errors.Is(err, ErrWebhookDeliveryNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	delivery.Status = webhook_delivery_status.Pending
+	delivery.Attempts = []models.WebhookDeliveryAttempt{}
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	return r.store.SaveWebhookDelivery(ctx, delivery)
+}
+
+// RecordWebhookDeliveryAttempt adds an attempt to the log of the delivery
+// and moves the delivery to the given status.
+func (r *WebhookDeliveryRepository) RecordWebhookDeliveryAttempt(
+	ctx context.Context,
+	deliveryID string,
+	attempt models.WebhookDeliveryAttempt,
+	status webhook_delivery_status.WebhookDeliveryStatus,
+) error {
+	r.deliveriesMutex.Lock()
+	defer r.deliveriesMutex.Unlock()
+
+	delivery, err := r.store.GetWebhookDelivery(ctx, deliveryID)
+
+	//error on retrieve
+	if err != nil {
+		return fmt.Errorf("error on recording attempt of webhook delivery: [%v], Error: %w", deliveryID, err)
+	}
+
+	// copy so that callers holding the delivery don't see the new attempt appear
+	delivery.Attempts = append(append([]models.WebhookDeliveryAttempt{}, delivery.Attempts...), attempt)
+	delivery.Status = status
+	delivery.UpdatedAt = time.Now()
+	return r.store.SaveWebhookDelivery(ctx, delivery)
+}
+
+// GetWebhookDeliveriesForTask returns the deliveries of the task, oldest first.
+func (r *WebhookDeliveryRepository) GetWebhookDeliveriesForTask(ctx context.Context, taskID string) ([]models.WebhookDelivery, error) {
+	return r.store.GetWebhookDeliveriesForTask(ctx, taskID)
+}
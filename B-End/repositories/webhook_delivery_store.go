@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reconciler.io/models"
+	"sync"
+)
+
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookDeliveryStore is where the WebhookDeliveryRepository keeps the log of the webhooks called for each task.
+type WebhookDeliveryStore interface {
+	SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	// GetWebhookDelivery returns ErrWebhookDeliveryNotFound when there is no such delivery.
+	GetWebhookDelivery(ctx context.Context, deliveryID string) (models.WebhookDelivery, error)
+	// GetWebhookDeliveriesForTask returns the deliveries of the task, oldest first.
+	GetWebhookDeliveriesForTask(ctx context.Context, taskID string) ([]models.WebhookDelivery, error)
+}
+
+// InMemoryWebhookDeliveryStore keeps the deliveries in a map, they are gone once the service stops.
+type InMemoryWebhookDeliveryStore struct {
+	deliveriesMap    map[string]models.WebhookDelivery
+	deliveriesByTask map[string][]string
+	deliveriesMutex  sync.Mutex
+}
+
+func NewInMemoryWebhookDeliveryStore() *InMemoryWebhookDeliveryStore {
+	return &InMemoryWebhookDeliveryStore{
+		deliveriesMap:    make(map[string]models.WebhookDelivery),
+		deliveriesByTask: make(map[string][]string),
+	}
+}
+
+func (s *InMemoryWebhookDeliveryStore) SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	s.deliveriesMutex.Lock()
+	defer s.deliveriesMutex.Unlock()
+
+	if _, exists := s.deliveriesMap[delivery.ID]; !exists {
+		s.deliveriesByTask[delivery.TaskID] = append(s.deliveriesByTask[delivery.TaskID], delivery.ID)
+	}
+	s.deliveriesMap[delivery.ID] = delivery
+	return nil
+}
+
+func (s *InMemoryWebhookDeliveryStore) GetWebhookDelivery(ctx context.Context, deliveryID string) (models.WebhookDelivery, error) {
+	s.deliveriesMutex.Lock()
+	defer s.deliveriesMutex.Unlock()
+
+	delivery, exists := s.deliveriesMap[deliveryID]
+	if !exists {
+		return models.WebhookDelivery{}, ErrWebhookDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+func (s *InMemoryWebhookDeliveryStore) GetWebhookDeliveriesForTask(ctx context.Context, taskID string) ([]models.WebhookDelivery, error) {
+	s.deliveriesMutex.Lock()
+	defer s.deliveriesMutex.Unlock()
+
+	deliveries := make([]models.WebhookDelivery, 0, len(s.deliveriesByTask[taskID]))
+	for _, deliveryID := range s.deliveriesByTask[taskID] {
+		deliveries = append(deliveries, s.deliveriesMap[deliveryID])
+	}
+	return deliveries, nil
+}
@@ -1,13 +1,18 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go.temporal.io/sdk/client"
+	"net/http"
+	"time"
 )
 
 type RestApiServer struct {
 	*gin.Engine
 	TemporalClient client.Client
+	onShutdown     []func()
 }
 
 func NewRestApiServer() *RestApiServer {
@@ -16,3 +21,58 @@ func NewRestApiServer() *RestApiServer {
 		Engine: r,
 	}
 }
+
+// UseTemporalClient makes the handlers run tasks as Temporal workflows
+// through the given client instead of in goroutines.
+func (s *RestApiServer) UseTemporalClient(temporalClient client.Client) {
+	s.TemporalClient = temporalClient
+	s.Use(func(ctx *gin.Context) {
+		ctx.Set("TemporalClient", temporalClient)
+		ctx.Next()
+	})
+}
+
+// OnShutdown registers a function that is called as soon as the server starts
+// shutting down, used to end the long lived requests that Shutdown would otherwise wait on.
+func (s *RestApiServer) OnShutdown(f func()) {
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// RunUntil serves requests on the given address until the context is done,
+// then stops accepting new connections and waits up to shutdownTimeout
+// for the requests that are in flight to finish.
+func (s *RestApiServer) RunUntil(ctx context.Context, addr string, shutdownTimeout time.Duration) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: s.Engine,
+	}
+	for _, f := range s.onShutdown {
+		server.RegisterOnShutdown(f)
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErrors:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		return err
+	}
+
+	// ListenAndServe always returns ErrServerClosed after a shutdown
+	err = <-serverErrors
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -6,19 +6,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"reconciler.io/constants"
-	"reconciler.io/models/enums/file_storage_locations"
 	"reconciler.io/models/enums/supported_file_extensions"
+	"regexp"
 	"time"
 )
 
+// unsafeKeyCharacters are the characters of a user ID that can't be part of a file key
+var unsafeKeyCharacters = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// GenerateFilePath is the key of a new file in the file store,
+// the files of each user are kept apart from those of other users.
 func GenerateFilePath(
 	fileID string,
-	storageLocation file_storage_locations.FileStorageLocation,
 	userId string,
 	fileExtension supported_file_extensions.FileExtension,
 ) string {
 	timestamp := time.Now().Format("20060102150405") // YYYYMMDDHHMMSS format
-	return fmt.Sprintf("./%s_%s.%s", fileID, timestamp, fileExtension)
+	directory := "shared"
+	if len(userId) > 0 {
+		directory = "users/" + unsafeKeyCharacters.ReplaceAllString(userId, "_")
+	}
+	return fmt.Sprintf("%s/%s_%s.%s", directory, fileID, timestamp, fileExtension)
 }
 
 // ParseAndBindJsonToStruct Parse and bind activity
@@ -0,0 +1,210 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"log"
+	preprocessing "reconciler.io/activities/pre-processing"
+	"reconciler.io/activities/reconciliation"
+	"reconciler.io/activities/reconstruction"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/models/enums/task_status"
+	"reconciler.io/repositories"
+	"time"
+)
+
+// ReconTaskActivities are the steps of the ReconTaskWorkflow.
+// They only take the taskID so that the workflow history stays small,
+// the task and file details are looked up from the repositories.
+type ReconTaskActivities struct {
+	TaskDetailsRepo *repositories.TaskDetailsRepository
+	FileDetailsRepo *repositories.FileDetailsRepository
+}
+
+// ReadFile reads the task's file with the given purpose into its stream.
+func (a *ReconTaskActivities) ReadFile(ctx context.Context, taskID string, filePurpose file_purpose.FilePurposeType) error {
+	taskDetails, err := a.TaskDetailsRepo.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		return err
+	}
+
+	fileToBeRead, err := a.getFileForTask(ctx, taskID, filePurpose)
+
+	//error on retrieve
+	if err != nil {
+		return err
+	}
+
+	workCtx, stopWork := workContext(ctx, taskDetails)
+	defer stopWork()
+
+	err = preprocessing.ReadFileIntoChannel(workCtx, fileToBeRead, taskDetails, constants.FILE_SECTION_BATCH_SIZE)
+	return stopRetryingIfCancelled(taskDetails, err)
+}
+
+// ReconcileFiles reconciles the primary file of the task against the comparison file.
+func (a *ReconTaskActivities) ReconcileFiles(ctx context.Context, taskID string) error {
+	taskDetails, err := a.TaskDetailsRepo.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		return err
+	}
+
+	primaryFile, err := a.getFileForTask(ctx, taskID, file_purpose.PrimaryFile)
+
+	//error on retrieve
+	if err != nil {
+		return err
+	}
+
+	comparisonFile, err := a.getFileForTask(ctx, taskID, file_purpose.ComparisonFile)
+
+	//error on retrieve
+	if err != nil {
+		return err
+	}
+
+	//update the original recon tasks status
+	err = a.transitionTask(ctx, taskID, task_status.Reconciling)
+
+	//error on update
+	if err != nil {
+		return err
+	}
+
+	workCtx, stopWork := workContext(ctx, taskDetails)
+	defer stopWork()
+
+	err = reconciliation.ReconcileFiles(workCtx, primaryFile, comparisonFile, taskDetails)
+	return stopRetryingIfCancelled(taskDetails, err)
+}
+
+// ReconstructFile writes the reconciled sections of the task into the results file
+// and returns the path of that file.
+func (a *ReconTaskActivities) ReconstructFile(ctx context.Context, taskID string) (string, error) {
+	taskDetails, err := a.TaskDetailsRepo.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		return "", err
+	}
+
+	err = a.transitionTask(ctx, taskID, task_status.Reconstructing)
+
+	//error on update
+	if err != nil {
+		return "", err
+	}
+
+	workCtx, stopWork := workContext(ctx, taskDetails)
+	defer stopWork()
+
+	filePath := reconstruction.ResultsFilePath(taskDetails)
+	summary, err := reconstruction.ReconstructFile(workCtx, taskDetails, filePath)
+
+	//error on reconstruction
+	if err != nil {
+		return "", stopRetryingIfCancelled(taskDetails, err)
+	}
+
+	err = a.TaskDetailsRepo.SaveReconSummary(ctx, taskID, summary)
+
+	//error on update
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// NotifyTaskCompleted marks the task as done.
+func (a *ReconTaskActivities) NotifyTaskCompleted(ctx context.Context, taskID string, resultsFilePath string) error {
+	_, err := a.TaskDetailsRepo.GetReconciliationTaskStatus(ctx, taskID)
+
+	//error on retrieve
+	if err != nil {
+		return err
+	}
+
+	err = a.TaskDetailsRepo.CompleteReconciliationTask(ctx, taskID, resultsFilePath)
+
+	//error on update
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("error on updating recon task details: [%v]", err),
+			"InvalidTaskTransition",
+			err,
+		)
+	}
+
+	log.Printf("Reconciliation task [%v] completed, results written to [%v]", taskID, resultsFilePath)
+	return nil
+}
+
+// MarkTaskFailed records why the workflow of the task failed.
+func (a *ReconTaskActivities) MarkTaskFailed(ctx context.Context, taskID string, reason string) error {
+	return a.TaskDetailsRepo.FailReconciliationTask(ctx, taskID, errors.New(reason))
+}
+
+// transitionTask moves the task to the given status.
+// A task that can't make the move never will, so there is no point retrying.
+func (a *ReconTaskActivities) transitionTask(ctx context.Context, taskID string, status task_status.TaskStatus) error {
+	err := a.TaskDetailsRepo.TransitionReconciliationTask(ctx, taskID, status)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("error on updating recon task details: [%v]", err),
+			"InvalidTaskTransition",
+			err,
+		)
+	}
+	return nil
+}
+
+// workContext is done once the activity or the task is cancelled. Until then a heartbeat is recorded
+// every TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL, so temporal can tell a long running activity from one
+// whose worker died and can let the activity know when its workflow is cancelled.
+func workContext(ctx context.Context, taskDetails models.ReconTaskDetails) (context.Context, context.CancelFunc) {
+	workCtx, cancel := context.WithCancel(ctx)
+	taskCtx := taskDetails.Control.Context()
+
+	go func() {
+		ticker := time.NewTicker(constants.TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-workCtx.Done():
+				return
+			case <-taskCtx.Done():
+				cancel()
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx)
+			}
+		}
+	}()
+
+	return workCtx, cancel
+}
+
+// stopRetryingIfCancelled keeps temporal from retrying the activities of a cancelled task
+func stopRetryingIfCancelled(taskDetails models.ReconTaskDetails, err error) error {
+	if err != nil && taskDetails.Control.Context().Err() != nil {
+		return temporal.NewNonRetryableApplicationError("task was cancelled", "TaskCancelled", err)
+	}
+	return err
+}
+
+func (a *ReconTaskActivities) getFileForTask(ctx context.Context, taskID string, filePurpose file_purpose.FilePurposeType) (models.FileToBeRead, error) {
+	if filePurpose == file_purpose.PrimaryFile {
+		return a.FileDetailsRepo.GetPrimaryFileDetailsForTask(ctx, taskID)
+	}
+	return a.FileDetailsRepo.GetComparisonFileDetailsForTask(ctx, taskID)
+}
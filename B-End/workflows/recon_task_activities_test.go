@@ -0,0 +1,103 @@
+package workflows
+
+import (
+	"context"
+	"reconciler.io/constants"
+	"reconciler.io/models"
+	"reconciler.io/models/enums/file_purpose"
+	"reconciler.io/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+)
+
+// sectionsNeverArrive is a file stream whose sections never arrive,
+// fetching from it waits until the context is done
+type sectionsNeverArrive struct{}
+
+func (s *sectionsNeverArrive) SetupStream(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (s *sectionsNeverArrive) DeleteStreamTopic(ctx context.Context, streamName string, topicName string) error {
+	return nil
+}
+
+func (s *sectionsNeverArrive) PublishToTopic(ctx context.Context, topicName string, data interface{}) error {
+	return nil
+}
+
+func (s *sectionsNeverArrive) CreateStreamConsumer(ctx context.Context, streamName, topicName, consumerName string) (models.StreamConsumer, error) {
+	return s, nil
+}
+
+func (s *sectionsNeverArrive) DeleteStreamConsumer(ctx context.Context, streamName string, consumerName string) error {
+	return nil
+}
+
+func (s *sectionsNeverArrive) Close(ctx context.Context) error {
+	return nil
+}
+
+func (s *sectionsNeverArrive) FetchNext() (*models.FileSection, error) {
+	return s.FetchNextWithContext(context.Background())
+}
+
+func (s *sectionsNeverArrive) FetchNextWithContext(ctx context.Context) (*models.FileSection, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestReconcileFilesHeartbeatsAndStopsWhenTheActivityIsCancelled(t *testing.T) {
+	heartbeatInterval := constants.TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL
+	constants.TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL = 10 * time.Millisecond
+	defer func() { constants.TEMPORAL_ACTIVITY_HEARTBEAT_INTERVAL = heartbeatInterval }()
+
+	ctx := context.Background()
+	activities := &ReconTaskActivities{
+		TaskDetailsRepo: repositories.NewTaskDetailsRepository(nil),
+		FileDetailsRepo: repositories.NewFileDetailsRepository(nil),
+	}
+
+	taskID, err := activities.TaskDetailsRepo.SaveTaskDetails(ctx, models.ReconTaskDetails{})
+	assert.NoError(t, err)
+
+	for _, file := range []models.FileToBeRead{
+		{ID: "PrimaryFile-" + taskID, FilePurpose: file_purpose.PrimaryFile},
+		{ID: "ComparisonFile-" + taskID, FilePurpose: file_purpose.ComparisonFile},
+	} {
+		file.ReconciliationTaskID = taskID
+		file.ReadFileResultsStream = &sectionsNeverArrive{}
+		_, err = activities.FileDetailsRepo.SaveFileToBeRead(ctx, file)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, activities.TaskDetailsRepo.AttachPrimaryFile(ctx, taskID, "PrimaryFile-"+taskID))
+	assert.NoError(t, activities.TaskDetailsRepo.AttachComparisonFile(ctx, taskID, "ComparisonFile-"+taskID))
+
+	// the workflow is cancelled once the activity has shown it is alive
+	activityCtx, cancelActivity := context.WithCancel(ctx)
+	heartbeats := 0
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.SetWorkerOptions(worker.Options{BackgroundActivityContext: activityCtx})
+	env.SetOnActivityHeartbeatListener(func(activityInfo *activity.Info, details converter.EncodedValues) {
+		heartbeats++
+		cancelActivity()
+	})
+	env.RegisterActivity(activities)
+
+	_, err = env.ExecuteActivity(activities.ReconcileFiles, taskID)
+
+	assert.Error(t, err)
+	assert.Greater(t, heartbeats, 0)
+
+	// only the activity was cancelled, the task itself carries on elsewhere
+	task, err := activities.TaskDetailsRepo.GetReconciliationTaskStatus(ctx, taskID)
+	assert.NoError(t, err)
+	assert.NoError(t, task.Control.Context().Err())
+}
@@ -0,0 +1,88 @@
+package workflows
+
+import (
+	"fmt"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"reconciler.io/constants"
+	"reconciler.io/models/enums/file_purpose"
+	"time"
+)
+
+// ReconTaskWorkflowID is the ID of the workflow that runs a task,
+// there is only ever one workflow per task.
+func ReconTaskWorkflowID(taskID string) string {
+	return fmt.Sprintf("ReconTask-%v", taskID)
+}
+
+// ReconTaskWorkflow runs the whole lifecycle of a reconciliation task:
+// both files are read into their streams, reconciled against each other,
+// the results are reconstructed into a file and finally the task is marked as done.
+// Every step is idempotent thanks to the task checkpoints so the activities can safely be retried.
+func ReconTaskWorkflow(ctx workflow.Context, taskID string) (string, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: constants.TEMPORAL_ACTIVITY_TIMEOUT,
+		HeartbeatTimeout:    constants.TEMPORAL_ACTIVITY_HEARTBEAT_TIMEOUT,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumAttempts:    int32(constants.TEMPORAL_ACTIVITY_MAX_ATTEMPTS),
+		},
+	})
+
+	resultsFilePath, err := runReconTask(ctx, taskID)
+
+	//a cancelled workflow belongs to a cancelled task
+	if temporal.IsCanceledError(err) {
+		return "", err
+	}
+
+	//record why the task failed on the task itself
+	if err != nil {
+		var activities *ReconTaskActivities
+		failErr := workflow.ExecuteActivity(ctx, activities.MarkTaskFailed, taskID, err.Error()).Get(ctx, nil)
+		if failErr != nil {
+			workflow.GetLogger(ctx).Error("error on marking task as failed", "TaskID", taskID, "Error", failErr)
+		}
+		return "", err
+	}
+
+	return resultsFilePath, nil
+}
+
+func runReconTask(ctx workflow.Context, taskID string) (string, error) {
+	var activities *ReconTaskActivities
+
+	//read both files at the same time
+	primaryFileRead := workflow.ExecuteActivity(ctx, activities.ReadFile, taskID, file_purpose.PrimaryFile)
+	comparisonFileRead := workflow.ExecuteActivity(ctx, activities.ReadFile, taskID, file_purpose.ComparisonFile)
+
+	//error on reading the primary file
+	if err := primaryFileRead.Get(ctx, nil); err != nil {
+		return "", fmt.Errorf("error on reading PrimaryFile: [%w]", err)
+	}
+
+	//error on reading the comparison file
+	if err := comparisonFileRead.Get(ctx, nil); err != nil {
+		return "", fmt.Errorf("error on reading ComparisonFile: [%w]", err)
+	}
+
+	//error on reconciliation
+	if err := workflow.ExecuteActivity(ctx, activities.ReconcileFiles, taskID).Get(ctx, nil); err != nil {
+		return "", fmt.Errorf("error on reconciliation: [%w]", err)
+	}
+
+	var resultsFilePath string
+
+	//error on reconstruction
+	if err := workflow.ExecuteActivity(ctx, activities.ReconstructFile, taskID).Get(ctx, &resultsFilePath); err != nil {
+		return "", fmt.Errorf("error on file reconstruction: [%w]", err)
+	}
+
+	//error on notify
+	if err := workflow.ExecuteActivity(ctx, activities.NotifyTaskCompleted, taskID, resultsFilePath).Get(ctx, nil); err != nil {
+		return "", fmt.Errorf("error on notifying task completion: [%w]", err)
+	}
+
+	return resultsFilePath, nil
+}
@@ -0,0 +1,83 @@
+package workflows
+
+import (
+	"errors"
+	"reconciler.io/models/enums/file_purpose"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestReconTaskWorkflowRunsEveryStepInOrder(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	var activities *ReconTaskActivities
+	env.RegisterActivity(activities)
+
+	var steps []string
+	record := func(step string) func(args mock.Arguments) {
+		return func(args mock.Arguments) { steps = append(steps, step) }
+	}
+
+	env.OnActivity(activities.ReadFile, mock.Anything, "task_1", file_purpose.PrimaryFile).Return(nil).Run(record("read"))
+	env.OnActivity(activities.ReadFile, mock.Anything, "task_1", file_purpose.ComparisonFile).Return(nil).Run(record("read"))
+	env.OnActivity(activities.ReconcileFiles, mock.Anything, "task_1").Return(nil).Run(record("reconcile"))
+	env.OnActivity(activities.ReconstructFile, mock.Anything, "task_1").Return("./ReconResults-task_1.Csv", nil).Run(record("reconstruct"))
+	env.OnActivity(activities.NotifyTaskCompleted, mock.Anything, "task_1", "./ReconResults-task_1.Csv").Return(nil).Run(record("notify"))
+
+	env.ExecuteWorkflow(ReconTaskWorkflow, "task_1")
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+
+	var resultsFilePath string
+	assert.NoError(t, env.GetWorkflowResult(&resultsFilePath))
+	assert.Equal(t, "./ReconResults-task_1.Csv", resultsFilePath)
+	assert.Equal(t, []string{"read", "read", "reconcile", "reconstruct", "notify"}, steps)
+	env.AssertExpectations(t)
+}
+
+func TestReconTaskWorkflowRetriesFailedActivities(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	var activities *ReconTaskActivities
+	env.RegisterActivity(activities)
+
+	env.OnActivity(activities.ReadFile, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(activities.ReconcileFiles, mock.Anything, "task_1").Return(errors.New("stream unavailable")).Once()
+	env.OnActivity(activities.ReconcileFiles, mock.Anything, "task_1").Return(nil).Once()
+	env.OnActivity(activities.ReconstructFile, mock.Anything, "task_1").Return("./ReconResults-task_1.Csv", nil)
+	env.OnActivity(activities.NotifyTaskCompleted, mock.Anything, "task_1", mock.Anything).Return(nil)
+
+	env.ExecuteWorkflow(ReconTaskWorkflow, "task_1")
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestReconTaskWorkflowFailsWhenReadingKeepsFailing(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	var activities *ReconTaskActivities
+	env.RegisterActivity(activities)
+
+	env.OnActivity(activities.ReadFile, mock.Anything, "task_1", file_purpose.PrimaryFile).Return(errors.New("file not found"))
+	env.OnActivity(activities.ReadFile, mock.Anything, "task_1", file_purpose.ComparisonFile).Return(nil)
+	env.OnActivity(activities.MarkTaskFailed, mock.Anything, "task_1", mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, "file not found")
+	})).Return(nil).Once()
+
+	env.ExecuteWorkflow(ReconTaskWorkflow, "task_1")
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.Error(t, env.GetWorkflowError())
+	assert.Contains(t, env.GetWorkflowError().Error(), "error on reading PrimaryFile")
+	env.AssertExpectations(t)
+}
@@ -0,0 +1,24 @@
+package workflows
+
+import (
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"reconciler.io/constants"
+	"reconciler.io/repositories"
+)
+
+// NewReconTaskWorker creates a worker that runs the ReconTaskWorkflow and its activities
+// off the reconciliation task queue. The caller is responsible for starting and stopping it.
+func NewReconTaskWorker(
+	temporalClient client.Client,
+	taskDetailsRepo *repositories.TaskDetailsRepository,
+	fileDetailsRepo *repositories.FileDetailsRepository,
+) worker.Worker {
+	reconTaskWorker := worker.New(temporalClient, constants.TEMPORAL_TASK_QUEUE, worker.Options{})
+	reconTaskWorker.RegisterWorkflow(ReconTaskWorkflow)
+	reconTaskWorker.RegisterActivity(&ReconTaskActivities{
+		TaskDetailsRepo: taskDetailsRepo,
+		FileDetailsRepo: fileDetailsRepo,
+	})
+	return reconTaskWorker
+}